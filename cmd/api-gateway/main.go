@@ -5,11 +5,14 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	_ "time/tzdata" // stats time zones must resolve in minimal container images
 	"platform/internal/api"
 	"platform/internal/config"
 	"platform/internal/database"
 	"platform/internal/repository/rabbitmq"
+	"platform/internal/stream"
 	"platform/pkg/logger"
+	"platform/pkg/metrics"
 )
 
 func main() {
@@ -31,8 +34,22 @@ func main() {
 	}
 	defer publisher.Close()
 
+	// Feed the live click stream from this replica's own subscription
+	hub := stream.NewHub(cfg.Stream.BufferSize)
+	subscriber, err := rabbitmq.NewSubscriber(cfg)
+	if err != nil {
+		logger.Fatal("Failed to initialize RabbitMQ subscriber", err)
+	}
+	defer subscriber.Close()
+	if err := subscriber.Subscribe(hub.Publish); err != nil {
+		logger.Fatal("Failed to subscribe to click events", err)
+	}
+
 	// Setup router
-	router := api.SetupRouter(publisher)
+	router := api.SetupRouter(publisher, hub)
+
+	// Expose gateway metrics, such as GeoIP availability
+	metrics.Serve(cfg.Metrics.Addr)
 
 	// Create a channel to listen for shutdown signals
 	quit := make(chan os.Signal, 1)
@@ -0,0 +1,291 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"platform/internal/archive"
+	"platform/internal/attribution"
+	"platform/internal/config"
+	"platform/internal/models"
+	"platform/internal/privacy"
+	"platform/internal/repository/mysql"
+	"platform/internal/retention"
+	"platform/internal/rollup"
+	"platform/internal/useragent"
+	"time"
+)
+
+// runCommand runs a one-off maintenance command given on the command line
+func runCommand(db *sql.DB, cfg *config.Config, args []string) error {
+	switch args[0] {
+	case "rebuild-rollups":
+		return rebuildRollups(db, cfg, args[1:])
+	case "purge":
+		return purge(db, cfg, args[1:])
+	case "archive":
+		return archiveDay(db, cfg, args[1:])
+	case "restore-archive":
+		return restoreArchive(db, cfg, args[1:])
+	case "scrub-pii":
+		return scrubPII(db, cfg, args[1:])
+	case "backfill-user-agents":
+		return backfillUserAgents(db, cfg, args[1:])
+	case "parse-user-agent":
+		return parseUserAgent(cfg, args[1:])
+	case "backfill-attribution":
+		return backfillAttribution(db, args[1:])
+	default:
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+}
+
+// rebuildRollups recomputes the click rollups of a range of closed UTC days from the
+// raw history. A day is closed ARCHIVE_LAG after it ends; rebuilding a day that is
+// still receiving clicks would race the worker's own rollup updates.
+// Usage: database-worker rebuild-rollups -from 2024-01-01 [-to 2024-01-31]
+func rebuildRollups(db *sql.DB, cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("rebuild-rollups", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	fromFlag := fs.String("from", "", "first UTC day to rebuild (YYYY-MM-DD)")
+	toFlag := fs.String("to", "", "last UTC day to rebuild, inclusive (YYYY-MM-DD, defaults to the last closed day)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *fromFlag == "" {
+		return fmt.Errorf("-from is required")
+	}
+	from, err := time.Parse("2006-01-02", *fromFlag)
+	if err != nil {
+		return fmt.Errorf("invalid -from: %w", err)
+	}
+
+	lastClosed := time.Now().UTC().Add(-cfg.Archive.Lag).Truncate(24*time.Hour).AddDate(0, 0, -1)
+	to := lastClosed
+	if *toFlag != "" {
+		to, err = time.Parse("2006-01-02", *toFlag)
+		if err != nil {
+			return fmt.Errorf("invalid -to: %w", err)
+		}
+	}
+	if to.After(lastClosed) {
+		return fmt.Errorf("%s is not closed yet; the last closed day is %s", to.Format("2006-01-02"), lastClosed.Format("2006-01-02"))
+	}
+	if to.Before(from) {
+		return fmt.Errorf("-to must not be before -from")
+	}
+
+	// Rollups outlive the raw data, so never rebuild days that may have been purged
+	purger := retention.NewPurger(mysql.NewRetentionRepository(db), cfg.Retention)
+	earliest, limited, err := purger.EarliestIntactDay(time.Now())
+	if err != nil {
+		return err
+	}
+	if limited && from.Before(earliest) {
+		return fmt.Errorf("raw clicks before %s may have been purged by retention; rebuild from that day on", earliest.Format("2006-01-02"))
+	}
+
+	parser, err := useragent.NewParser(cfg.UserAgent)
+	if err != nil {
+		return err
+	}
+	return rollup.Rebuild(mysql.NewRollupRepository(db), parser, from, to.AddDate(0, 0, 1))
+}
+
+// purge runs the retention purge once and prints its report as JSON.
+// Usage: database-worker purge [-dry-run]
+func purge(db *sql.DB, cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("purge", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	dryRun := fs.Bool("dry-run", false, "report what would be purged without deleting anything")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	purger := retention.NewPurger(mysql.NewRetentionRepository(db), cfg.Retention)
+	report, err := purger.Purge(context.Background(), *dryRun)
+	if err != nil {
+		return err
+	}
+
+	return printJSON(report)
+}
+
+// archiveDay archives one UTC day of a raw click table and prints its manifest.
+// Usage: database-worker archive -table request_logs -day 2024-01-15
+func archiveDay(db *sql.DB, cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("archive", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	table := fs.String("table", "", "table to archive (request_logs or redirect_history)")
+	dayFlag := fs.String("day", "", "UTC day to archive (YYYY-MM-DD)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	day, err := time.Parse("2006-01-02", *dayFlag)
+	if err != nil {
+		return fmt.Errorf("invalid -day: %w", err)
+	}
+	if !models.IsRetentionTable(*table) {
+		return fmt.Errorf("invalid -table %q", *table)
+	}
+
+	store, err := archive.NewStore(cfg.Archive)
+	if err != nil {
+		return err
+	}
+	archiver := archive.NewArchiver(mysql.NewArchiveRepository(db), store, cfg.Archive)
+	manifest, err := archiver.ArchiveDay(context.Background(), *table, day)
+	if err != nil {
+		return err
+	}
+	return printJSON(manifest)
+}
+
+// restoreArchive re-imports an archived day into a scratch table.
+// Usage: database-worker restore-archive -table request_logs -day 2024-01-15 [-into restore_request_logs_20240115]
+func restoreArchive(db *sql.DB, cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("restore-archive", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	table := fs.String("table", "", "archived table (request_logs or redirect_history)")
+	dayFlag := fs.String("day", "", "archived UTC day (YYYY-MM-DD)")
+	into := fs.String("into", "", "scratch table to create (defaults to restore_<table>_<YYYYMMDD>)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	day, err := time.Parse("2006-01-02", *dayFlag)
+	if err != nil {
+		return fmt.Errorf("invalid -day: %w", err)
+	}
+	scratch := *into
+	if scratch == "" {
+		scratch = archive.ScratchTableName(*table, day)
+	}
+
+	store, err := archive.NewStore(cfg.Archive)
+	if err != nil {
+		return err
+	}
+	restorer := archive.NewRestorer(mysql.NewArchiveRepository(db), store)
+	manifest, err := restorer.Restore(context.Background(), *table, day, scratch)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "Restored %d rows of %s on %s into %s\n", manifest.Rows, *table, day.Format("2006-01-02"), scratch)
+	return nil
+}
+
+// scrubPII applies the current privacy policies to the stored request logs and
+// prints a report as JSON. Archived days are not rewritten.
+// Usage: database-worker scrub-pii [-dry-run] [-batch 1000] [-after-id 0]
+func scrubPII(db *sql.DB, cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("scrub-pii", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	dryRun := fs.Bool("dry-run", false, "count the rows that would change without rewriting them")
+	batch := fs.Int("batch", 1000, "rows read and rewritten per transaction")
+	afterID := fs.Int64("after-id", 0, "resume after this request log ID")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *batch <= 0 {
+		return fmt.Errorf("-batch must be positive")
+	}
+
+	policy, err := privacy.NewPolicy(cfg.Privacy)
+	if err != nil {
+		return err
+	}
+	resolver := privacy.NewResolver(policy, mysql.NewPrivacyRepository(db), cfg.Privacy.SettingsCacheTTL)
+
+	// The report is printed on failure too: its last ID is where to resume
+	report, err := privacy.Scrub(context.Background(), resolver, *batch, *afterID, *dryRun)
+	if printErr := printJSON(report); err == nil {
+		err = printErr
+	}
+	return err
+}
+
+// backfillUserAgents parses the User-Agents of stored request logs into their
+// columns and prints a report as JSON.
+// Usage: database-worker backfill-user-agents [-all] [-batch 1000] [-after-id 0]
+func backfillUserAgents(db *sql.DB, cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("backfill-user-agents", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	all := fs.Bool("all", false, "reparse rows parsed before, e.g. after updating the rules")
+	batch := fs.Int("batch", 1000, "rows read and updated per transaction")
+	afterID := fs.Int64("after-id", 0, "resume after this request log ID")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *batch <= 0 {
+		return fmt.Errorf("-batch must be positive")
+	}
+
+	parser, err := useragent.NewParser(cfg.UserAgent)
+	if err != nil {
+		return err
+	}
+
+	// The report is printed on failure too: its last ID is where to resume
+	report, err := useragent.Backfill(context.Background(), mysql.NewRequestRepository(db), parser, *batch, *afterID, *all)
+	if printErr := printJSON(report); err == nil {
+		err = printErr
+	}
+	return err
+}
+
+// parseUserAgent prints how the configured rules parse the given User-Agents,
+// to check a rules update before deploying it.
+// Usage: database-worker parse-user-agent "Mozilla/5.0 ..." [...]
+func parseUserAgent(cfg *config.Config, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("at least one User-Agent is required")
+	}
+
+	parser, err := useragent.NewParser(cfg.UserAgent)
+	if err != nil {
+		return err
+	}
+	for _, userAgent := range args {
+		if err := printJSON(parser.Parse(userAgent)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// backfillAttribution parses the referrer and UTM parameters of stored request
+// logs into their columns and prints a report as JSON.
+// Usage: database-worker backfill-attribution [-all] [-batch 1000] [-after-id 0]
+func backfillAttribution(db *sql.DB, args []string) error {
+	fs := flag.NewFlagSet("backfill-attribution", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	all := fs.Bool("all", false, "reparse rows parsed before, e.g. after the source lists changed")
+	batch := fs.Int("batch", 1000, "rows read and updated per transaction")
+	afterID := fs.Int64("after-id", 0, "resume after this request log ID")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *batch <= 0 {
+		return fmt.Errorf("-batch must be positive")
+	}
+
+	// The report is printed on failure too: its last ID is where to resume
+	report, err := attribution.Backfill(context.Background(), mysql.NewRequestRepository(db), *batch, *afterID, *all)
+	if printErr := printJSON(report); err == nil {
+		err = printErr
+	}
+	return err
+}
+
+func printJSON(v interface{}) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
@@ -1,16 +1,28 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 	_ "github.com/go-sql-driver/mysql"
+	"platform/internal/archive"
+	"platform/internal/bot"
 	"platform/internal/config"
-	"platform/internal/models"
+	"platform/internal/fraud"
+	"platform/internal/geoip"
 	"platform/internal/repository/mysql"
 	"platform/internal/repository/rabbitmq"
+	"platform/internal/retention"
+	"platform/internal/rollup"
+	"platform/internal/useragent"
+	"platform/internal/webhook"
+	"platform/internal/worker"
 	"platform/pkg/logger"
-	"strings"
+	"platform/pkg/metrics"
 )
 
 func main() {
@@ -62,78 +74,90 @@ func main() {
 	}
 	defer db.Close()
 
+	// Run a one-off maintenance command instead of the worker when one is given
+	if len(os.Args) > 1 {
+		if err := runCommand(db, cfg, os.Args[1:]); err != nil {
+			logger.Fatal("Command failed", err)
+		}
+		return
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	// Initialize repositories
 	requestRepo := mysql.NewRequestRepository(db)
 	redirectRepo := mysql.NewRedirectRepository(db)
+	webhookRepo := mysql.NewWebhookRepository(db)
+	rollupRepo := mysql.NewRollupRepository(db)
+	retentionRepo := mysql.NewRetentionRepository(db)
+	archiveRepo := mysql.NewArchiveRepository(db)
 	consumer, err := rabbitmq.NewConsumer(cfg)
 	if err != nil {
 		logger.Fatal("Failed to initialize RabbitMQ consumer", err)
 	}
-	defer consumer.Close()
 
-	// Start consuming messages
-	logger.Info("Starting database worker")
-	if err := consumer.Consume(func(request *models.Request) error {
-		// Save request to database
-		if err := requestRepo.SaveRequest(request); err != nil {
-			return fmt.Errorf("failed to save request: %w", err)
-		}
+	// Deliver queued webhooks in the background
+	dispatcher := webhook.NewDispatcher(webhookRepo, cfg.Webhook)
+	go dispatcher.Run(ctx)
 
-		// Extract hash from request URL
-		hash := extractHashFromURL(request.RequestURL)
-		if hash != "" {
-			// Get redirect URL
-			redirectURL, err := redirectRepo.GetRedirectURL(hash)
-			if err != nil {
-				return fmt.Errorf("failed to get redirect URL: %w", err)
-			}
-
-			if redirectURL != "" {
-				// Create redirect record
-				redirect := &models.Redirect{
-					RequestLogID:     request.ID,
-					OriginalURL:      request.RequestURL,
-					RedirectURL:      redirectURL,
-					RedirectType:     determineRedirectType(redirectURL),
-					RedirectStatus:   302, // Temporary redirect
-					RedirectTimestamp: request.Timestamp,
-				}
-
-				// Save redirect record
-				if err := redirectRepo.SaveRedirect(redirect); err != nil {
-					return fmt.Errorf("failed to save redirect: %w", err)
-				}
-			}
+	// Write click rollups in batches
+	aggregator := rollup.NewAggregator(rollupRepo, cfg.Rollup.FlushInterval)
+	go aggregator.Run(ctx)
+
+	// Export closed days of raw click data before they can be purged
+	if cfg.Archive.Enabled {
+		store, err := archive.NewStore(cfg.Archive)
+		if err != nil {
+			logger.Fatal("Failed to initialize archive store", err)
 		}
+		archiver := archive.NewArchiver(archiveRepo, store, cfg.Archive)
+		go archiver.Run(ctx)
+	}
 
-		return nil
-	}); err != nil {
-		logger.Fatal("Failed to start consuming messages", err)
+	// Purge expired raw click data on a schedule
+	if cfg.Retention.Enabled {
+		purger := retention.NewPurger(retentionRepo, cfg.Retention)
+		go purger.Run(ctx)
 	}
 
-	// Keep the worker running
-	select {}
-}
+	// Expose worker metrics
+	metrics.Serve(cfg.Metrics.Addr)
 
-func extractHashFromURL(url string) string {
-	// Extract hash from URL path
-	// Example: /345678?click_id=clic-hash -> 345678
-	parts := strings.Split(url, "/")
-	if len(parts) >= 2 {
-		// Remove any query parameters
-		hash := strings.Split(parts[1], "?")[0]
-		return hash
+	// Parse User-Agents into their own columns as clicks are stored
+	parser, err := useragent.NewParser(cfg.UserAgent)
+	if err != nil {
+		logger.Fatal("Failed to load user agent rules", err)
 	}
-	return ""
-}
 
-func determineRedirectType(redirectURL string) string {
-	// Extract the domain from the URL
-	// Example: http://site1.com/special-offer -> site1
-	parts := strings.Split(redirectURL, "/")
-	if len(parts) >= 3 {
-		domain := strings.Split(parts[2], ".")[0]
-		return domain
+	// Locate clicks with the local GeoIP databases, picking up new releases on disk
+	locator := geoip.NewLocator(cfg.GeoIP)
+	locator.Watch(ctx)
+
+	// Flag automated clicks, picking up new crawler ranges on disk
+	classifier := bot.NewClassifier(cfg.Bot)
+	classifier.Watch(ctx)
+
+	// Score clicks for fraud before they are stored
+	scorer := fraud.NewScorer(cfg.Fraud, redirectRepo)
+
+	// Persist click events exactly as the gateway published them
+	processor := worker.NewProcessor(requestRepo, redirectRepo, dispatcher, aggregator, parser, locator, classifier, scorer, cfg.Visitor.UniqueWindow)
+
+	// Start consuming messages
+	logger.Info("Starting database worker")
+	if err := consumer.Consume(processor.Process); err != nil {
+		logger.Fatal("Failed to start consuming messages", err)
+	}
+
+	// Keep the worker running until it is asked to stop
+	<-ctx.Done()
+	logger.Info("Shutting down database worker")
+
+	// Stop consuming and wait for the event being processed before the final
+	// rollup flush, so no counts are left behind
+	consumer.Close()
+	if err := aggregator.Flush(); err != nil {
+		logger.Error("Failed to flush click rollups on shutdown", "error", err)
 	}
-	return "unknown"
-} 
\ No newline at end of file
+}
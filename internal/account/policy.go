@@ -0,0 +1,52 @@
+package account
+
+import (
+	"fmt"
+	"platform/internal/config"
+	"platform/internal/models"
+)
+
+// Policy decides what accounts that have not verified their email address
+// can do
+type Policy struct {
+	mode        string
+	maxMappings int
+}
+
+// NewPolicy validates the unverified account policy
+func NewPolicy(cfg config.AccountConfig) (*Policy, error) {
+	switch cfg.UnverifiedPolicy {
+	case models.UnverifiedPolicyAllow, models.UnverifiedPolicyLimit, models.UnverifiedPolicyBlock:
+	default:
+		return nil, fmt.Errorf("unknown unverified account policy %q", cfg.UnverifiedPolicy)
+	}
+	if cfg.UnverifiedMaxMappings < 0 {
+		return nil, fmt.Errorf("unverified account mapping limit must not be negative")
+	}
+
+	return &Policy{
+		mode:        cfg.UnverifiedPolicy,
+		maxMappings: cfg.UnverifiedMaxMappings,
+	}, nil
+}
+
+// LimitsUnverified reports whether unverified accounts are kept from creating
+// API keys, webhooks and organizations
+func (p *Policy) LimitsUnverified() bool {
+	return p.mode != models.UnverifiedPolicyAllow
+}
+
+// BlocksUnverified reports whether unverified accounts are kept out of the API
+// until they verify their email address
+func (p *Policy) BlocksUnverified() bool {
+	return p.mode == models.UnverifiedPolicyBlock
+}
+
+// MaxMappings returns how many mappings an account may create, or 0 for no
+// limit
+func (p *Policy) MaxMappings(verified bool) int {
+	if verified || p.mode != models.UnverifiedPolicyLimit {
+		return 0
+	}
+	return p.maxMappings
+}
@@ -0,0 +1,277 @@
+package account
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"golang.org/x/crypto/bcrypt"
+	"net/url"
+	"platform/internal/auth"
+	"platform/internal/config"
+	"platform/internal/mail"
+	"platform/internal/models"
+	"platform/internal/repository/mysql"
+	"platform/pkg/logger"
+	"strings"
+	"time"
+)
+
+const (
+	verificationTokenPrefix = "ev_"
+	resetTokenPrefix        = "pr_"
+)
+
+// resendInterval is how long a client waits before it is sent another token
+// for the same purpose
+const resendInterval = time.Minute
+
+// sendTimeout bounds the delivery of one email
+const sendTimeout = 30 * time.Second
+
+var (
+	// ErrInvalidToken is returned for unknown, expired and used tokens
+	ErrInvalidToken = errors.New("invalid or expired token")
+	// ErrAlreadyVerified is returned when resending the verification email of
+	// a verified account
+	ErrAlreadyVerified = errors.New("email address already verified")
+	// ErrTooSoon is returned when a token was sent less than resendInterval ago
+	ErrTooSoon = errors.New("token sent too recently")
+)
+
+// Service verifies email addresses and resets forgotten passwords with
+// single-use tokens sent by email
+type Service struct {
+	tokenRepo  *mysql.TokenRepository
+	clientRepo *mysql.ClientRepository
+	sessions   *auth.Sessions
+	mailer     mail.Mailer
+	cfg        config.AccountConfig
+}
+
+func NewService(tokenRepo *mysql.TokenRepository, clientRepo *mysql.ClientRepository, sessions *auth.Sessions, mailer mail.Mailer, cfg config.AccountConfig) *Service {
+	return &Service{
+		tokenRepo:  tokenRepo,
+		clientRepo: clientRepo,
+		sessions:   sessions,
+		mailer:     mailer,
+		cfg:        cfg,
+	}
+}
+
+// SendVerification emails a client a token that verifies its email address
+func (s *Service) SendVerification(client *models.Client) error {
+	if client.EmailVerifiedAt != nil {
+		return ErrAlreadyVerified
+	}
+	if err := s.checkInterval(client.ID, models.AccountTokenVerifyEmail); err != nil {
+		return err
+	}
+
+	token, expiresAt, err := s.issue(client, models.AccountTokenVerifyEmail, s.cfg.VerificationTTL)
+	if err != nil {
+		return err
+	}
+
+	s.send(mail.Message{
+		To:      client.Email,
+		Subject: "Verify your email address",
+		Body: body(client.Username, "Confirm your email address", s.link("verify-email", token),
+			"POST /api/email/verify", token, expiresAt),
+	})
+	return nil
+}
+
+// VerifyEmail marks the email address a verification token was sent to as
+// verified
+func (s *Service) VerifyEmail(token string) error {
+	stored, client, err := s.redeem(token, models.AccountTokenVerifyEmail)
+	if err != nil {
+		return err
+	}
+	if client.EmailVerifiedAt != nil {
+		return nil
+	}
+
+	verified, err := s.clientRepo.VerifyEmail(stored)
+	if err != nil {
+		return err
+	}
+	if !verified {
+		return ErrInvalidToken
+	}
+	return nil
+}
+
+// RequestPasswordReset emails a password reset token to the account with an
+// email address. Unknown addresses and suspended accounts are ignored, so the
+// caller cannot tell which addresses have an account.
+func (s *Service) RequestPasswordReset(email string) error {
+	client, err := s.clientRepo.GetByEmail(email)
+	if err != nil {
+		return err
+	}
+	if client == nil || client.SuspendedAt != nil {
+		return nil
+	}
+	if err := s.checkInterval(client.ID, models.AccountTokenResetPassword); err != nil {
+		if errors.Is(err, ErrTooSoon) {
+			return nil
+		}
+		return err
+	}
+
+	token, expiresAt, err := s.issue(client, models.AccountTokenResetPassword, s.cfg.PasswordResetTTL)
+	if err != nil {
+		return err
+	}
+
+	s.send(mail.Message{
+		To:      client.Email,
+		Subject: "Reset your password",
+		Body: body(client.Username, "Someone asked to reset your password. If it was you, choose a new one", s.link("reset-password", token),
+			"POST /api/password/reset", token, expiresAt) +
+			"\nIf you did not ask for this, ignore this email; your password stays the same.\n",
+	})
+	return nil
+}
+
+// SendRequiredReset emails a password reset token to a client an admin requires
+// to choose a new password. The emailed token is the only way to clear the
+// requirement, so it is sent however recently another token was.
+func (s *Service) SendRequiredReset(client *models.Client) error {
+	token, expiresAt, err := s.issue(client, models.AccountTokenResetPassword, s.cfg.PasswordResetTTL)
+	if err != nil {
+		return err
+	}
+
+	s.send(mail.Message{
+		To:      client.Email,
+		Subject: "Choose a new password",
+		Body: body(client.Username, "An administrator requires you to choose a new password before using your account again. Choose it", s.link("reset-password", token),
+			"POST /api/password/reset", token, expiresAt) +
+			"\nYour current password no longer works.\n",
+	})
+	return nil
+}
+
+// ResetPassword replaces the password of the account a reset token was sent
+// to and ends all its sessions
+func (s *Service) ResetPassword(token, newPassword string) error {
+	stored, client, err := s.redeem(token, models.AccountTokenResetPassword)
+	if err != nil {
+		return err
+	}
+	if client.SuspendedAt != nil {
+		return ErrInvalidToken
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	reset, err := s.clientRepo.ResetPassword(stored, string(hashedPassword))
+	if err != nil {
+		return err
+	}
+	if !reset {
+		return ErrInvalidToken
+	}
+
+	// The password is already replaced; failing to revoke sessions only
+	// leaves them to expire
+	if err := s.sessions.EndAll(client.ID, ""); err != nil {
+		logger.Error("Failed to end sessions after password reset", "client_id", client.ID, "error", err)
+	}
+	return nil
+}
+
+// redeem looks up an unused, unexpired token and the client it was sent to.
+// Tokens sent to an address the client no longer has are invalid.
+func (s *Service) redeem(token, purpose string) (*models.AccountToken, *models.Client, error) {
+	stored, err := s.tokenRepo.GetAccountToken(auth.HashOpaqueToken(token), purpose)
+	if err != nil {
+		return nil, nil, err
+	}
+	if stored == nil || stored.UsedAt != nil || !time.Now().Before(stored.ExpiresAt) {
+		return nil, nil, ErrInvalidToken
+	}
+
+	client, err := s.clientRepo.GetByID(stored.ClientID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if client == nil || !strings.EqualFold(client.Email, stored.Email) {
+		return nil, nil, ErrInvalidToken
+	}
+	return stored, client, nil
+}
+
+func (s *Service) checkInterval(clientID int64, purpose string) error {
+	last, err := s.tokenRepo.LastAccountTokenAt(clientID, purpose)
+	if err != nil {
+		return err
+	}
+	if last != nil && time.Since(*last) < resendInterval {
+		return ErrTooSoon
+	}
+	return nil
+}
+
+func (s *Service) issue(client *models.Client, purpose string, ttl time.Duration) (string, time.Time, error) {
+	prefix := verificationTokenPrefix
+	if purpose == models.AccountTokenResetPassword {
+		prefix = resetTokenPrefix
+	}
+
+	token, tokenHash, err := auth.GenerateOpaqueToken(prefix)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to generate account token: %w", err)
+	}
+
+	stored := &models.AccountToken{
+		ClientID:  client.ID,
+		Purpose:   purpose,
+		Email:     client.Email,
+		TokenHash: tokenHash,
+		ExpiresAt: time.Now().Add(ttl),
+	}
+	if err := s.tokenRepo.CreateAccountToken(stored); err != nil {
+		return "", time.Time{}, err
+	}
+	return token, stored.ExpiresAt, nil
+}
+
+// send delivers an email in the background, so requests do not wait on the
+// mail server and take the same time whether or not an email is sent
+func (s *Service) send(msg mail.Message) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+		defer cancel()
+
+		if err := s.mailer.Send(ctx, msg); err != nil {
+			logger.Error("Failed to send email", "subject", msg.Subject, "error", err)
+		}
+	}()
+}
+
+// link returns the page of the app that takes a token, or an empty string if
+// no app URL is configured
+func (s *Service) link(page, token string) string {
+	if s.cfg.AppURL == "" {
+		return ""
+	}
+	return strings.TrimRight(s.cfg.AppURL, "/") + "/" + page + "?token=" + url.QueryEscape(token)
+}
+
+func body(username, intro, link, endpoint, token string, expiresAt time.Time) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Hi %s,\n\n", username)
+	if link != "" {
+		fmt.Fprintf(&b, "%s by following this link:\n\n%s\n\n", intro, link)
+	} else {
+		fmt.Fprintf(&b, "%s by sending this token to %s:\n\n%s\n\n", intro, endpoint, token)
+	}
+	fmt.Fprintf(&b, "It expires on %s.\n", expiresAt.UTC().Format("Mon, 02 Jan 2006 15:04 MST"))
+	return b.String()
+}
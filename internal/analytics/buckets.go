@@ -0,0 +1,88 @@
+package analytics
+
+import (
+	"fmt"
+	"platform/internal/models"
+	"time"
+)
+
+type Interval string
+
+const (
+	IntervalHour Interval = "hour"
+	IntervalDay  Interval = "day"
+	IntervalWeek Interval = "week"
+)
+
+// MaxBuckets bounds the number of points a single series may contain
+const MaxBuckets = 2000
+
+func ParseInterval(raw string) (Interval, error) {
+	switch Interval(raw) {
+	case IntervalHour, IntervalDay, IntervalWeek:
+		return Interval(raw), nil
+	}
+	return "", fmt.Errorf("interval must be one of hour, day or week")
+}
+
+// BucketStart truncates t to the start of its bucket in loc. Weeks start on Monday.
+func BucketStart(t time.Time, interval Interval, loc *time.Location) time.Time {
+	t = t.In(loc)
+	switch interval {
+	case IntervalHour:
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, loc)
+	case IntervalWeek:
+		day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+		offset := (int(day.Weekday()) + 6) % 7
+		return day.AddDate(0, 0, -offset)
+	default:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+	}
+}
+
+func nextBucket(start time.Time, interval Interval) time.Time {
+	switch interval {
+	case IntervalHour:
+		return start.Add(time.Hour)
+	case IntervalWeek:
+		return start.AddDate(0, 0, 7)
+	default:
+		return start.AddDate(0, 0, 1)
+	}
+}
+
+// CountBuckets returns how many buckets cover [from, to)
+func CountBuckets(from, to time.Time, interval Interval, loc *time.Location) int {
+	n := 0
+	for b := BucketStart(from, interval, loc); b.Before(to); b = nextBucket(b, interval) {
+		n++
+		if n > MaxBuckets {
+			break
+		}
+	}
+	return n
+}
+
+// Series folds hourly or daily UTC counts into zero-filled buckets covering [from, to).
+// Buckets are aligned to whole UTC hours, so zones with sub-hour offsets are
+// approximated to the hour.
+func Series(hourly []models.HourlyCount, from, to time.Time, interval Interval, loc *time.Location) []models.SeriesPoint {
+	var points []models.SeriesPoint
+	index := make(map[int64]int)
+	for b := BucketStart(from, interval, loc); b.Before(to); b = nextBucket(b, interval) {
+		index[b.Unix()] = len(points)
+		points = append(points, models.SeriesPoint{Start: b})
+		if len(points) >= MaxBuckets {
+			break
+		}
+	}
+
+	for _, h := range hourly {
+		if i, ok := index[BucketStart(h.Hour, interval, loc).Unix()]; ok {
+			points[i].Clicks += h.Clicks
+			points[i].UniqueClicks += h.UniqueClicks
+		}
+	}
+
+	return points
+}
@@ -0,0 +1,136 @@
+package analytics
+
+import (
+	"fmt"
+	"platform/internal/models"
+	"platform/internal/repository/mysql"
+	"time"
+)
+
+// Query describes a stats report request
+type Query struct {
+	Filter     models.StatsFilter
+	Interval   Interval
+	Location   *time.Location
+	Breakdowns []string
+	Limit      int
+	Compare    bool
+	// ExactVisitors counts unique visitors from the raw history instead of
+	// estimating them from the rollup sketches
+	ExactVisitors bool
+}
+
+// Service builds click stats reports. Time series and the variant, country and
+// device breakdowns are read from the pre-aggregated rollups; the remaining
+// breakdowns scan the raw logs.
+type Service struct {
+	statsRepo  *mysql.StatsRepository
+	rollupRepo *mysql.RollupRepository
+}
+
+func NewService(statsRepo *mysql.StatsRepository, rollupRepo *mysql.RollupRepository) *Service {
+	return &Service{
+		statsRepo:  statsRepo,
+		rollupRepo: rollupRepo,
+	}
+}
+
+// Report returns the time series, breakdowns and optional previous-period
+// comparison for the query
+func (s *Service) Report(q Query) (*models.StatsReport, error) {
+	current, err := s.period(q.Filter, q)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &models.StatsReport{
+		MappingID: q.Filter.MappingID,
+		Interval:  string(q.Interval),
+		TimeZone:  q.Location.String(),
+		Current:   *current,
+	}
+
+	if len(q.Breakdowns) > 0 {
+		report.Breakdowns = make(map[string][]models.BreakdownItem, len(q.Breakdowns))
+		for _, dimension := range q.Breakdowns {
+			items, err := s.breakdown(q, dimension)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get breakdown: %w", err)
+			}
+			for i := range items {
+				if current.TotalClicks > 0 {
+					items[i].Share = float64(items[i].Clicks) / float64(current.TotalClicks)
+				}
+			}
+			if items == nil {
+				items = []models.BreakdownItem{}
+			}
+			report.Breakdowns[dimension] = items
+		}
+	}
+
+	if q.Compare {
+		previous := q.Filter
+		previous.To = q.Filter.From
+		previous.From = q.Filter.From.Add(-q.Filter.To.Sub(q.Filter.From))
+
+		report.Previous, err = s.period(previous, q)
+		if err != nil {
+			return nil, err
+		}
+		if report.Previous.TotalClicks > 0 {
+			change := float64(current.TotalClicks-report.Previous.TotalClicks) / float64(report.Previous.TotalClicks)
+			report.Change = &change
+		}
+	}
+
+	return report, nil
+}
+
+func (s *Service) breakdown(q Query, dimension string) ([]models.BreakdownItem, error) {
+	if mysql.IsRollupDimension(dimension) {
+		return s.rollupRepo.Breakdown(q.Filter, dimension, q.Limit, useDailyRollups(q.Filter, q.Interval, q.Location))
+	}
+	return s.statsRepo.Breakdown(q.Filter, dimension, q.Limit)
+}
+
+func (s *Service) period(filter models.StatsFilter, q Query) (*models.PeriodStats, error) {
+	counts, err := s.rollupRepo.BucketClicks(filter, useDailyRollups(filter, q.Interval, q.Location))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get clicks: %w", err)
+	}
+
+	period := &models.PeriodStats{
+		From:   filter.From,
+		To:     filter.To,
+		Series: Series(counts, filter.From, filter.To, q.Interval, q.Location),
+	}
+	for _, c := range counts {
+		period.TotalClicks += c.Clicks
+		period.UniqueClicks += c.UniqueClicks
+	}
+
+	if q.ExactVisitors {
+		period.UniqueVisitors, err = s.statsRepo.UniqueVisitors(filter)
+	} else {
+		period.UniqueVisitors, err = s.rollupRepo.Visitors(filter, useDailyRollups(filter, q.Interval, q.Location))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get unique visitors: %w", err)
+	}
+
+	return period, nil
+}
+
+// useDailyRollups reports whether the daily rollups can answer the query: daily
+// buckets are UTC days, so they only fit UTC day or week series over whole days
+func useDailyRollups(filter models.StatsFilter, interval Interval, loc *time.Location) bool {
+	if loc.String() != time.UTC.String() || interval == IntervalHour {
+		return false
+	}
+	return isMidnightUTC(filter.From) && isMidnightUTC(filter.To)
+}
+
+func isMidnightUTC(t time.Time) bool {
+	return t.UTC().Equal(t.UTC().Truncate(24 * time.Hour))
+}
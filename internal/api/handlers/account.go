@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"platform/internal/account"
+	"platform/internal/models"
+	"platform/internal/repository/mysql"
+	"platform/pkg/logger"
+)
+
+type AccountHandler struct {
+	accounts   *account.Service
+	clientRepo *mysql.ClientRepository
+}
+
+func NewAccountHandler(accounts *account.Service, clientRepo *mysql.ClientRepository) *AccountHandler {
+	return &AccountHandler{
+		accounts:   accounts,
+		clientRepo: clientRepo,
+	}
+}
+
+// VerifyEmail marks an email address verified with the token sent to it
+func (h *AccountHandler) VerifyEmail(c *gin.Context) {
+	var verification models.EmailVerification
+	if err := c.ShouldBindJSON(&verification); err != nil {
+		logger.Error("Invalid email verification data", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid email verification data"})
+		return
+	}
+
+	err := h.accounts.VerifyEmail(verification.Token)
+	if errors.Is(err, account.ErrInvalidToken) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired token"})
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to verify email", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify email"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// ResendVerification sends the client a new email verification token,
+// replacing the previous one
+func (h *AccountHandler) ResendVerification(c *gin.Context) {
+	client, err := h.clientRepo.GetByID(c.GetInt64("client_id"))
+	if err != nil {
+		logger.Error("Failed to get client", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send email verification"})
+		return
+	}
+	if client == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	err = h.accounts.SendVerification(client)
+	if errors.Is(err, account.ErrAlreadyVerified) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Email address already verified"})
+		return
+	}
+	if errors.Is(err, account.ErrTooSoon) {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "An email was sent recently, try again in a minute"})
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to send email verification", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send email verification"})
+		return
+	}
+
+	c.Status(http.StatusAccepted)
+}
+
+// ForgotPassword emails a password reset token if an account has the address.
+// It answers the same either way.
+func (h *AccountHandler) ForgotPassword(c *gin.Context) {
+	var request models.PasswordResetRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		logger.Error("Invalid password reset request", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid password reset request"})
+		return
+	}
+
+	if err := h.accounts.RequestPasswordReset(request.Email); err != nil {
+		logger.Error("Failed to request password reset", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to request password reset"})
+		return
+	}
+
+	c.Status(http.StatusAccepted)
+}
+
+// ResetPassword sets a new password with a password reset token and ends the
+// account's sessions
+func (h *AccountHandler) ResetPassword(c *gin.Context) {
+	var reset models.PasswordReset
+	if err := c.ShouldBindJSON(&reset); err != nil {
+		logger.Error("Invalid password reset data", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid password reset data"})
+		return
+	}
+
+	err := h.accounts.ResetPassword(reset.Token, reset.NewPassword)
+	if errors.Is(err, account.ErrInvalidToken) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired token"})
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to reset password", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset password"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
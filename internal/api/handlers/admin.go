@@ -0,0 +1,310 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"platform/internal/account"
+	"platform/internal/auth"
+	"platform/internal/models"
+	"platform/internal/repository/mysql"
+	"platform/pkg/logger"
+	"time"
+)
+
+// adminTopClients is how many clients the usage overview ranks by clicks
+const adminTopClients = 10
+
+type AdminHandler struct {
+	adminRepo  *mysql.AdminRepository
+	clientRepo *mysql.ClientRepository
+	sessions   *auth.Sessions
+	accounts   *account.Service
+}
+
+func NewAdminHandler(adminRepo *mysql.AdminRepository, clientRepo *mysql.ClientRepository, sessions *auth.Sessions, accounts *account.Service) *AdminHandler {
+	return &AdminHandler{
+		adminRepo:  adminRepo,
+		clientRepo: clientRepo,
+		sessions:   sessions,
+		accounts:   accounts,
+	}
+}
+
+// SearchClients lists the clients whose username or email contains "q",
+// optionally only the "active" or "suspended" ones
+func (h *AdminHandler) SearchClients(c *gin.Context) {
+	search := models.ClientSearch{
+		Query:  c.Query("q"),
+		Status: c.Query("status"),
+	}
+	if search.Status != "" && search.Status != "active" && search.Status != "suspended" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status must be active or suspended"})
+		return
+	}
+
+	var ok bool
+	if search.Limit, ok = parseLimit(c, "limit", 50, 500); !ok {
+		return
+	}
+
+	clients, err := h.adminRepo.SearchClients(search)
+	if err != nil {
+		logger.Error("Failed to search clients", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search clients"})
+		return
+	}
+	if clients == nil {
+		clients = []models.Client{}
+	}
+
+	c.JSON(http.StatusOK, clients)
+}
+
+// GetClient returns a client with its mappings by status and its clicks over
+// the last "days" days
+func (h *AdminHandler) GetClient(c *gin.Context) {
+	clientID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	days, ok := parseLimit(c, "days", 30, 366)
+	if !ok {
+		return
+	}
+
+	overview, err := h.adminRepo.GetClientOverview(clientID, usageSince(days))
+	if err != nil {
+		logger.Error("Failed to get client overview", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get client"})
+		return
+	}
+	if overview == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Client not found"})
+		return
+	}
+
+	overview.Days = days
+	c.JSON(http.StatusOK, overview)
+}
+
+// GetClientRedirects lists every mapping a client created
+func (h *AdminHandler) GetClientRedirects(c *gin.Context) {
+	clientID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	mappings, err := h.adminRepo.GetClientMappings(clientID)
+	if err != nil {
+		logger.Error("Failed to get client redirect mappings", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get redirect mappings"})
+		return
+	}
+	if mappings == nil {
+		mappings = []models.RedirectMapping{}
+	}
+
+	c.JSON(http.StatusOK, mappings)
+}
+
+// SuspendClient suspends a client and ends its sessions. Suspended clients
+// cannot log in or use their API keys and postback token; their links keep
+// working until taken down.
+func (h *AdminHandler) SuspendClient(c *gin.Context) {
+	target, request, ok := h.clientAction(c)
+	if !ok {
+		return
+	}
+	if target.IsAdmin {
+		c.JSON(http.StatusConflict, gin.H{"error": "Admins cannot be suspended"})
+		return
+	}
+
+	suspended, err := h.adminRepo.SuspendClient(c.GetInt64("client_id"), target.ID, request.Reason)
+	if err != nil {
+		logger.Error("Failed to suspend client", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to suspend client"})
+		return
+	}
+	if !suspended {
+		c.JSON(http.StatusConflict, gin.H{"error": "Client is already suspended"})
+		return
+	}
+
+	h.endSessions(target.ID)
+	c.Status(http.StatusNoContent)
+}
+
+// UnsuspendClient lifts a client's suspension
+func (h *AdminHandler) UnsuspendClient(c *gin.Context) {
+	clientID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	unsuspended, err := h.adminRepo.UnsuspendClient(c.GetInt64("client_id"), clientID)
+	if err != nil {
+		logger.Error("Failed to unsuspend client", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unsuspend client"})
+		return
+	}
+	if !unsuspended {
+		c.JSON(http.StatusConflict, gin.H{"error": "Client is not suspended"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// ResetClientPassword ends a client's sessions, stops its current password from
+// working and emails it a password reset token. The client cannot log in or use
+// the API again until it sets a new password with the token.
+func (h *AdminHandler) ResetClientPassword(c *gin.Context) {
+	target, request, ok := h.clientAction(c)
+	if !ok {
+		return
+	}
+
+	required, err := h.adminRepo.RequirePasswordReset(c.GetInt64("client_id"), target.ID, request.Reason)
+	if err != nil {
+		logger.Error("Failed to require password reset", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset password"})
+		return
+	}
+	if !required {
+		c.JSON(http.StatusConflict, gin.H{"error": "A password change is already required"})
+		return
+	}
+
+	h.endSessions(target.ID)
+
+	// The requirement stands without the email; the client can ask for
+	// another token through the forgotten password flow
+	if err := h.accounts.SendRequiredReset(target); err != nil {
+		logger.Error("Failed to send password reset", "client_id", target.ID, "error", err)
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// TakeDownRedirect archives a mapping of any client so that it answers 410 Gone
+func (h *AdminHandler) TakeDownRedirect(c *gin.Context) {
+	mappingID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var request models.AdminReason
+	if err := c.ShouldBindJSON(&request); err != nil {
+		logger.Error("Invalid takedown data", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A reason is required"})
+		return
+	}
+
+	found, err := h.adminRepo.TakeDownMapping(c.GetInt64("client_id"), mappingID, request.Reason)
+	if err != nil {
+		logger.Error("Failed to take down redirect mapping", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to take down redirect mapping"})
+		return
+	}
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Redirect mapping not found"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// GetUsage summarizes clients, organizations, mappings and clicks over the
+// last "days" days
+func (h *AdminHandler) GetUsage(c *gin.Context) {
+	days, ok := parseLimit(c, "days", 30, 366)
+	if !ok {
+		return
+	}
+
+	usage, err := h.adminRepo.GetUsage(usageSince(days), adminTopClients)
+	if err != nil {
+		logger.Error("Failed to get platform usage", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get usage"})
+		return
+	}
+
+	usage.Days = days
+	c.JSON(http.StatusOK, usage)
+}
+
+// GetAuditLog lists admin actions, newest first, optionally by admin_id or on
+// one target_type and target_id
+func (h *AdminHandler) GetAuditLog(c *gin.Context) {
+	filter := models.AdminActionFilter{TargetType: c.Query("target_type")}
+	if filter.TargetType != "" && filter.TargetType != models.AdminTargetClient && filter.TargetType != models.AdminTargetMapping {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "target_type must be client or mapping"})
+		return
+	}
+
+	var ok bool
+	if filter.AdminID, ok = parseIDQuery(c, "admin_id"); !ok {
+		return
+	}
+	if filter.TargetID, ok = parseIDQuery(c, "target_id"); !ok {
+		return
+	}
+	if filter.Limit, ok = parseLimit(c, "limit", 100, 1000); !ok {
+		return
+	}
+
+	actions, err := h.adminRepo.GetActions(filter)
+	if err != nil {
+		logger.Error("Failed to get admin actions", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get audit log"})
+		return
+	}
+	if actions == nil {
+		actions = []models.AdminAction{}
+	}
+
+	c.JSON(http.StatusOK, actions)
+}
+
+// clientAction reads the target client and the reason of an action on it,
+// answering 404 if there is no such client
+func (h *AdminHandler) clientAction(c *gin.Context) (*models.Client, *models.AdminReason, bool) {
+	clientID, ok := parseIDParam(c, "id")
+	if !ok {
+		return nil, nil, false
+	}
+
+	var request models.AdminReason
+	if err := c.ShouldBindJSON(&request); err != nil {
+		logger.Error("Invalid admin action data", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A reason is required"})
+		return nil, nil, false
+	}
+
+	target, err := h.clientRepo.GetByID(clientID)
+	if err != nil {
+		logger.Error("Failed to get client", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get client"})
+		return nil, nil, false
+	}
+	if target == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Client not found"})
+		return nil, nil, false
+	}
+
+	return target, &request, true
+}
+
+// endSessions revokes a client's sessions. The action already applies without
+// it, since every request checks the account, so failures are only logged.
+func (h *AdminHandler) endSessions(clientID int64) {
+	if err := h.sessions.EndAll(clientID, ""); err != nil {
+		logger.Error("Failed to end client sessions", "client_id", clientID, "error", err)
+	}
+}
+
+// usageSince returns the first UTC day of a window of days ending today
+func usageSince(days int) time.Time {
+	return time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, 1-days)
+}
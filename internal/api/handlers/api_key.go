@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"platform/internal/auth"
+	"platform/internal/models"
+	"platform/internal/repository/mysql"
+	"platform/pkg/logger"
+	"time"
+)
+
+const (
+	apiKeyPrefix = "ak_"
+	// apiKeyShownPrefix is how many leading characters of a key are kept to
+	// identify it
+	apiKeyShownPrefix = 10
+)
+
+type APIKeyHandler struct {
+	apiKeyRepo *mysql.APIKeyRepository
+}
+
+func NewAPIKeyHandler(apiKeyRepo *mysql.APIKeyRepository) *APIKeyHandler {
+	return &APIKeyHandler{
+		apiKeyRepo: apiKeyRepo,
+	}
+}
+
+// CreateAPIKey creates an API key with the requested scopes. The key is only
+// returned here.
+func (h *APIKeyHandler) CreateAPIKey(c *gin.Context) {
+	var create models.APIKeyCreate
+	if err := c.ShouldBindJSON(&create); err != nil {
+		logger.Error("Invalid API key data", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid API key data"})
+		return
+	}
+	if create.ExpiresAt != nil && !create.ExpiresAt.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "expires_at must be in the future"})
+		return
+	}
+
+	key, keyHash, err := auth.GenerateOpaqueToken(apiKeyPrefix)
+	if err != nil {
+		logger.Error("Failed to generate API key", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create API key"})
+		return
+	}
+
+	apiKey := models.APIKey{
+		ClientID:  c.GetInt64("client_id"),
+		Name:      create.Name,
+		Prefix:    key[:apiKeyShownPrefix],
+		KeyHash:   keyHash,
+		Scopes:    create.Scopes,
+		ExpiresAt: create.ExpiresAt,
+	}
+	if err := h.apiKeyRepo.Create(&apiKey); err != nil {
+		logger.Error("Failed to create API key", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create API key"})
+		return
+	}
+
+	apiKey.CreatedAt = time.Now()
+	c.JSON(http.StatusCreated, models.APIKeyCreated{APIKey: apiKey, Key: key})
+}
+
+// GetAPIKeys lists the client's API keys without the keys themselves
+func (h *APIKeyHandler) GetAPIKeys(c *gin.Context) {
+	keys, err := h.apiKeyRepo.GetClientKeys(c.GetInt64("client_id"))
+	if err != nil {
+		logger.Error("Failed to get API keys", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get API keys"})
+		return
+	}
+
+	c.JSON(http.StatusOK, keys)
+}
+
+// DeleteAPIKey revokes an API key
+func (h *APIKeyHandler) DeleteAPIKey(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	deleted, err := h.apiKeyRepo.Delete(c.GetInt64("client_id"), id)
+	if err != nil {
+		logger.Error("Failed to delete API key", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete API key"})
+		return
+	}
+	if !deleted {
+		c.JSON(http.StatusNotFound, gin.H{"error": "API key not found"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -1,24 +1,35 @@
 package handlers
 
 import (
+	"fmt"
 	"github.com/gin-gonic/gin"
 	"golang.org/x/crypto/bcrypt"
+	"io"
 	"net/http"
+	"platform/internal/account"
 	"platform/internal/auth"
+	"platform/internal/forwarding"
 	"platform/internal/models"
 	"platform/internal/repository/mysql"
 	"platform/pkg/logger"
+	"strings"
 )
 
 type ClientHandler struct {
 	clientRepo   *mysql.ClientRepository
 	redirectRepo *mysql.RedirectRepository
+	sessions     *auth.Sessions
+	accounts     *account.Service
+	policy       *account.Policy
 }
 
-func NewClientHandler(clientRepo *mysql.ClientRepository, redirectRepo *mysql.RedirectRepository) *ClientHandler {
+func NewClientHandler(clientRepo *mysql.ClientRepository, redirectRepo *mysql.RedirectRepository, sessions *auth.Sessions, accounts *account.Service, policy *account.Policy) *ClientHandler {
 	return &ClientHandler{
 		clientRepo:   clientRepo,
 		redirectRepo: redirectRepo,
+		sessions:     sessions,
+		accounts:     accounts,
+		policy:       policy,
 	}
 }
 
@@ -64,21 +75,29 @@ func (h *ClientHandler) Register(c *gin.Context) {
 		return
 	}
 
-	// Generate JWT token
-	token, err := auth.GenerateToken(client.ID, client.Username)
+	// Send the email verification token; the client can ask for another one
+	if err := h.accounts.SendVerification(client); err != nil {
+		logger.Error("Failed to send email verification", "error", err)
+	}
+
+	// Start a session
+	tokens, err := h.sessions.Start(client)
 	if err != nil {
-		logger.Error("Failed to generate token", "error", err)
+		logger.Error("Failed to start session", "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process registration"})
 		return
 	}
 
-	// Return client response with token
+	// Return client response with tokens
 	response := &models.ClientResponse{
-		ID:        client.ID,
-		Username:  client.Username,
-		Email:     client.Email,
-		CreatedAt: client.CreatedAt,
-		Token:     token,
+		ID:            client.ID,
+		Username:      client.Username,
+		Email:         client.Email,
+		EmailVerified: client.EmailVerifiedAt != nil,
+		CreatedAt:     client.CreatedAt,
+		Token:         tokens.Token,
+		ExpiresIn:     tokens.ExpiresIn,
+		RefreshToken:  tokens.RefreshToken,
 	}
 
 	c.JSON(http.StatusCreated, response)
@@ -111,31 +130,89 @@ func (h *ClientHandler) Login(c *gin.Context) {
 		return
 	}
 
-	// Generate JWT token
-	token, err := auth.GenerateToken(client.ID, client.Username)
+	if client.SuspendedAt != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Account suspended"})
+		return
+	}
+
+	// An admin required a new password, which only the emailed reset token sets
+	if client.PasswordResetRequired {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Password reset required"})
+		return
+	}
+
+	// Start a session
+	tokens, err := h.sessions.Start(client)
 	if err != nil {
-		logger.Error("Failed to generate token", "error", err)
+		logger.Error("Failed to start session", "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process login"})
 		return
 	}
 
-	// Return client response with token
+	// Return client response with tokens
 	response := &models.ClientResponse{
-		ID:        client.ID,
-		Username:  client.Username,
-		Email:     client.Email,
-		CreatedAt: client.CreatedAt,
-		Token:     token,
+		ID:            client.ID,
+		Username:      client.Username,
+		Email:         client.Email,
+		EmailVerified: client.EmailVerifiedAt != nil,
+		CreatedAt:     client.CreatedAt,
+		Token:         tokens.Token,
+		ExpiresIn:     tokens.ExpiresIn,
+		RefreshToken:  tokens.RefreshToken,
 	}
 
 	c.JSON(http.StatusOK, response)
 }
 
-// CreateRedirectMapping creates a new redirect mapping for the authenticated client
+// ChangePassword replaces the client's password and ends its other sessions
+func (h *ClientHandler) ChangePassword(c *gin.Context) {
+	var change models.PasswordChange
+	if err := c.ShouldBindJSON(&change); err != nil {
+		logger.Error("Invalid password change data", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid password change data"})
+		return
+	}
+
+	client, err := h.clientRepo.GetByID(c.GetInt64("client_id"))
+	if err != nil {
+		logger.Error("Failed to get client", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to change password"})
+		return
+	}
+	if client == nil || !h.clientRepo.ValidatePassword(client, change.CurrentPassword) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+		return
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(change.NewPassword), bcrypt.DefaultCost)
+	if err != nil {
+		logger.Error("Failed to hash password", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to change password"})
+		return
+	}
+
+	if err := h.clientRepo.SetPasswordHash(client.ID, string(hashedPassword)); err != nil {
+		logger.Error("Failed to set password", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to change password"})
+		return
+	}
+
+	var sessionID string
+	if value, exists := c.Get("token_claims"); exists {
+		sessionID = value.(*auth.Claims).SessionID
+	}
+	if err := h.sessions.EndAll(client.ID, sessionID); err != nil {
+		logger.Error("Failed to end other sessions", "error", err)
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// CreateRedirectMapping creates a new redirect mapping for the authenticated client,
+// owned by its active organization if it has one
 func (h *ClientHandler) CreateRedirectMapping(c *gin.Context) {
-	clientID, exists := c.Get("client_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+	scope := requestScope(c)
+	if !requirePermission(c, scope, models.PermissionEditMappings) {
 		return
 	}
 
@@ -146,12 +223,39 @@ func (h *ClientHandler) CreateRedirectMapping(c *gin.Context) {
 		return
 	}
 
+	if err := forwarding.Check(&mapping.Forwarding); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Unverified accounts may be limited to a few mappings
+	if limit := h.policy.MaxMappings(c.GetBool("email_verified")); limit > 0 {
+		count, err := h.redirectRepo.CountClientMappings(scope.ClientID)
+		if err != nil {
+			logger.Error("Failed to count redirect mappings", "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create redirect mapping"})
+			return
+		}
+		if count >= limit {
+			c.JSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("Verify your email address to create more than %d mappings", limit)})
+			return
+		}
+	}
+
 	redirectMapping := &models.RedirectMapping{
 		RedirectURL:     mapping.RedirectURL,
 		RedirectURLBlack: mapping.RedirectURLBlack,
+		UTM:             mapping.UTM,
+		UTMPolicy:       mapping.UTMPolicy,
+		Forwarding:      mapping.Forwarding,
+		Status:          mapping.Status,
+		InactiveResponse: mapping.InactiveResponse,
+	}
+	if redirectMapping.Status == "" {
+		redirectMapping.Status = models.MappingStatusActive
 	}
 
-	if err := h.redirectRepo.CreateRedirectMapping(clientID.(int64), redirectMapping); err != nil {
+	if err := h.redirectRepo.CreateRedirectMapping(scope, redirectMapping); err != nil {
 		logger.Error("Failed to create redirect mapping", "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create redirect mapping"})
 		return
@@ -160,15 +264,27 @@ func (h *ClientHandler) CreateRedirectMapping(c *gin.Context) {
 	c.JSON(http.StatusCreated, redirectMapping)
 }
 
-// GetRedirectMappings returns all redirect mappings for the authenticated client
+// GetRedirectMappings returns all redirect mappings of the authenticated client or
+// its active organization, optionally only those in the comma-separated statuses
+// of the status parameter
 func (h *ClientHandler) GetRedirectMappings(c *gin.Context) {
-	clientID, exists := c.Get("client_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
-		return
+	scope := requestScope(c)
+
+	var statuses []string
+	if raw := c.Query("status"); raw != "" {
+		for _, status := range strings.Split(raw, ",") {
+			status = strings.TrimSpace(status)
+			switch status {
+			case models.MappingStatusDraft, models.MappingStatusActive, models.MappingStatusPaused, models.MappingStatusArchived:
+				statuses = append(statuses, status)
+			default:
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported status: " + status})
+				return
+			}
+		}
 	}
 
-	mappings, err := h.redirectRepo.GetClientRedirectMappings(clientID.(int64))
+	mappings, err := h.redirectRepo.GetScopedRedirectMappings(scope, statuses)
 	if err != nil {
 		logger.Error("Failed to get redirect mappings", "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get redirect mappings"})
@@ -176,4 +292,235 @@ func (h *ClientHandler) GetRedirectMappings(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, mappings)
-} 
\ No newline at end of file
+}
+
+// GetRedirectHistory returns the clicks served by a mapping within the request's scope
+func (h *ClientHandler) GetRedirectHistory(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	from, to, ok := parseTimeRange(c)
+	if !ok {
+		return
+	}
+
+	limit, ok := parseLimit(c, "limit", 100, 1000)
+	if !ok {
+		return
+	}
+
+	mapping, err := h.redirectRepo.GetScopedMapping(requestScope(c), id)
+	if err != nil {
+		logger.Error("Failed to get redirect mapping", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get redirect history"})
+		return
+	}
+	if mapping == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Redirect mapping not found"})
+		return
+	}
+
+	history, err := h.redirectRepo.GetHistoryByMapping(mapping.ID, from, to, limit)
+	if err != nil {
+		logger.Error("Failed to get redirect history", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get redirect history"})
+		return
+	}
+
+	c.JSON(http.StatusOK, history)
+}
+
+// UpdateRedirectUTM replaces the UTM parameters and policy of a mapping within the request's scope
+func (h *ClientHandler) UpdateRedirectUTM(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var update models.MappingUTMUpdate
+	if err := c.ShouldBindJSON(&update); err != nil {
+		logger.Error("Invalid redirect UTM data", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid redirect UTM data"})
+		return
+	}
+
+	scope := requestScope(c)
+	if !requirePermission(c, scope, models.PermissionEditMappings) {
+		return
+	}
+	mapping, err := h.redirectRepo.GetScopedMapping(scope, id)
+	if err != nil {
+		logger.Error("Failed to get redirect mapping", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update redirect UTM"})
+		return
+	}
+	if mapping == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Redirect mapping not found"})
+		return
+	}
+
+	if err := h.redirectRepo.UpdateMappingUTM(scope, mapping.ID, &update); err != nil {
+		logger.Error("Failed to update redirect UTM", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update redirect UTM"})
+		return
+	}
+	mapping.UTM = update.UTM
+	mapping.UTMPolicy = update.UTMPolicy
+
+	c.JSON(http.StatusOK, mapping)
+}
+
+// UpdateRedirectForwarding replaces the query forwarding policy of a mapping within the request's scope
+func (h *ClientHandler) UpdateRedirectForwarding(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var update models.QueryForwarding
+	if err := c.ShouldBindJSON(&update); err != nil {
+		logger.Error("Invalid redirect forwarding data", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid redirect forwarding data"})
+		return
+	}
+	if err := forwarding.Check(&update); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	scope := requestScope(c)
+	if !requirePermission(c, scope, models.PermissionEditMappings) {
+		return
+	}
+	mapping, err := h.redirectRepo.GetScopedMapping(scope, id)
+	if err != nil {
+		logger.Error("Failed to get redirect mapping", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update redirect forwarding"})
+		return
+	}
+	if mapping == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Redirect mapping not found"})
+		return
+	}
+
+	if err := h.redirectRepo.UpdateMappingForwarding(scope, mapping.ID, &update); err != nil {
+		logger.Error("Failed to update redirect forwarding", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update redirect forwarding"})
+		return
+	}
+	mapping.Forwarding = update
+
+	c.JSON(http.StatusOK, mapping)
+}
+
+// ActivateRedirect makes a draft mapping live
+func (h *ClientHandler) ActivateRedirect(c *gin.Context) {
+	h.transitionRedirect(c, models.MappingActionActivate)
+}
+
+// PauseRedirect stops an active mapping from redirecting until it is resumed
+func (h *ClientHandler) PauseRedirect(c *gin.Context) {
+	h.transitionRedirect(c, models.MappingActionPause)
+}
+
+// ResumeRedirect makes a paused mapping redirect again
+func (h *ClientHandler) ResumeRedirect(c *gin.Context) {
+	h.transitionRedirect(c, models.MappingActionResume)
+}
+
+// ArchiveRedirect retires a mapping for good
+func (h *ClientHandler) ArchiveRedirect(c *gin.Context) {
+	h.transitionRedirect(c, models.MappingActionArchive)
+}
+
+// transitionRedirect applies a lifecycle action to a mapping within the request's scope.
+// The body is optional.
+func (h *ClientHandler) transitionRedirect(c *gin.Context, action string) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var request models.MappingTransitionRequest
+	if err := c.ShouldBindJSON(&request); err != nil && err != io.EOF {
+		logger.Error("Invalid redirect transition data", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid redirect transition data"})
+		return
+	}
+
+	scope := requestScope(c)
+	if !requirePermission(c, scope, models.PermissionEditMappings) {
+		return
+	}
+	mapping, err := h.redirectRepo.GetScopedMapping(scope, id)
+	if err != nil {
+		logger.Error("Failed to get redirect mapping", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update redirect status"})
+		return
+	}
+	if mapping == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Redirect mapping not found"})
+		return
+	}
+
+	status, ok := models.NextStatus(action, mapping.Status)
+	if !ok {
+		c.JSON(http.StatusConflict, gin.H{"error": "Cannot " + action + " a " + mapping.Status + " redirect mapping"})
+		return
+	}
+
+	changed, err := h.redirectRepo.TransitionMapping(scope, mapping.ID, mapping.Status, status, &request)
+	if err != nil {
+		logger.Error("Failed to update redirect status", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update redirect status"})
+		return
+	}
+	if !changed {
+		c.JSON(http.StatusConflict, gin.H{"error": "Redirect mapping status changed concurrently"})
+		return
+	}
+
+	mapping.Status = status
+	if request.InactiveResponse != "" {
+		mapping.InactiveResponse = request.InactiveResponse
+	}
+	c.JSON(http.StatusOK, mapping)
+}
+
+// GetRedirectStatusHistory returns the status changes of a mapping within the request's scope
+func (h *ClientHandler) GetRedirectStatusHistory(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	limit, ok := parseLimit(c, "limit", 100, 1000)
+	if !ok {
+		return
+	}
+
+	mapping, err := h.redirectRepo.GetScopedMapping(requestScope(c), id)
+	if err != nil {
+		logger.Error("Failed to get redirect mapping", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get redirect status history"})
+		return
+	}
+	if mapping == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Redirect mapping not found"})
+		return
+	}
+
+	changes, err := h.redirectRepo.GetStatusChanges(mapping.ID, limit)
+	if err != nil {
+		logger.Error("Failed to get redirect status history", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get redirect status history"})
+		return
+	}
+	if changes == nil {
+		changes = []models.MappingStatusChange{}
+	}
+
+	c.JSON(http.StatusOK, changes)
+}
\ No newline at end of file
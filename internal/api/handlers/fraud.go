@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"platform/internal/models"
+	"platform/internal/repository/mysql"
+	"platform/pkg/logger"
+)
+
+type FraudHandler struct {
+	redirectRepo *mysql.RedirectRepository
+	flagScore    int
+}
+
+func NewFraudHandler(redirectRepo *mysql.RedirectRepository, flagScore int) *FraudHandler {
+	return &FraudHandler{
+		redirectRepo: redirectRepo,
+		flagScore:    flagScore,
+	}
+}
+
+// GetFlaggedClicks lists the clicks within the request's scope scoring at least
+// min_score, which defaults to the platform's flag threshold, optionally for one
+// mapping_id
+func (h *FraudHandler) GetFlaggedClicks(c *gin.Context) {
+	from, to, ok := parseTimeRange(c)
+	if !ok {
+		return
+	}
+
+	minScore, ok := parseLimit(c, "min_score", h.flagScore, models.MaxFraudScore)
+	if !ok {
+		return
+	}
+
+	limit, ok := parseLimit(c, "limit", 100, 1000)
+	if !ok {
+		return
+	}
+
+	mappingID, ok := parseIDQuery(c, "mapping_id")
+	if !ok {
+		return
+	}
+
+	clicks, err := h.redirectRepo.GetFlaggedHistory(requestScope(c), mappingID, minScore, from, to, limit)
+	if err != nil {
+		logger.Error("Failed to get flagged clicks", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get flagged clicks"})
+		return
+	}
+
+	c.JSON(http.StatusOK, clicks)
+}
@@ -0,0 +1,28 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"platform/internal/auth"
+)
+
+// jwksMaxAge is how long verifiers may cache the key set. Keys being rotated
+// in should be listed as verification keys at least this long before use.
+const jwksMaxAge = "300"
+
+type JWKSHandler struct {
+	signer *auth.Signer
+}
+
+func NewJWKSHandler(signer *auth.Signer) *JWKSHandler {
+	return &JWKSHandler{
+		signer: signer,
+	}
+}
+
+// GetJWKS publishes the public keys that verify access tokens, so other
+// services can verify them without sharing a secret
+func (h *JWKSHandler) GetJWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age="+jwksMaxAge)
+	c.JSON(http.StatusOK, h.signer.JWKS())
+}
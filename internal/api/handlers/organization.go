@@ -0,0 +1,389 @@
+package handlers
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"platform/internal/auth"
+	"platform/internal/models"
+	"platform/internal/repository/mysql"
+	"platform/pkg/logger"
+	"strings"
+	"time"
+)
+
+const invitationTokenPrefix = "inv_"
+
+type OrganizationHandler struct {
+	orgRepo       *mysql.OrganizationRepository
+	clientRepo    *mysql.ClientRepository
+	invitationTTL time.Duration
+}
+
+func NewOrganizationHandler(orgRepo *mysql.OrganizationRepository, clientRepo *mysql.ClientRepository, invitationTTL time.Duration) *OrganizationHandler {
+	return &OrganizationHandler{
+		orgRepo:       orgRepo,
+		clientRepo:    clientRepo,
+		invitationTTL: invitationTTL,
+	}
+}
+
+// CreateOrganization creates an organization with the client as its owner
+func (h *OrganizationHandler) CreateOrganization(c *gin.Context) {
+	var create models.OrganizationCreate
+	if err := c.ShouldBindJSON(&create); err != nil {
+		logger.Error("Invalid organization data", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid organization data"})
+		return
+	}
+
+	org := &models.Organization{
+		Name:      create.Name,
+		CreatedBy: c.GetInt64("client_id"),
+	}
+	if err := h.orgRepo.Create(org); err != nil {
+		logger.Error("Failed to create organization", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create organization"})
+		return
+	}
+
+	org.CreatedAt = time.Now()
+	c.JSON(http.StatusCreated, org)
+}
+
+// GetOrganizations lists the organizations the client is a member of with its role
+func (h *OrganizationHandler) GetOrganizations(c *gin.Context) {
+	orgs, err := h.orgRepo.GetClientOrganizations(c.GetInt64("client_id"))
+	if err != nil {
+		logger.Error("Failed to get organizations", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get organizations"})
+		return
+	}
+	if orgs == nil {
+		orgs = []models.Organization{}
+	}
+
+	c.JSON(http.StatusOK, orgs)
+}
+
+// DeleteOrganization deletes an organization that no longer owns mappings
+func (h *OrganizationHandler) DeleteOrganization(c *gin.Context) {
+	orgID, _, ok := h.authorize(c, models.PermissionManageOwners)
+	if !ok {
+		return
+	}
+
+	if err := h.orgRepo.Delete(orgID); err != nil {
+		if errors.Is(err, mysql.ErrOrganizationNotEmpty) {
+			c.JSON(http.StatusConflict, gin.H{"error": "The organization still owns mappings"})
+			return
+		}
+		logger.Error("Failed to delete organization", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete organization"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// GetMembers lists the members of an organization
+func (h *OrganizationHandler) GetMembers(c *gin.Context) {
+	orgID, _, ok := h.authorize(c, models.PermissionViewMappings)
+	if !ok {
+		return
+	}
+
+	members, err := h.orgRepo.GetMembers(orgID)
+	if err != nil {
+		logger.Error("Failed to get organization members", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get organization members"})
+		return
+	}
+
+	c.JSON(http.StatusOK, members)
+}
+
+// UpdateMember changes the role of a member. Only owners can grant or revoke
+// the owner role.
+func (h *OrganizationHandler) UpdateMember(c *gin.Context) {
+	orgID, actor, ok := h.authorize(c, models.PermissionManageMembers)
+	if !ok {
+		return
+	}
+	clientID, ok := parseIDParam(c, "client_id")
+	if !ok {
+		return
+	}
+
+	var update models.OrganizationMemberUpdate
+	if err := c.ShouldBindJSON(&update); err != nil {
+		logger.Error("Invalid organization member data", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid organization member data"})
+		return
+	}
+
+	target, ok := h.targetMember(c, orgID, clientID)
+	if !ok {
+		return
+	}
+	if (target.Role == models.OrgRoleOwner || update.Role == models.OrgRoleOwner) &&
+		!models.RoleAllows(actor.Role, models.PermissionManageOwners) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only owners can grant or revoke the owner role"})
+		return
+	}
+
+	updated, err := h.orgRepo.SetMemberRole(orgID, clientID, update.Role)
+	if !h.memberChanged(c, updated, err) {
+		return
+	}
+
+	target.Role = update.Role
+	c.JSON(http.StatusOK, target)
+}
+
+// RemoveMember removes a member from an organization. Members can remove
+// themselves; removing an owner takes an owner.
+func (h *OrganizationHandler) RemoveMember(c *gin.Context) {
+	orgID, actor, ok := h.authorize(c, models.PermissionViewMappings)
+	if !ok {
+		return
+	}
+	clientID, ok := parseIDParam(c, "client_id")
+	if !ok {
+		return
+	}
+
+	if clientID != actor.ClientID {
+		if !requireRole(c, actor, models.PermissionManageMembers) {
+			return
+		}
+		target, ok := h.targetMember(c, orgID, clientID)
+		if !ok {
+			return
+		}
+		if target.Role == models.OrgRoleOwner && !requireRole(c, actor, models.PermissionManageOwners) {
+			return
+		}
+	}
+
+	removed, err := h.orgRepo.RemoveMember(orgID, clientID)
+	if !h.memberChanged(c, removed, err) {
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// CreateInvitation invites the account with an email address to join the
+// organization. The token is only returned here; the invitee accepts it
+// while logged in. Only owners can invite owners.
+func (h *OrganizationHandler) CreateInvitation(c *gin.Context) {
+	orgID, actor, ok := h.authorize(c, models.PermissionManageMembers)
+	if !ok {
+		return
+	}
+
+	var create models.OrganizationInvitationCreate
+	if err := c.ShouldBindJSON(&create); err != nil {
+		logger.Error("Invalid organization invitation data", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid organization invitation data"})
+		return
+	}
+	if create.Role == models.OrgRoleOwner && !requireRole(c, actor, models.PermissionManageOwners) {
+		return
+	}
+
+	token, tokenHash, err := auth.GenerateOpaqueToken(invitationTokenPrefix)
+	if err != nil {
+		logger.Error("Failed to generate invitation token", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create organization invitation"})
+		return
+	}
+
+	invitation := models.OrganizationInvitation{
+		OrganizationID: orgID,
+		Email:          create.Email,
+		Role:           create.Role,
+		TokenHash:      tokenHash,
+		InvitedBy:      actor.ClientID,
+		ExpiresAt:      time.Now().Add(h.invitationTTL),
+	}
+	if err := h.orgRepo.CreateInvitation(&invitation); err != nil {
+		logger.Error("Failed to create organization invitation", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create organization invitation"})
+		return
+	}
+
+	invitation.CreatedAt = time.Now()
+	c.JSON(http.StatusCreated, models.OrganizationInvitationCreated{OrganizationInvitation: invitation, Token: token})
+}
+
+// GetInvitations lists the organization's pending invitations
+func (h *OrganizationHandler) GetInvitations(c *gin.Context) {
+	orgID, _, ok := h.authorize(c, models.PermissionManageMembers)
+	if !ok {
+		return
+	}
+
+	invitations, err := h.orgRepo.GetPendingInvitations(orgID)
+	if err != nil {
+		logger.Error("Failed to get organization invitations", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get organization invitations"})
+		return
+	}
+	if invitations == nil {
+		invitations = []models.OrganizationInvitation{}
+	}
+
+	c.JSON(http.StatusOK, invitations)
+}
+
+// DeleteInvitation withdraws a pending invitation
+func (h *OrganizationHandler) DeleteInvitation(c *gin.Context) {
+	orgID, _, ok := h.authorize(c, models.PermissionManageMembers)
+	if !ok {
+		return
+	}
+	id, ok := parseIDParam(c, "invitation_id")
+	if !ok {
+		return
+	}
+
+	deleted, err := h.orgRepo.DeleteInvitation(orgID, id)
+	if err != nil {
+		logger.Error("Failed to delete organization invitation", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete organization invitation"})
+		return
+	}
+	if !deleted {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invitation not found"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// AcceptInvitation adds the client to the organization of an invitation sent
+// to its email address
+func (h *OrganizationHandler) AcceptInvitation(c *gin.Context) {
+	var accept models.OrganizationInvitationAccept
+	if err := c.ShouldBindJSON(&accept); err != nil {
+		logger.Error("Invalid invitation data", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid invitation data"})
+		return
+	}
+
+	invitation, err := h.orgRepo.GetInvitationByHash(auth.HashOpaqueToken(accept.Token))
+	if err != nil {
+		logger.Error("Failed to get organization invitation", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to accept invitation"})
+		return
+	}
+	if invitation == nil || invitation.AcceptedAt != nil || !time.Now().Before(invitation.ExpiresAt) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invitation not found or expired"})
+		return
+	}
+
+	client, err := h.clientRepo.GetByID(c.GetInt64("client_id"))
+	if err != nil {
+		logger.Error("Failed to get client", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to accept invitation"})
+		return
+	}
+	if client == nil || !strings.EqualFold(client.Email, invitation.Email) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "The invitation was sent to another email address"})
+		return
+	}
+
+	accepted, err := h.orgRepo.AcceptInvitation(invitation, client.ID)
+	if errors.Is(err, mysql.ErrDuplicate) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Already a member of the organization"})
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to accept organization invitation", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to accept invitation"})
+		return
+	}
+	if !accepted {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invitation not found or expired"})
+		return
+	}
+
+	member, err := h.orgRepo.GetMember(invitation.OrganizationID, client.ID)
+	if err != nil {
+		logger.Error("Failed to get organization member", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to accept invitation"})
+		return
+	}
+
+	c.JSON(http.StatusOK, member)
+}
+
+// authorize reads the organization ID path parameter and checks that the
+// client is a member whose role grants permission. Organizations the client
+// is not a member of answer 404.
+func (h *OrganizationHandler) authorize(c *gin.Context, permission string) (int64, *models.OrganizationMember, bool) {
+	orgID, ok := parseIDParam(c, "id")
+	if !ok {
+		return 0, nil, false
+	}
+
+	member, err := h.orgRepo.GetMember(orgID, c.GetInt64("client_id"))
+	if err != nil {
+		logger.Error("Failed to get organization member", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get organization"})
+		return 0, nil, false
+	}
+	if member == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Organization not found"})
+		return 0, nil, false
+	}
+	if !requireRole(c, member, permission) {
+		return 0, nil, false
+	}
+
+	return orgID, member, true
+}
+
+// targetMember returns the member a request changes, answering 404 if there is none
+func (h *OrganizationHandler) targetMember(c *gin.Context, orgID, clientID int64) (*models.OrganizationMember, bool) {
+	member, err := h.orgRepo.GetMember(orgID, clientID)
+	if err != nil {
+		logger.Error("Failed to get organization member", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update organization member"})
+		return nil, false
+	}
+	if member == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Organization member not found"})
+		return nil, false
+	}
+	return member, true
+}
+
+// memberChanged answers the outcome of a member change that did not succeed
+func (h *OrganizationHandler) memberChanged(c *gin.Context, changed bool, err error) bool {
+	if errors.Is(err, mysql.ErrLastOwner) {
+		c.JSON(http.StatusConflict, gin.H{"error": "The organization must keep an owner"})
+		return false
+	}
+	if err != nil {
+		logger.Error("Failed to update organization member", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update organization member"})
+		return false
+	}
+	if !changed {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Organization member not found"})
+		return false
+	}
+	return true
+}
+
+// requireRole answers 403 unless the member's role grants permission
+func requireRole(c *gin.Context, member *models.OrganizationMember, permission string) bool {
+	if !models.RoleAllows(member.Role, permission) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Your organization role does not allow this"})
+		return false
+	}
+	return true
+}
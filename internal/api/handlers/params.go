@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// defaultStatsWindow is the time range used when a request gives no "from"
+const defaultStatsWindow = 30 * 24 * time.Hour
+
+// parseIDParam reads a numeric path parameter, answering 400 when it is malformed
+func parseIDParam(c *gin.Context, name string) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param(name), 10, 64)
+	if err != nil || id < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + name})
+		return 0, false
+	}
+	return id, true
+}
+
+// parseIDQuery reads an optional numeric query parameter, answering 400 when
+// it is malformed
+func parseIDQuery(c *gin.Context, name string) (int64, bool) {
+	raw := c.Query(name)
+	if raw == "" {
+		return 0, true
+	}
+
+	id, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil || id < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + name})
+		return 0, false
+	}
+	return id, true
+}
+
+// parseTimeRange reads the "from" and "to" query parameters (RFC 3339 or YYYY-MM-DD),
+// defaulting to the last 30 days, and answers 400 when they are malformed
+func parseTimeRange(c *gin.Context) (time.Time, time.Time, bool) {
+	return parseTimeRangeIn(c, time.UTC)
+}
+
+// parseTimeRangeIn is parseTimeRange with plain dates interpreted in loc
+func parseTimeRangeIn(c *gin.Context, loc *time.Location) (time.Time, time.Time, bool) {
+	to := time.Now()
+	if raw := c.Query("to"); raw != "" {
+		parsed, err := parseTimeParam(raw, loc)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to: " + err.Error()})
+			return time.Time{}, time.Time{}, false
+		}
+		to = parsed
+	}
+
+	from := to.Add(-defaultStatsWindow)
+	if raw := c.Query("from"); raw != "" {
+		parsed, err := parseTimeParam(raw, loc)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from: " + err.Error()})
+			return time.Time{}, time.Time{}, false
+		}
+		from = parsed
+	}
+
+	if !from.Before(to) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from must be before to"})
+		return time.Time{}, time.Time{}, false
+	}
+
+	return from, to, true
+}
+
+func parseTimeParam(raw string, loc *time.Location) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", raw, loc); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("expected RFC 3339 timestamp or YYYY-MM-DD date")
+}
+
+// parseLimit reads an optional positive integer query parameter bounded by max
+func parseLimit(c *gin.Context, name string, def, max int) (int, bool) {
+	raw := c.Query(name)
+	if raw == "" {
+		return def, true
+	}
+
+	limit, err := strconv.Atoi(raw)
+	if err != nil || limit < 1 || limit > max {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("%s must be between 1 and %d", name, max)})
+		return 0, false
+	}
+	return limit, true
+}
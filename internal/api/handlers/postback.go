@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"platform/internal/auth"
+	"platform/internal/models"
+	"platform/internal/repository/mysql"
+	"platform/pkg/logger"
+	"strings"
+	"time"
+)
+
+const (
+	postbackTokenPrefix = "pb_"
+	postbackTokenHeader = "X-Postback-Token"
+	defaultEventName    = "conversion"
+)
+
+type PostbackHandler struct {
+	clientRepo     *mysql.ClientRepository
+	conversionRepo *mysql.ConversionRepository
+}
+
+func NewPostbackHandler(clientRepo *mysql.ClientRepository, conversionRepo *mysql.ConversionRepository) *PostbackHandler {
+	return &PostbackHandler{
+		clientRepo:     clientRepo,
+		conversionRepo: conversionRepo,
+	}
+}
+
+// Postback records a server-to-server conversion for a previously served click.
+// The client token is read from the X-Postback-Token header or the "token" parameter.
+func (h *PostbackHandler) Postback(c *gin.Context) {
+	token := c.GetHeader(postbackTokenHeader)
+	if token == "" {
+		token = c.Query("token")
+	}
+	if token == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Postback token is required"})
+		return
+	}
+
+	client, err := h.clientRepo.GetByPostbackTokenHash(auth.HashOpaqueToken(token))
+	if err != nil {
+		logger.Error("Failed to get client by postback token", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process postback"})
+		return
+	}
+	if client == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid postback token"})
+		return
+	}
+	if client.SuspendedAt != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Account suspended"})
+		return
+	}
+
+	var postback models.PostbackRequest
+	if err := c.ShouldBind(&postback); err != nil {
+		logger.Error("Invalid postback data", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid postback data"})
+		return
+	}
+	if postback.EventName == "" {
+		postback.EventName = defaultEventName
+	}
+
+	click, err := h.conversionRepo.FindClick(client.ID, postback.ClickID)
+	if err != nil {
+		logger.Error("Failed to find click for postback", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process postback"})
+		return
+	}
+	if click == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Click not found"})
+		return
+	}
+
+	conversion := &models.Conversion{
+		ClientID:          client.ID,
+		OrganizationID:    click.OrganizationID,
+		MappingID:         click.MappingID,
+		RedirectHistoryID: click.RedirectHistoryID,
+		ClickID:           postback.ClickID,
+		EventName:         postback.EventName,
+		Variant:           click.Variant,
+		Payout:            postback.Payout,
+		Currency:          strings.ToUpper(postback.Currency),
+		IPAddress:         c.ClientIP(),
+	}
+	if err := h.conversionRepo.Create(conversion); err != nil {
+		if errors.Is(err, mysql.ErrDuplicate) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Conversion already recorded"})
+			return
+		}
+		logger.Error("Failed to create conversion", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process postback"})
+		return
+	}
+
+	conversion.CreatedAt = time.Now()
+	c.JSON(http.StatusCreated, conversion)
+}
+
+// RotatePostbackToken issues a new postback token for the authenticated client.
+// The token is only shown in this response; the previous token stops working.
+func (h *PostbackHandler) RotatePostbackToken(c *gin.Context) {
+	token, tokenHash, err := auth.GenerateOpaqueToken(postbackTokenPrefix)
+	if err != nil {
+		logger.Error("Failed to generate postback token", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate postback token"})
+		return
+	}
+
+	if err := h.clientRepo.SetPostbackTokenHash(c.GetInt64("client_id"), tokenHash); err != nil {
+		logger.Error("Failed to store postback token", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate postback token"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"token": token})
+}
+
+// GetConversionStats returns conversion counts and rates per mapping and variant
+// within the request's scope
+func (h *PostbackHandler) GetConversionStats(c *gin.Context) {
+	from, to, ok := parseTimeRange(c)
+	if !ok {
+		return
+	}
+
+	stats, err := h.conversionRepo.GetStats(requestScope(c), from, to, c.Query("include_bots") == "true")
+	if err != nil {
+		logger.Error("Failed to get conversion stats", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get conversion stats"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"from":  from,
+		"to":    to,
+		"stats": stats,
+	})
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"platform/internal/models"
+	"platform/internal/privacy"
+	"platform/internal/repository/mysql"
+	"platform/pkg/logger"
+)
+
+type PrivacyHandler struct {
+	privacyRepo *mysql.PrivacyRepository
+	resolver    *privacy.Resolver
+}
+
+func NewPrivacyHandler(privacyRepo *mysql.PrivacyRepository, resolver *privacy.Resolver) *PrivacyHandler {
+	return &PrivacyHandler{
+		privacyRepo: privacyRepo,
+		resolver:    resolver,
+	}
+}
+
+// GetPrivacy returns the platform privacy policy and the client's override
+func (h *PrivacyHandler) GetPrivacy(c *gin.Context) {
+	settings, err := h.privacyRepo.GetSettings(c.GetInt64("client_id"))
+	if err != nil {
+		logger.Error("Failed to get privacy settings", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get privacy settings"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.PrivacySetting{
+		Platform: h.resolver.Platform().View(),
+		Override: settings,
+	})
+}
+
+// SetPrivacy replaces the client's privacy settings. They apply to new clicks;
+// stored request logs are rewritten by the scrub-pii command.
+func (h *PrivacyHandler) SetPrivacy(c *gin.Context) {
+	var update models.PrivacySettingsUpdate
+	if err := c.ShouldBindJSON(&update); err != nil {
+		logger.Error("Invalid privacy settings data", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid privacy settings data"})
+		return
+	}
+
+	if err := h.resolver.Platform().CheckSettings(&update); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	clientID := c.GetInt64("client_id")
+	if err := h.privacyRepo.SetSettings(clientID, &update); err != nil {
+		logger.Error("Failed to set privacy settings", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to set privacy settings"})
+		return
+	}
+	h.resolver.Forget(clientID)
+
+	h.GetPrivacy(c)
+}
+
+// DeletePrivacy removes the client's privacy settings, restoring the platform policy
+func (h *PrivacyHandler) DeletePrivacy(c *gin.Context) {
+	clientID := c.GetInt64("client_id")
+	deleted, err := h.privacyRepo.DeleteSettings(clientID)
+	if err != nil {
+		logger.Error("Failed to delete privacy settings", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete privacy settings"})
+		return
+	}
+	if !deleted {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Privacy settings not found"})
+		return
+	}
+	h.resolver.Forget(clientID)
+
+	c.Status(http.StatusNoContent)
+}
@@ -1,13 +1,19 @@
 package handlers
 
 import (
-	"encoding/json"
-	"fmt"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"net/http"
+	"net/url"
+	"platform/internal/forwarding"
+	"platform/internal/geoip"
+	"platform/internal/lifecycle"
 	"platform/internal/models"
+	"platform/internal/privacy"
 	"platform/internal/repository/mysql"
 	"platform/internal/repository/rabbitmq"
+	"platform/internal/utm"
+	"platform/internal/visitor"
 	"platform/pkg/logger"
 	"time"
 )
@@ -15,12 +21,22 @@ import (
 type RequestHandler struct {
 	publisher        *rabbitmq.Publisher
 	redirectRepo     *mysql.RedirectRepository
+	privacy          *privacy.Resolver
+	locator          *geoip.Locator
+	utm              *utm.Resolver
+	visitors         *visitor.Identifier
+	lifecycle        *lifecycle.Responder
 }
 
-func NewRequestHandler(publisher *rabbitmq.Publisher, redirectRepo *mysql.RedirectRepository) *RequestHandler {
+func NewRequestHandler(publisher *rabbitmq.Publisher, redirectRepo *mysql.RedirectRepository, privacy *privacy.Resolver, locator *geoip.Locator, utm *utm.Resolver, visitors *visitor.Identifier, lifecycle *lifecycle.Responder) *RequestHandler {
 	return &RequestHandler{
 		publisher:    publisher,
 		redirectRepo: redirectRepo,
+		privacy:      privacy,
+		locator:      locator,
+		utm:          utm,
+		visitors:     visitors,
+		lifecycle:    lifecycle,
 	}
 }
 
@@ -48,54 +64,104 @@ func (h *RequestHandler) ProcessRequest(c *gin.Context) {
 		return
 	}
 
-	// Convert headers to JSON
-	headers := make(map[string]string)
-	for k, v := range c.Request.Header {
-		if len(v) > 0 {
-			headers[k] = v[0]
-		}
+	// Get redirect mapping from database
+	mapping, err := h.redirectRepo.GetMappingByHash(hash)
+	if err != nil {
+		logger.Error("Failed to get redirect mapping", "error", err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process request"})
+		return
+	}
+
+	// Apply the privacy policy of the mapping's client before anything is published;
+	// organization mappings follow the platform policy
+	var clientID int64
+	if mapping != nil {
+		clientID = mapping.SettingsClientID()
 	}
-	headersJSON, err := json.Marshal(headers)
+	policy, err := h.privacy.For(clientID)
 	if err != nil {
-		headersJSON = []byte("{}")
+		logger.Error("Failed to get privacy policy", "error", err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process request"})
+		return
 	}
 
 	// Create request log
-	request := &models.Request{
-		Timestamp:      time.Now(),
-		IPAddress:      c.ClientIP(),
-		RequestURL:     c.Request.URL.String(),
-		RequestMethod:  c.Request.Method,
-		RequestHeaders: headersJSON,
+	now := time.Now()
+	request := models.Request{
+		RequestID:     c.GetString("RequestID"),
+		Timestamp:     now,
+		RequestURL:    c.Request.URL.String(),
+		RequestMethod: c.Request.Method,
+		// Locate the full IP before the privacy policy anonymizes it
+		Geo: h.locator.Lookup(c.ClientIP()),
 	}
+	policy.Apply(&request, c.ClientIP(), c.Request.Header)
 
-	// Get redirect URL from database
-	redirectURL, err := h.redirectRepo.GetRedirectURL(hash)
-	if err != nil {
-		logger.Error("Failed to get redirect URL", "error", err.Error())
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process request"})
+	event := &models.ClickEvent{
+		EventID:   uuid.New().String(),
+		RequestID: request.RequestID,
+		ClickID:   clickID,
+		Hash:      hash,
+		Timestamp: now,
+		Request:   request,
+	}
+
+	if mapping != nil && mapping.Status != models.MappingStatusActive {
+		h.serveInactive(c, event, mapping)
 		return
 	}
 
-	if redirectURL != "" {
-		// Store request in RabbitMQ
-		if err := h.publisher.PublishRequest(request); err != nil {
-			logger.Error("Failed to publish request", "error", err.Error())
+	if mapping != nil {
+		// Merge forwarded, UTM and click_id parameters into redirect URL
+		finalURL, forwarded, err := h.destination(mapping, c.Request.URL.Query(), clickID)
+		if err != nil {
+			logger.Error("Failed to build redirect URL", "error", err.Error(), "mapping_id", mapping.ID)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process request"})
 			return
 		}
 
-		// Append click_id parameter to redirect URL
-		finalURL := fmt.Sprintf("%s?click_id=%s", redirectURL, clickID)
+		event.MappingID = mapping.ID
+		event.ClientID = mapping.ClientID
+		if mapping.OrganizationID != nil {
+			event.OrganizationID = *mapping.OrganizationID
+		}
+		event.Destination = finalURL
+		event.ForwardedParams = forwarded
+		event.Variant = models.VariantPrimary
+		event.StatusCode = http.StatusTemporaryRedirect
+
+		// Identify the visitor from the full IP, before it is anonymized
+		visitorID, fromCookie, err := h.visitors.Identify(c.Request, c.ClientIP())
+		if err != nil {
+			logger.Error("Failed to identify visitor", "error", err.Error())
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process request"})
+			return
+		}
+		event.VisitorID = visitorID
+
+		// Store click event in RabbitMQ
+		if err := h.publisher.PublishClickEvent(event); err != nil {
+			logger.Error("Failed to publish click event", "error", err.Error())
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process request"})
+			return
+		}
+
+		// Keep the visitor ID in the cookie for later clicks
+		if !fromCookie {
+			if cookie := h.visitors.Cookie(visitorID); cookie != nil {
+				http.SetCookie(c.Writer, cookie)
+			}
+		}
 
 		// Redirect to the appropriate site
-		c.Redirect(http.StatusTemporaryRedirect, finalURL)
+		c.Redirect(event.StatusCode, finalURL)
 		return
 	}
 
-	// If no redirect URL found, just store the request
-	if err := h.publisher.PublishRequest(request); err != nil {
-		logger.Error("Failed to publish request", "error", err.Error())
+	// If no redirect mapping found, just store the request
+	event.StatusCode = http.StatusOK
+	if err := h.publisher.PublishClickEvent(event); err != nil {
+		logger.Error("Failed to publish click event", "error", err.Error())
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process request"})
 		return
 	}
@@ -104,4 +170,82 @@ func (h *RequestHandler) ProcessRequest(c *gin.Context) {
 		"message": "Request processed successfully",
 		"request": request,
 	})
-} 
\ No newline at end of file
+}
+
+// serveInactive records a click on a mapping that is not active and serves the
+// mapping's inactive response. Responses are not cached, so the link works
+// again as soon as it is resumed.
+func (h *RequestHandler) serveInactive(c *gin.Context, event *models.ClickEvent, mapping *models.RedirectMapping) {
+	response := h.lifecycle.ResponseFor(mapping)
+
+	event.MappingID = mapping.ID
+	event.ClientID = mapping.ClientID
+	if mapping.OrganizationID != nil {
+		event.OrganizationID = *mapping.OrganizationID
+	}
+	event.Variant = models.VariantInactive
+	switch response {
+	case models.InactiveResponseFallback:
+		fallbackURL, err := url.Parse(mapping.RedirectURLBlack)
+		if err != nil {
+			logger.Error("Failed to build fallback URL", "error", err.Error(), "mapping_id", mapping.ID)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process request"})
+			return
+		}
+		query := fallbackURL.Query()
+		query.Set(forwarding.ReservedParam, event.ClickID)
+		fallbackURL.RawQuery = query.Encode()
+
+		event.Destination = fallbackURL.String()
+		event.Variant = models.VariantBlack
+		event.StatusCode = http.StatusTemporaryRedirect
+	case models.InactiveResponseGone:
+		event.StatusCode = http.StatusGone
+	default:
+		event.StatusCode = http.StatusOK
+	}
+
+	if err := h.publisher.PublishClickEvent(event); err != nil {
+		logger.Error("Failed to publish click event", "error", err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process request"})
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	switch response {
+	case models.InactiveResponseFallback:
+		c.Redirect(event.StatusCode, event.Destination)
+	case models.InactiveResponseGone:
+		c.JSON(event.StatusCode, gin.H{"error": "This link is no longer available"})
+	default:
+		c.Data(event.StatusCode, "text/html; charset=utf-8", h.lifecycle.HoldingPage())
+	}
+}
+
+// destination builds the URL a click on the mapping is redirected to and
+// returns the click's parameters it forwarded. Forwarded parameters replace
+// those already on the destination; the mapping's UTM parameters, inheriting
+// the client's defaults on a personal mapping, are then merged per the UTM
+// policy, and click_id always replaces an existing one.
+func (h *RequestHandler) destination(mapping *models.RedirectMapping, incoming url.Values, clickID string) (string, url.Values, error) {
+	u, err := url.Parse(mapping.RedirectURL)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var defaults *models.UTMSettings
+	if clientID := mapping.SettingsClientID(); clientID != 0 {
+		defaults, err = h.utm.For(clientID)
+		if err != nil {
+			return "", nil, err
+		}
+	}
+	params, policy := utm.Effective(mapping, defaults)
+
+	query := u.Query()
+	forwarded := forwarding.Apply(query, incoming, mapping.Forwarding)
+	utm.Merge(query, params, policy)
+	query.Set(forwarding.ReservedParam, clickID)
+	u.RawQuery = query.Encode()
+	return u.String(), forwarded, nil
+}
\ No newline at end of file
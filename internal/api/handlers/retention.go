@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"platform/internal/config"
+	"platform/internal/models"
+	"platform/internal/repository/mysql"
+	"platform/internal/retention"
+	"platform/pkg/logger"
+)
+
+type RetentionHandler struct {
+	retentionRepo *mysql.RetentionRepository
+	cfg           config.RetentionConfig
+}
+
+func NewRetentionHandler(retentionRepo *mysql.RetentionRepository, cfg config.RetentionConfig) *RetentionHandler {
+	return &RetentionHandler{
+		retentionRepo: retentionRepo,
+		cfg:           cfg,
+	}
+}
+
+// GetRetention returns how long each raw click table keeps the client's data
+func (h *RetentionHandler) GetRetention(c *gin.Context) {
+	policies, err := h.retentionRepo.GetClientPolicies(c.GetInt64("client_id"))
+	if err != nil {
+		logger.Error("Failed to get retention policies", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get retention"})
+		return
+	}
+
+	overrides := make(map[string]int, len(policies))
+	for _, policy := range policies {
+		overrides[policy.Table] = policy.Days
+	}
+
+	settings := make([]models.RetentionSetting, 0, len(models.RetentionTables))
+	for _, table := range models.RetentionTables {
+		setting := models.RetentionSetting{
+			Table:       table,
+			DefaultDays: retention.DefaultDays(h.cfg, table),
+		}
+		setting.EffectiveDays = setting.DefaultDays
+		if days, ok := overrides[table]; ok {
+			setting.OverrideDays = &days
+			setting.EffectiveDays = days
+		}
+		settings = append(settings, setting)
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+// SetRetention overrides the retention of one table for the client
+func (h *RetentionHandler) SetRetention(c *gin.Context) {
+	var update models.RetentionPolicyUpdate
+	if err := c.ShouldBindJSON(&update); err != nil {
+		logger.Error("Invalid retention policy data", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid retention policy data"})
+		return
+	}
+
+	if update.Days > h.cfg.MaxDays {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("retention_days must be at most %d", h.cfg.MaxDays)})
+		return
+	}
+
+	if err := h.retentionRepo.SetPolicy(c.GetInt64("client_id"), update.Table, update.Days); err != nil {
+		logger.Error("Failed to set retention policy", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to set retention"})
+		return
+	}
+
+	h.GetRetention(c)
+}
+
+// DeleteRetention removes the client's override of one table, restoring the default
+func (h *RetentionHandler) DeleteRetention(c *gin.Context) {
+	table := c.Param("table")
+	if !models.IsRetentionTable(table) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid table"})
+		return
+	}
+
+	deleted, err := h.retentionRepo.DeletePolicy(c.GetInt64("client_id"), table)
+	if err != nil {
+		logger.Error("Failed to delete retention policy", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete retention"})
+		return
+	}
+	if !deleted {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Retention override not found"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,26 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"platform/internal/models"
+)
+
+// requestScope returns who the request acts for: the organization selected by
+// the Auth middleware with the client's role in it, or the client alone
+func requestScope(c *gin.Context) models.Scope {
+	return models.Scope{
+		ClientID:       c.GetInt64("client_id"),
+		OrganizationID: c.GetInt64("organization_id"),
+		Role:           c.GetString("organization_role"),
+	}
+}
+
+// requirePermission answers 403 unless the scope grants permission
+func requirePermission(c *gin.Context, scope models.Scope, permission string) bool {
+	if !scope.Allows(permission) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Your organization role does not allow this"})
+		return false
+	}
+	return true
+}
@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"platform/internal/auth"
+	"platform/internal/models"
+	"platform/pkg/logger"
+)
+
+type SessionHandler struct {
+	sessions *auth.Sessions
+}
+
+func NewSessionHandler(sessions *auth.Sessions) *SessionHandler {
+	return &SessionHandler{
+		sessions: sessions,
+	}
+}
+
+// RefreshToken exchanges a refresh token for a new access token and refresh
+// token. Each refresh token can be exchanged once.
+func (h *SessionHandler) RefreshToken(c *gin.Context) {
+	var request models.TokenRefreshRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		logger.Error("Invalid token refresh data", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid token refresh data"})
+		return
+	}
+
+	tokens, err := h.sessions.Refresh(request.RefreshToken)
+	if errors.Is(err, auth.ErrInvalidRefreshToken) || errors.Is(err, auth.ErrRefreshTokenReused) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid refresh token"})
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to refresh token", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+// Logout ends the session of the access token used for the request, revoking
+// its refresh tokens and access tokens
+func (h *SessionHandler) Logout(c *gin.Context) {
+	value, _ := c.Get("token_claims")
+	claims, ok := value.(*auth.Claims)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	if err := h.sessions.End(claims); err != nil {
+		logger.Error("Failed to end session", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log out"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"platform/internal/analytics"
+	"platform/internal/models"
+	"platform/internal/repository/mysql"
+	"platform/pkg/logger"
+	"strings"
+	"time"
+)
+
+type StatsHandler struct {
+	analytics    *analytics.Service
+	redirectRepo *mysql.RedirectRepository
+}
+
+func NewStatsHandler(analyticsService *analytics.Service, redirectRepo *mysql.RedirectRepository) *StatsHandler {
+	return &StatsHandler{
+		analytics:    analyticsService,
+		redirectRepo: redirectRepo,
+	}
+}
+
+// GetMappingStats returns click stats for a mapping within the request's scope
+func (h *StatsHandler) GetMappingStats(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	scope := requestScope(c)
+	mapping, err := h.redirectRepo.GetScopedMapping(scope, id)
+	if err != nil {
+		logger.Error("Failed to get redirect mapping", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get stats"})
+		return
+	}
+	if mapping == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Redirect mapping not found"})
+		return
+	}
+
+	h.report(c, scope, mapping.ID)
+}
+
+// GetClientStats returns click stats across all of the mappings within the
+// request's scope: the organization's mappings, or the client's personal ones
+func (h *StatsHandler) GetClientStats(c *gin.Context) {
+	h.report(c, requestScope(c), 0)
+}
+
+func (h *StatsHandler) report(c *gin.Context, scope models.Scope, mappingID int64) {
+	query, ok := parseStatsQuery(c)
+	if !ok {
+		return
+	}
+	query.Filter.Scope = scope
+	query.Filter.MappingID = mappingID
+
+	report, err := h.analytics.Report(query)
+	if err != nil {
+		logger.Error("Failed to build stats report", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get stats"})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// parseStatsQuery reads tz, from, to, interval, breakdown, limit, compare and include_bots
+func parseStatsQuery(c *gin.Context) (analytics.Query, bool) {
+	loc := time.UTC
+	if tz := c.Query("tz"); tz != "" {
+		var err error
+		loc, err = time.LoadLocation(tz)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid tz"})
+			return analytics.Query{}, false
+		}
+	}
+
+	from, to, ok := parseTimeRangeIn(c, loc)
+	if !ok {
+		return analytics.Query{}, false
+	}
+
+	interval, err := analytics.ParseInterval(c.DefaultQuery("interval", string(analytics.IntervalDay)))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return analytics.Query{}, false
+	}
+	if analytics.CountBuckets(from, to, interval, loc) > analytics.MaxBuckets {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Time range has too many buckets for this interval"})
+		return analytics.Query{}, false
+	}
+
+	var breakdowns []string
+	if raw := c.Query("breakdown"); raw != "" {
+		for _, dimension := range strings.Split(raw, ",") {
+			dimension = strings.TrimSpace(dimension)
+			if !mysql.IsStatsDimension(dimension) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported breakdown: " + dimension})
+				return analytics.Query{}, false
+			}
+			breakdowns = append(breakdowns, dimension)
+		}
+	}
+
+	limit, ok := parseLimit(c, "limit", 10, 100)
+	if !ok {
+		return analytics.Query{}, false
+	}
+
+	visitors := c.DefaultQuery("visitors", "approx")
+	if visitors != "approx" && visitors != "exact" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "visitors must be approx or exact"})
+		return analytics.Query{}, false
+	}
+
+	return analytics.Query{
+		Filter:     models.StatsFilter{From: from, To: to, IncludeBots: c.Query("include_bots") == "true"},
+		Interval:   interval,
+		Location:   loc,
+		Breakdowns: breakdowns,
+		Limit:      limit,
+		Compare:    c.Query("compare") == "true",
+		// Exact counts scan the raw history, estimates merge the rollup sketches
+		ExactVisitors: visitors == "exact",
+	}, true
+}
@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"platform/internal/models"
+	"platform/internal/repository/mysql"
+	"platform/internal/stream"
+	"platform/pkg/logger"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxStreamMappings bounds the mapping filter of one stream
+const maxStreamMappings = 100
+
+type StreamHandler struct {
+	hub          *stream.Hub
+	redirectRepo *mysql.RedirectRepository
+	heartbeat    time.Duration
+}
+
+func NewStreamHandler(hub *stream.Hub, redirectRepo *mysql.RedirectRepository, heartbeat time.Duration) *StreamHandler {
+	return &StreamHandler{
+		hub:          hub,
+		redirectRepo: redirectRepo,
+		heartbeat:    heartbeat,
+	}
+}
+
+// StreamClicks streams the clicks within the request's scope as Server-Sent
+// Events while the connection is open. "mapping_id" may be repeated or
+// comma-separated to restrict the stream to some mappings.
+func (h *StreamHandler) StreamClicks(c *gin.Context) {
+	scope := requestScope(c)
+
+	mappingIDs, ok := h.parseMappingFilter(c, scope)
+	if !ok {
+		return
+	}
+
+	sub := h.hub.Subscribe(scope, mappingIDs)
+	defer h.hub.Unsubscribe(sub)
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	// Keep nginx-style proxies from buffering the stream
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	// Tell the browser how long to wait before reconnecting and open the stream
+	fmt.Fprintf(c.Writer, "retry: %d\n\n", (5 * time.Second).Milliseconds())
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(h.heartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case event := <-sub.Events():
+			if dropped := sub.TakeDropped(); dropped > 0 {
+				if err := writeEvent(c, "dropped", "", gin.H{"count": dropped}); err != nil {
+					return
+				}
+			}
+			if err := writeEvent(c, models.WebhookEventClick, event.EventID, models.NewWebhookClickData(event)); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			// Comment lines are ignored by clients but keep proxies from closing idle connections
+			if _, err := fmt.Fprint(c.Writer, ": heartbeat\n\n"); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		}
+	}
+}
+
+// parseMappingFilter reads the mapping_id filter, answering 400 for malformed IDs
+// and 404 for mappings outside the request's scope
+func (h *StreamHandler) parseMappingFilter(c *gin.Context, scope models.Scope) ([]int64, bool) {
+	var mappingIDs []int64
+	for _, raw := range c.QueryArray("mapping_id") {
+		for _, part := range strings.Split(raw, ",") {
+			id, err := strconv.ParseInt(strings.TrimSpace(part), 10, 64)
+			if err != nil || id < 1 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid mapping_id"})
+				return nil, false
+			}
+			mappingIDs = append(mappingIDs, id)
+		}
+	}
+
+	if len(mappingIDs) > maxStreamMappings {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("At most %d mapping_id values are allowed", maxStreamMappings)})
+		return nil, false
+	}
+
+	for _, id := range mappingIDs {
+		mapping, err := h.redirectRepo.GetScopedMapping(scope, id)
+		if err != nil {
+			logger.Error("Failed to get redirect mapping", "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to open stream"})
+			return nil, false
+		}
+		if mapping == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("Redirect mapping %d not found", id)})
+			return nil, false
+		}
+	}
+
+	return mappingIDs, true
+}
+
+// writeEvent writes one Server-Sent Event with a JSON data line and flushes it
+func writeEvent(c *gin.Context, name, id string, data interface{}) error {
+	body, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to marshal stream event: %w", err)
+	}
+
+	if id != "" {
+		if _, err := fmt.Fprintf(c.Writer, "id: %s\n", id); err != nil {
+			return err
+		}
+	}
+	if _, err := fmt.Fprintf(c.Writer, "event: %s\ndata: %s\n\n", name, body); err != nil {
+		return err
+	}
+	c.Writer.Flush()
+	return nil
+}
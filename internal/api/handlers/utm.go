@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"platform/internal/models"
+	"platform/internal/repository/mysql"
+	"platform/internal/utm"
+	"platform/pkg/logger"
+)
+
+type UTMHandler struct {
+	utmRepo  *mysql.UTMRepository
+	resolver *utm.Resolver
+}
+
+func NewUTMHandler(utmRepo *mysql.UTMRepository, resolver *utm.Resolver) *UTMHandler {
+	return &UTMHandler{
+		utmRepo:  utmRepo,
+		resolver: resolver,
+	}
+}
+
+// GetUTM returns the client's default UTM settings
+func (h *UTMHandler) GetUTM(c *gin.Context) {
+	settings, err := h.utmRepo.GetSettings(c.GetInt64("client_id"))
+	if err != nil {
+		logger.Error("Failed to get UTM settings", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get UTM settings"})
+		return
+	}
+	if settings == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "UTM settings not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+// SetUTM replaces the client's default UTM settings. Mappings inherit them for
+// the parameters and policy they leave empty.
+func (h *UTMHandler) SetUTM(c *gin.Context) {
+	var update models.UTMSettingsUpdate
+	if err := c.ShouldBindJSON(&update); err != nil {
+		logger.Error("Invalid UTM settings data", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid UTM settings data"})
+		return
+	}
+
+	clientID := c.GetInt64("client_id")
+	if err := h.utmRepo.SetSettings(clientID, &update); err != nil {
+		logger.Error("Failed to set UTM settings", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to set UTM settings"})
+		return
+	}
+	h.resolver.Forget(clientID)
+
+	h.GetUTM(c)
+}
+
+// DeleteUTM removes the client's default UTM settings
+func (h *UTMHandler) DeleteUTM(c *gin.Context) {
+	clientID := c.GetInt64("client_id")
+	deleted, err := h.utmRepo.DeleteSettings(clientID)
+	if err != nil {
+		logger.Error("Failed to delete UTM settings", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete UTM settings"})
+		return
+	}
+	if !deleted {
+		c.JSON(http.StatusNotFound, gin.H{"error": "UTM settings not found"})
+		return
+	}
+	h.resolver.Forget(clientID)
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,253 @@
+package handlers
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"platform/internal/models"
+	"platform/internal/repository/mysql"
+	"platform/internal/webhook"
+	"platform/pkg/logger"
+	"time"
+)
+
+type WebhookHandler struct {
+	webhookRepo  *mysql.WebhookRepository
+	redirectRepo *mysql.RedirectRepository
+}
+
+func NewWebhookHandler(webhookRepo *mysql.WebhookRepository, redirectRepo *mysql.RedirectRepository) *WebhookHandler {
+	return &WebhookHandler{
+		webhookRepo:  webhookRepo,
+		redirectRepo: redirectRepo,
+	}
+}
+
+// CreateEndpoint registers a webhook endpoint for the request's scope, so an
+// organization's endpoint receives the events of the organization's mappings.
+// The signing secret is only returned here.
+func (h *WebhookHandler) CreateEndpoint(c *gin.Context) {
+	scope := requestScope(c)
+	if !requirePermission(c, scope, models.PermissionEditMappings) {
+		return
+	}
+
+	var create models.WebhookEndpointCreate
+	if err := c.ShouldBindJSON(&create); err != nil {
+		logger.Error("Invalid webhook endpoint data", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid webhook endpoint data"})
+		return
+	}
+	if create.MinFraudScore != nil && create.MaxFraudScore != nil && *create.MinFraudScore > *create.MaxFraudScore {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "min_fraud_score must not exceed max_fraud_score"})
+		return
+	}
+	if err := webhook.CheckURL(c.Request.Context(), create.URL); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	for _, id := range create.MappingIDs {
+		mapping, err := h.redirectRepo.GetScopedMapping(scope, id)
+		if err != nil {
+			logger.Error("Failed to get redirect mapping", "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create webhook endpoint"})
+			return
+		}
+		if mapping == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Redirect mapping %d not found", id)})
+			return
+		}
+	}
+
+	secret, err := webhook.GenerateSecret()
+	if err != nil {
+		logger.Error("Failed to generate webhook secret", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create webhook endpoint"})
+		return
+	}
+
+	endpoint := &models.WebhookEndpoint{
+		ClientID:      scope.ClientID,
+		URL:           create.URL,
+		Secret:        secret,
+		EventTypes:    create.EventTypes,
+		MappingIDs:    create.MappingIDs,
+		MinFraudScore: create.MinFraudScore,
+		MaxFraudScore: create.MaxFraudScore,
+	}
+	if scope.OrganizationID != 0 {
+		endpoint.OrganizationID = &scope.OrganizationID
+	}
+	if err := h.webhookRepo.CreateEndpoint(endpoint); err != nil {
+		logger.Error("Failed to create webhook endpoint", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create webhook endpoint"})
+		return
+	}
+
+	endpoint.CreatedAt = time.Now()
+	endpoint.UpdatedAt = endpoint.CreatedAt
+	c.JSON(http.StatusCreated, endpoint)
+}
+
+// GetEndpoints lists the webhook endpoints of the request's scope without their secrets
+func (h *WebhookHandler) GetEndpoints(c *gin.Context) {
+	endpoints, err := h.webhookRepo.GetScopedEndpoints(requestScope(c))
+	if err != nil {
+		logger.Error("Failed to get webhook endpoints", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get webhook endpoints"})
+		return
+	}
+
+	for i := range endpoints {
+		endpoints[i].Secret = ""
+	}
+
+	c.JSON(http.StatusOK, endpoints)
+}
+
+// DeleteEndpoint removes a webhook endpoint together with its delivery log
+func (h *WebhookHandler) DeleteEndpoint(c *gin.Context) {
+	scope := requestScope(c)
+	if !requirePermission(c, scope, models.PermissionEditMappings) {
+		return
+	}
+
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	deleted, err := h.webhookRepo.DeleteEndpoint(scope, id)
+	if err != nil {
+		logger.Error("Failed to delete webhook endpoint", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete webhook endpoint"})
+		return
+	}
+	if !deleted {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Webhook endpoint not found"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// EnableEndpoint re-activates an endpoint that was disabled after repeated failures
+func (h *WebhookHandler) EnableEndpoint(c *gin.Context) {
+	scope := requestScope(c)
+	if !requirePermission(c, scope, models.PermissionEditMappings) {
+		return
+	}
+
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	enabled, err := h.webhookRepo.EnableEndpoint(scope, id)
+	if err != nil {
+		logger.Error("Failed to enable webhook endpoint", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to enable webhook endpoint"})
+		return
+	}
+	if !enabled {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Webhook endpoint not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Webhook endpoint enabled"})
+}
+
+// GetDeliveries returns the delivery log of an endpoint, newest first
+func (h *WebhookHandler) GetDeliveries(c *gin.Context) {
+	endpoint, ok := h.getEndpoint(c)
+	if !ok {
+		return
+	}
+
+	status := c.Query("status")
+	switch status {
+	case "", models.WebhookStatusPending, models.WebhookStatusSucceeded, models.WebhookStatusFailed:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status filter"})
+		return
+	}
+
+	limit, ok := parseLimit(c, "limit", 50, 500)
+	if !ok {
+		return
+	}
+
+	deliveries, err := h.webhookRepo.GetEndpointDeliveries(endpoint.ID, status, limit)
+	if err != nil {
+		logger.Error("Failed to get webhook deliveries", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get webhook deliveries"})
+		return
+	}
+
+	c.JSON(http.StatusOK, deliveries)
+}
+
+// ReplayDelivery queues a fresh delivery of a logged event's original payload
+func (h *WebhookHandler) ReplayDelivery(c *gin.Context) {
+	if !requirePermission(c, requestScope(c), models.PermissionEditMappings) {
+		return
+	}
+
+	endpoint, ok := h.getEndpoint(c)
+	if !ok {
+		return
+	}
+
+	deliveryID, ok := parseIDParam(c, "delivery_id")
+	if !ok {
+		return
+	}
+
+	original, err := h.webhookRepo.GetDelivery(endpoint.ID, deliveryID)
+	if err != nil {
+		logger.Error("Failed to get webhook delivery", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to replay webhook delivery"})
+		return
+	}
+	if original == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Webhook delivery not found"})
+		return
+	}
+
+	replay := &models.WebhookDelivery{
+		EndpointID:    endpoint.ID,
+		EventID:       original.EventID,
+		EventType:     original.EventType,
+		ReplayOf:      &original.ID,
+		Payload:       original.Payload,
+		NextAttemptAt: time.Now(),
+	}
+	if err := h.webhookRepo.CreateDelivery(replay); err != nil {
+		logger.Error("Failed to create webhook delivery", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to replay webhook delivery"})
+		return
+	}
+
+	replay.CreatedAt = replay.NextAttemptAt
+	c.JSON(http.StatusAccepted, replay)
+}
+
+func (h *WebhookHandler) getEndpoint(c *gin.Context) (*models.WebhookEndpoint, bool) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return nil, false
+	}
+
+	endpoint, err := h.webhookRepo.GetEndpoint(requestScope(c), id)
+	if err != nil {
+		logger.Error("Failed to get webhook endpoint", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get webhook endpoint"})
+		return nil, false
+	}
+	if endpoint == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Webhook endpoint not found"})
+		return nil, false
+	}
+
+	return endpoint, true
+}
@@ -4,54 +4,228 @@ import (
 	"github.com/gin-gonic/gin"
 	"net/http"
 	"platform/internal/auth"
+	"platform/internal/models"
 	"platform/internal/repository/mysql"
 	"platform/pkg/logger"
+	"strconv"
 	"strings"
+	"time"
 )
 
-func Auth(clientRepo *mysql.ClientRepository) gin.HandlerFunc {
+const (
+	apiKeyHeader       = "X-API-Key"
+	organizationHeader = "X-Organization-ID"
+)
+
+// apiKeyTouchInterval bounds how often a key's last use is written
+const apiKeyTouchInterval = time.Minute
+
+// Auth authenticates requests with either a Bearer JWT or an API key in the
+// X-API-Key header. Requests made with an API key carry the key in the context
+// as "api_key"; RequireScope and RequireSession restrict what they can reach.
+// An X-Organization-ID header makes the request act for that organization,
+// which the client must be a member of; its ID and the client's role are put
+// in the context as "organization_id" and "organization_role".
+// Suspended accounts and accounts that must reset their password are
+// rejected. "platform_admin" and "email_verified" tell
+// whether the client is a platform admin and has verified its email address.
+func Auth(signer *auth.Signer, clientRepo *mysql.ClientRepository, apiKeyRepo *mysql.APIKeyRepository, orgRepo *mysql.OrganizationRepository, revocations *auth.Revocations) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header is required"})
-			c.Abort()
-			return
+		var clientID int64
+		if key := c.GetHeader(apiKeyHeader); key != "" {
+			apiKey, ok := authenticateAPIKey(c, apiKeyRepo, key)
+			if !ok {
+				return
+			}
+			clientID = apiKey.ClientID
+			c.Set("api_key", apiKey)
+		} else {
+			claims, ok := authenticateToken(c, signer, revocations)
+			if !ok {
+				return
+			}
+			clientID = claims.ClientID
+			c.Set("token_claims", claims)
 		}
 
-		// Extract token from Bearer scheme
-		parts := strings.Split(authHeader, " ")
-		if len(parts) != 2 || parts[0] != "Bearer" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid authorization header format"})
+		// Get client by ID
+		client, err := clientRepo.GetByID(clientID)
+		if err != nil {
+			logger.Error("Failed to get client", "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to authenticate"})
 			c.Abort()
 			return
 		}
 
-		// Validate JWT token
-		claims, err := auth.ValidateToken(parts[1])
-		if err != nil {
-			logger.Error("Failed to validate token", "error", err)
+		if client == nil {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
 			c.Abort()
 			return
 		}
 
-		// Get client by ID
-		client, err := clientRepo.GetByID(claims.ClientID)
-		if err != nil {
-			logger.Error("Failed to get client", "error", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to authenticate"})
+		if client.SuspendedAt != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Account suspended"})
 			c.Abort()
 			return
 		}
 
-		if client == nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+		if client.PasswordResetRequired {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Password reset required"})
 			c.Abort()
 			return
 		}
 
+		// Select the active organization
+		if header := c.GetHeader(organizationHeader); header != "" {
+			orgID, err := strconv.ParseInt(header, 10, 64)
+			if err != nil || orgID < 1 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + organizationHeader + " header"})
+				c.Abort()
+				return
+			}
+
+			member, err := orgRepo.GetMember(orgID, client.ID)
+			if err != nil {
+				logger.Error("Failed to get organization member", "error", err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to authenticate"})
+				c.Abort()
+				return
+			}
+			if member == nil {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Not a member of this organization"})
+				c.Abort()
+				return
+			}
+
+			c.Set("organization_id", orgID)
+			c.Set("organization_role", member.Role)
+		}
+
 		// Set client ID in context
 		c.Set("client_id", client.ID)
+		c.Set("platform_admin", client.IsAdmin)
+		c.Set("email_verified", client.EmailVerifiedAt != nil)
+		c.Next()
+	}
+}
+
+func authenticateToken(c *gin.Context, signer *auth.Signer, revocations *auth.Revocations) (*auth.Claims, bool) {
+	authHeader := c.GetHeader("Authorization")
+	if authHeader == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header is required"})
+		c.Abort()
+		return nil, false
+	}
+
+	// Extract token from Bearer scheme
+	parts := strings.Split(authHeader, " ")
+	if len(parts) != 2 || parts[0] != "Bearer" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid authorization header format"})
+		c.Abort()
+		return nil, false
+	}
+
+	// Validate JWT token
+	claims, err := signer.ValidateToken(parts[1])
+	if err != nil {
+		logger.Error("Failed to validate token", "error", err)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+		c.Abort()
+		return nil, false
+	}
+
+	// Reject revoked tokens
+	revoked, err := revocations.IsRevoked(claims.ID, claims.ExpiresAt.Time)
+	if err != nil {
+		logger.Error("Failed to check token revocation", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to authenticate"})
+		c.Abort()
+		return nil, false
+	}
+	if revoked {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Token has been revoked"})
+		c.Abort()
+		return nil, false
+	}
+
+	return claims, true
+}
+
+func authenticateAPIKey(c *gin.Context, apiKeyRepo *mysql.APIKeyRepository, key string) (*models.APIKey, bool) {
+	apiKey, err := apiKeyRepo.GetByHash(auth.HashOpaqueToken(key))
+	if err != nil {
+		logger.Error("Failed to get API key", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to authenticate"})
+		c.Abort()
+		return nil, false
+	}
+
+	now := time.Now()
+	if apiKey == nil || apiKey.Expired(now) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid API key"})
+		c.Abort()
+		return nil, false
+	}
+
+	// Record the use, at most once per interval per key
+	if apiKey.LastUsedAt == nil || now.Sub(*apiKey.LastUsedAt) >= apiKeyTouchInterval {
+		if err := apiKeyRepo.TouchLastUsed(apiKey.ID, now); err != nil {
+			logger.Error("Failed to record API key use", "error", err)
+		}
+	}
+
+	return apiKey, true
+}
+
+// RequireScope rejects requests made with an API key that lacks scope.
+// Requests made with a login session pass.
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if value, exists := c.Get("api_key"); exists {
+			if apiKey := value.(*models.APIKey); !apiKey.HasScope(scope) {
+				c.JSON(http.StatusForbidden, gin.H{"error": "API key lacks the " + scope + " scope"})
+				c.Abort()
+				return
+			}
+		}
+		c.Next()
+	}
+}
+
+// RequireAdmin rejects requests from clients that are not platform admins
+func RequireAdmin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !c.GetBool("platform_admin") {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireVerifiedEmail rejects clients that have not verified their email
+// address when enforce is set, as the unverified account policy decides
+func RequireVerifiedEmail(enforce bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if enforce && !c.GetBool("email_verified") {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Verify your email address first"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireSession rejects requests made with an API key, for endpoints no scope
+// covers
+func RequireSession() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, exists := c.Get("api_key"); exists {
+			c.JSON(http.StatusForbidden, gin.H{"error": "This endpoint requires logging in"})
+			c.Abort()
+			return
+		}
 		c.Next()
 	}
 } 
\ No newline at end of file
@@ -1,17 +1,29 @@
 package api
 
 import (
+	"context"
 	"github.com/gin-gonic/gin"
+	"platform/internal/account"
+	"platform/internal/analytics"
 	"platform/internal/api/handlers"
 	"platform/internal/api/middleware"
+	"platform/internal/auth"
 	"platform/internal/config"
 	"platform/internal/database"
+	"platform/internal/geoip"
+	"platform/internal/lifecycle"
+	"platform/internal/mail"
+	"platform/internal/models"
+	"platform/internal/privacy"
 	"platform/internal/repository/mysql"
 	"platform/internal/repository/rabbitmq"
+	"platform/internal/stream"
+	"platform/internal/utm"
+	"platform/internal/visitor"
 	"platform/pkg/logger"
 )
 
-func SetupRouter(publisher *rabbitmq.Publisher) *gin.Engine {
+func SetupRouter(publisher *rabbitmq.Publisher, hub *stream.Hub) *gin.Engine {
 	// Load configuration
 	cfg, err := config.LoadConfig()
 	if err != nil {
@@ -26,10 +38,68 @@ func SetupRouter(publisher *rabbitmq.Publisher) *gin.Engine {
 	// Initialize repositories
 	clientRepo := mysql.NewClientRepository(database.GetDB())
 	redirectRepo := mysql.NewRedirectRepository(database.GetDB())
+	webhookRepo := mysql.NewWebhookRepository(database.GetDB())
+	conversionRepo := mysql.NewConversionRepository(database.GetDB())
+	statsRepo := mysql.NewStatsRepository(database.GetDB())
+	rollupRepo := mysql.NewRollupRepository(database.GetDB())
+	retentionRepo := mysql.NewRetentionRepository(database.GetDB())
+	privacyRepo := mysql.NewPrivacyRepository(database.GetDB())
+	utmRepo := mysql.NewUTMRepository(database.GetDB())
+	visitorRepo := mysql.NewVisitorRepository(database.GetDB())
+	tokenRepo := mysql.NewTokenRepository(database.GetDB())
+	apiKeyRepo := mysql.NewAPIKeyRepository(database.GetDB())
+	orgRepo := mysql.NewOrganizationRepository(database.GetDB())
+	adminRepo := mysql.NewAdminRepository(database.GetDB())
+
+	// Initialize services
+	analyticsService := analytics.NewService(statsRepo, rollupRepo)
+	privacyPolicy, err := privacy.NewPolicy(cfg.Privacy)
+	if err != nil {
+		logger.Fatal("Invalid privacy configuration", err)
+	}
+	privacyResolver := privacy.NewResolver(privacyPolicy, privacyRepo, cfg.Privacy.SettingsCacheTTL)
+	utmResolver := utm.NewResolver(utmRepo, cfg.UTM.SettingsCacheTTL)
+	visitorIdentifier := visitor.NewIdentifier(cfg.Visitor, visitorRepo)
+	lifecycleResponder, err := lifecycle.NewResponder(cfg.Lifecycle)
+	if err != nil {
+		logger.Fatal("Invalid lifecycle configuration", err)
+	}
+	signer, err := auth.NewSigner(cfg.JWT)
+	if err != nil {
+		logger.Fatal("Invalid JWT configuration", err)
+	}
+	revocations := auth.NewRevocations(tokenRepo, cfg.Auth.RevocationCacheTTL)
+	revocations.Watch(context.Background(), cfg.Auth.PruneInterval)
+	sessions := auth.NewSessions(signer, tokenRepo, clientRepo, revocations, cfg.Auth)
+	mailer, err := mail.NewMailer(cfg.Mail)
+	if err != nil {
+		logger.Fatal("Invalid mail configuration", err)
+	}
+	accountPolicy, err := account.NewPolicy(cfg.Account)
+	if err != nil {
+		logger.Fatal("Invalid account configuration", err)
+	}
+	accountService := account.NewService(tokenRepo, clientRepo, sessions, mailer, cfg.Account)
+	geoLocator := geoip.NewLocator(cfg.GeoIP)
+	geoLocator.Watch(context.Background())
 
 	// Initialize handlers
-	requestHandler := handlers.NewRequestHandler(publisher, redirectRepo)
-	clientHandler := handlers.NewClientHandler(clientRepo, redirectRepo)
+	requestHandler := handlers.NewRequestHandler(publisher, redirectRepo, privacyResolver, geoLocator, utmResolver, visitorIdentifier, lifecycleResponder)
+	clientHandler := handlers.NewClientHandler(clientRepo, redirectRepo, sessions, accountService, accountPolicy)
+	accountHandler := handlers.NewAccountHandler(accountService, clientRepo)
+	sessionHandler := handlers.NewSessionHandler(sessions)
+	apiKeyHandler := handlers.NewAPIKeyHandler(apiKeyRepo)
+	orgHandler := handlers.NewOrganizationHandler(orgRepo, clientRepo, cfg.Org.InvitationTTL)
+	adminHandler := handlers.NewAdminHandler(adminRepo, clientRepo, sessions, accountService)
+	jwksHandler := handlers.NewJWKSHandler(signer)
+	webhookHandler := handlers.NewWebhookHandler(webhookRepo, redirectRepo)
+	postbackHandler := handlers.NewPostbackHandler(clientRepo, conversionRepo)
+	statsHandler := handlers.NewStatsHandler(analyticsService, redirectRepo)
+	streamHandler := handlers.NewStreamHandler(hub, redirectRepo, cfg.Stream.HeartbeatInterval)
+	retentionHandler := handlers.NewRetentionHandler(retentionRepo, cfg.Retention)
+	privacyHandler := handlers.NewPrivacyHandler(privacyRepo, privacyResolver)
+	fraudHandler := handlers.NewFraudHandler(redirectRepo, cfg.Fraud.FlagScore)
+	utmHandler := handlers.NewUTMHandler(utmRepo, utmResolver)
 
 	// Create router
 	router := gin.New()
@@ -41,16 +111,116 @@ func SetupRouter(publisher *rabbitmq.Publisher) *gin.Engine {
 	// Health check
 	router.GET("/health", handlers.HealthCheck)
 
+	// Public keys that verify access tokens
+	router.GET("/.well-known/jwks.json", jwksHandler.GetJWKS)
+
 	// Public endpoints
 	router.POST("/api/register", clientHandler.Register)
 	router.POST("/api/login", clientHandler.Login)
+	router.POST("/api/token/refresh", sessionHandler.RefreshToken)
+	router.POST("/api/email/verify", accountHandler.VerifyEmail)
+	router.POST("/api/password/forgot", accountHandler.ForgotPassword)
+	router.POST("/api/password/reset", accountHandler.ResetPassword)
 
-	// Protected endpoints
+	// Server-to-server conversion postbacks (authenticated with the client's postback token)
+	router.GET("/postback", postbackHandler.Postback)
+	router.POST("/postback", postbackHandler.Postback)
+
+	// Protected endpoints, reachable with a login session or an API key with the
+	// route's scope
 	protected := router.Group("/api")
-	protected.Use(middleware.Auth(clientRepo))
+	protected.Use(middleware.Auth(signer, clientRepo, apiKeyRepo, orgRepo, revocations), middleware.RequireVerifiedEmail(accountPolicy.BlocksUnverified()))
+	{
+		redirectsRead := middleware.RequireScope(models.ScopeRedirectsRead)
+		redirectsWrite := middleware.RequireScope(models.ScopeRedirectsWrite)
+		statsRead := middleware.RequireScope(models.ScopeStatsRead)
+
+		protected.POST("/redirects", redirectsWrite, clientHandler.CreateRedirectMapping)
+		protected.GET("/redirects", redirectsRead, clientHandler.GetRedirectMappings)
+		protected.PUT("/redirects/:id/utm", redirectsWrite, clientHandler.UpdateRedirectUTM)
+		protected.PUT("/redirects/:id/forwarding", redirectsWrite, clientHandler.UpdateRedirectForwarding)
+		protected.POST("/redirects/:id/activate", redirectsWrite, clientHandler.ActivateRedirect)
+		protected.POST("/redirects/:id/pause", redirectsWrite, clientHandler.PauseRedirect)
+		protected.POST("/redirects/:id/resume", redirectsWrite, clientHandler.ResumeRedirect)
+		protected.POST("/redirects/:id/archive", redirectsWrite, clientHandler.ArchiveRedirect)
+		protected.GET("/redirects/:id/status-history", redirectsRead, clientHandler.GetRedirectStatusHistory)
+		protected.GET("/redirects/:id/history", redirectsRead, clientHandler.GetRedirectHistory)
+		protected.GET("/redirects/:id/stats", statsRead, statsHandler.GetMappingStats)
+		protected.GET("/stats", statsRead, statsHandler.GetClientStats)
+		protected.GET("/stream/clicks", statsRead, streamHandler.StreamClicks)
+		protected.GET("/clicks/flagged", statsRead, fraudHandler.GetFlaggedClicks)
+		protected.GET("/conversions/stats", statsRead, postbackHandler.GetConversionStats)
+	}
+
+	// Session endpoints, reachable with a login session whether or not the
+	// email address is verified
+	session := router.Group("/api")
+	session.Use(middleware.Auth(signer, clientRepo, apiKeyRepo, orgRepo, revocations), middleware.RequireSession())
+	{
+		session.POST("/logout", sessionHandler.Logout)
+		session.PUT("/password", clientHandler.ChangePassword)
+		session.POST("/email/verify/resend", accountHandler.ResendVerification)
+	}
+
+	// Account endpoints, reachable with a login session only
+	account := router.Group("/api")
+	account.Use(middleware.Auth(signer, clientRepo, apiKeyRepo, orgRepo, revocations), middleware.RequireSession(), middleware.RequireVerifiedEmail(accountPolicy.BlocksUnverified()))
+	{
+		// Unverified accounts may be kept from creating keys, webhooks and organizations
+		verified := middleware.RequireVerifiedEmail(accountPolicy.LimitsUnverified())
+
+		account.POST("/keys", verified, apiKeyHandler.CreateAPIKey)
+		account.GET("/keys", apiKeyHandler.GetAPIKeys)
+		account.DELETE("/keys/:id", apiKeyHandler.DeleteAPIKey)
+
+		account.POST("/orgs", verified, orgHandler.CreateOrganization)
+		account.GET("/orgs", orgHandler.GetOrganizations)
+		account.DELETE("/orgs/:id", orgHandler.DeleteOrganization)
+		account.GET("/orgs/:id/members", orgHandler.GetMembers)
+		account.PUT("/orgs/:id/members/:client_id", orgHandler.UpdateMember)
+		account.DELETE("/orgs/:id/members/:client_id", orgHandler.RemoveMember)
+		account.POST("/orgs/:id/invitations", verified, orgHandler.CreateInvitation)
+		account.GET("/orgs/:id/invitations", orgHandler.GetInvitations)
+		account.DELETE("/orgs/:id/invitations/:invitation_id", orgHandler.DeleteInvitation)
+		account.POST("/invitations/accept", verified, orgHandler.AcceptInvitation)
+
+		account.POST("/webhooks", verified, webhookHandler.CreateEndpoint)
+		account.GET("/webhooks", webhookHandler.GetEndpoints)
+		account.DELETE("/webhooks/:id", webhookHandler.DeleteEndpoint)
+		account.POST("/webhooks/:id/enable", webhookHandler.EnableEndpoint)
+		account.GET("/webhooks/:id/deliveries", webhookHandler.GetDeliveries)
+		account.POST("/webhooks/:id/deliveries/:delivery_id/replay", webhookHandler.ReplayDelivery)
+
+		account.POST("/postback/token", postbackHandler.RotatePostbackToken)
+
+		account.GET("/retention", retentionHandler.GetRetention)
+		account.PUT("/retention", retentionHandler.SetRetention)
+		account.DELETE("/retention/:table", retentionHandler.DeleteRetention)
+
+		account.GET("/privacy", privacyHandler.GetPrivacy)
+		account.PUT("/privacy", privacyHandler.SetPrivacy)
+		account.DELETE("/privacy", privacyHandler.DeletePrivacy)
+
+		account.GET("/utm", utmHandler.GetUTM)
+		account.PUT("/utm", utmHandler.SetUTM)
+		account.DELETE("/utm", utmHandler.DeleteUTM)
+	}
+
+	// Platform administration, for logged-in platform admins only
+	admin := router.Group("/api/admin")
+	admin.Use(middleware.Auth(signer, clientRepo, apiKeyRepo, orgRepo, revocations), middleware.RequireSession(), middleware.RequireVerifiedEmail(accountPolicy.BlocksUnverified()), middleware.RequireAdmin())
 	{
-		protected.POST("/redirects", clientHandler.CreateRedirectMapping)
-		protected.GET("/redirects", clientHandler.GetRedirectMappings)
+		admin.GET("/clients", adminHandler.SearchClients)
+		admin.GET("/clients/:id", adminHandler.GetClient)
+		admin.GET("/clients/:id/redirects", adminHandler.GetClientRedirects)
+		admin.POST("/clients/:id/suspend", adminHandler.SuspendClient)
+		admin.POST("/clients/:id/unsuspend", adminHandler.UnsuspendClient)
+		admin.POST("/clients/:id/password-reset", adminHandler.ResetClientPassword)
+
+		admin.POST("/redirects/:id/takedown", adminHandler.TakeDownRedirect)
+
+		admin.GET("/usage", adminHandler.GetUsage)
+		admin.GET("/audit", adminHandler.GetAuditLog)
 	}
 
 	// Hash endpoint with dynamic hash parameter
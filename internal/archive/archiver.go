@@ -0,0 +1,269 @@
+package archive
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"platform/internal/config"
+	"platform/internal/models"
+	"platform/internal/repository/mysql"
+	"platform/pkg/logger"
+	"platform/pkg/metrics"
+	"time"
+)
+
+var (
+	rangesArchived = metrics.Map("archive_ranges_archived")
+	rowsArchived   = metrics.Map("archive_rows_archived")
+	archiveErrors  = metrics.Int("archive_failures")
+)
+
+// Archiver exports closed UTC days of the raw click tables to a Store, one
+// directory per table and day holding the NDJSON files and a manifest
+type Archiver struct {
+	repo  *mysql.ArchiveRepository
+	store Store
+	cfg   config.ArchiveConfig
+}
+
+func NewArchiver(repo *mysql.ArchiveRepository, store Store, cfg config.ArchiveConfig) *Archiver {
+	return &Archiver{
+		repo:  repo,
+		store: store,
+		cfg:   cfg,
+	}
+}
+
+// ManifestKey returns the key of the manifest of a table's archived day
+func ManifestKey(table string, day time.Time) string {
+	return dayPrefix(table, day) + "/manifest.json"
+}
+
+func dayPrefix(table string, day time.Time) string {
+	return table + "/" + day.UTC().Format("2006/01/02")
+}
+
+// Run archives all closed days once per interval until the context is cancelled
+func (a *Archiver) Run(ctx context.Context) {
+	ticker := time.NewTicker(a.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := a.ArchiveClosed(ctx); err != nil {
+			archiveErrors.Add(1)
+			logger.Error("Failed to archive raw clicks", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ArchiveClosed archives, in order, every day of the raw tables that ended at
+// least Lag ago and has not been archived yet
+func (a *Archiver) ArchiveClosed(ctx context.Context) error {
+	closedBefore := time.Now().UTC().Add(-a.cfg.Lag)
+
+	for _, table := range models.RetentionTables {
+		day, ok, err := a.repo.NextDay(table)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+
+		for ; !day.AddDate(0, 0, 1).After(closedBefore); day = day.AddDate(0, 0, 1) {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if _, err := a.ArchiveDay(ctx, table, day); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// ArchiveDay exports one UTC day of a table, uploads its files and manifest and
+// records the run. Days without rows get a manifest too, so the archive covers
+// every day without gaps.
+func (a *Archiver) ArchiveDay(ctx context.Context, table string, day time.Time) (*models.ArchiveManifest, error) {
+	manifest := &models.ArchiveManifest{
+		Table:  table,
+		From:   day.UTC(),
+		To:     day.UTC().AddDate(0, 0, 1),
+		Format: models.ArchiveFormat,
+		Files:  []models.ArchiveFile{},
+	}
+
+	var part *partWriter
+	closePart := func() error {
+		if part == nil {
+			return nil
+		}
+		file, err := part.finish(ctx, a.store)
+		part = nil
+		if err != nil {
+			return err
+		}
+		manifest.Files = append(manifest.Files, *file)
+		return nil
+	}
+	defer func() {
+		if part != nil {
+			part.discard()
+		}
+	}()
+
+	err := a.repo.ForEachRow(table, manifest.From, manifest.To, func(columns []string, values []interface{}) error {
+		if manifest.Columns == nil {
+			manifest.Columns = columns
+		}
+
+		if part == nil {
+			key := fmt.Sprintf("%s/part-%05d.ndjson.gz", dayPrefix(table, day), len(manifest.Files))
+			var err error
+			if part, err = newPartWriter(key); err != nil {
+				return err
+			}
+		}
+
+		row := make(map[string]interface{}, len(columns))
+		for i, column := range columns {
+			row[column] = values[i]
+		}
+		if err := part.write(row); err != nil {
+			return err
+		}
+		manifest.Rows++
+
+		if part.rows >= int64(a.cfg.RowsPerFile) {
+			return closePart()
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := closePart(); err != nil {
+		return nil, err
+	}
+
+	// The manifest goes last: its presence marks the archive complete
+	manifest.CreatedAt = time.Now().UTC()
+	body, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+	manifestKey := ManifestKey(table, day)
+	if err := a.store.Put(ctx, manifestKey, bytes.NewReader(body), int64(len(body)), sha256Hex(body)); err != nil {
+		return nil, err
+	}
+
+	err = a.repo.RecordRun(&models.ArchiveRun{
+		Table:       table,
+		RangeStart:  manifest.From,
+		RangeEnd:    manifest.To,
+		ManifestKey: manifestKey,
+		Rows:        manifest.Rows,
+	})
+	if err != nil && !errors.Is(err, mysql.ErrDuplicate) {
+		return nil, err
+	}
+
+	rangesArchived.Add(table, 1)
+	rowsArchived.Add(table, manifest.Rows)
+	logger.Info("Archived raw clicks", "table", table, "day", day.Format("2006-01-02"), "rows", manifest.Rows, "files", len(manifest.Files))
+	return manifest, nil
+}
+
+// partWriter writes one gzip-compressed NDJSON file to a temporary file while
+// hashing the compressed bytes
+type partWriter struct {
+	key     string
+	file    *os.File
+	digest  hash.Hash
+	buf     *bufio.Writer
+	gz      *gzip.Writer
+	encoder *json.Encoder
+	rows    int64
+}
+
+func newPartWriter(key string) (*partWriter, error) {
+	file, err := os.CreateTemp("", "archive-*.ndjson.gz")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create archive file: %w", err)
+	}
+
+	digest := sha256.New()
+	buf := bufio.NewWriter(io.MultiWriter(file, digest))
+	gz := gzip.NewWriter(buf)
+
+	return &partWriter{
+		key:     key,
+		file:    file,
+		digest:  digest,
+		buf:     buf,
+		gz:      gz,
+		encoder: json.NewEncoder(gz),
+	}, nil
+}
+
+func (w *partWriter) write(row map[string]interface{}) error {
+	if err := w.encoder.Encode(row); err != nil {
+		return fmt.Errorf("failed to write archive row: %w", err)
+	}
+	w.rows++
+	return nil
+}
+
+// finish completes the file, uploads it and removes the temporary copy
+func (w *partWriter) finish(ctx context.Context, store Store) (*models.ArchiveFile, error) {
+	defer w.discard()
+
+	if err := w.gz.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress archive file: %w", err)
+	}
+	if err := w.buf.Flush(); err != nil {
+		return nil, fmt.Errorf("failed to write archive file: %w", err)
+	}
+
+	size, err := w.file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, fmt.Errorf("failed to size archive file: %w", err)
+	}
+	if _, err := w.file.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("failed to rewind archive file: %w", err)
+	}
+
+	checksum := hex.EncodeToString(w.digest.Sum(nil))
+	if err := store.Put(ctx, w.key, w.file, size, checksum); err != nil {
+		return nil, err
+	}
+
+	return &models.ArchiveFile{
+		Key:    w.key,
+		Rows:   w.rows,
+		Bytes:  size,
+		SHA256: checksum,
+	}, nil
+}
+
+func (w *partWriter) discard() {
+	w.file.Close()
+	os.Remove(w.file.Name())
+}
@@ -0,0 +1,183 @@
+package archive
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"platform/internal/models"
+	"platform/internal/repository/mysql"
+	"platform/pkg/logger"
+	"regexp"
+	"time"
+)
+
+// restoreBatchSize bounds the number of rows inserted by one statement
+const restoreBatchSize = 500
+
+var scratchTablePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,63}$`)
+
+// Restorer re-imports archived days into scratch tables for audits
+type Restorer struct {
+	repo  *mysql.ArchiveRepository
+	store Store
+}
+
+func NewRestorer(repo *mysql.ArchiveRepository, store Store) *Restorer {
+	return &Restorer{
+		repo:  repo,
+		store: store,
+	}
+}
+
+// ScratchTableName returns the default scratch table for a table's archived day
+func ScratchTableName(table string, day time.Time) string {
+	return "restore_" + table + "_" + day.UTC().Format("20060102")
+}
+
+// Restore verifies the checksums of an archived day and imports its rows into a
+// new scratch table with the structure of the source table
+func (r *Restorer) Restore(ctx context.Context, table string, day time.Time, scratch string) (*models.ArchiveManifest, error) {
+	if !models.IsRetentionTable(table) {
+		return nil, fmt.Errorf("unsupported archive table %q", table)
+	}
+	if !scratchTablePattern.MatchString(scratch) {
+		return nil, fmt.Errorf("invalid scratch table name %q", scratch)
+	}
+
+	manifest, err := r.manifest(ctx, table, day)
+	if err != nil {
+		return nil, err
+	}
+	if manifest.Format != models.ArchiveFormat {
+		return nil, fmt.Errorf("unsupported archive format %q", manifest.Format)
+	}
+
+	if err := r.repo.CreateScratchTable(table, scratch); err != nil {
+		return nil, err
+	}
+
+	for _, file := range manifest.Files {
+		if err := r.restoreFile(ctx, manifest, file, scratch); err != nil {
+			return nil, err
+		}
+		logger.Info("Restored archive file", "key", file.Key, "rows", file.Rows, "table", scratch)
+	}
+
+	return manifest, nil
+}
+
+func (r *Restorer) manifest(ctx context.Context, table string, day time.Time) (*models.ArchiveManifest, error) {
+	body, err := r.store.Get(ctx, ManifestKey(table, day))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get manifest: %w", err)
+	}
+	defer body.Close()
+
+	var manifest models.ArchiveManifest
+	if err := json.NewDecoder(body).Decode(&manifest); err != nil {
+		return nil, fmt.Errorf("failed to decode manifest: %w", err)
+	}
+	return &manifest, nil
+}
+
+// restoreFile downloads a file to disk, checks it against the manifest and
+// inserts its rows in batches
+func (r *Restorer) restoreFile(ctx context.Context, manifest *models.ArchiveManifest, file models.ArchiveFile, scratch string) error {
+	body, err := r.store.Get(ctx, file.Key)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	tmp, err := os.CreateTemp("", "restore-*.ndjson.gz")
+	if err != nil {
+		return fmt.Errorf("failed to create restore file: %w", err)
+	}
+	defer func() {
+		tmp.Close()
+		os.Remove(tmp.Name())
+	}()
+
+	digest := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(tmp, digest), body); err != nil {
+		return fmt.Errorf("failed to download %s: %w", file.Key, err)
+	}
+	if checksum := hex.EncodeToString(digest.Sum(nil)); checksum != file.SHA256 {
+		return fmt.Errorf("checksum mismatch for %s: manifest has %s, file has %s", file.Key, file.SHA256, checksum)
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to rewind restore file: %w", err)
+	}
+
+	gz, err := gzip.NewReader(bufio.NewReader(tmp))
+	if err != nil {
+		return fmt.Errorf("failed to decompress %s: %w", file.Key, err)
+	}
+	defer gz.Close()
+
+	decoder := json.NewDecoder(gz)
+	var rows int64
+	batch := make([][]interface{}, 0, restoreBatchSize)
+	for {
+		var row map[string]json.RawMessage
+		if err := decoder.Decode(&row); err == io.EOF {
+			break
+		} else if err != nil {
+			return fmt.Errorf("failed to decode %s: %w", file.Key, err)
+		}
+
+		values := make([]interface{}, len(manifest.Columns))
+		for i, column := range manifest.Columns {
+			values[i], err = columnValue(row[column])
+			if err != nil {
+				return fmt.Errorf("failed to decode %s of %s: %w", column, file.Key, err)
+			}
+		}
+		batch = append(batch, values)
+		rows++
+
+		if len(batch) == restoreBatchSize {
+			if err := r.repo.InsertRows(scratch, manifest.Columns, batch); err != nil {
+				return err
+			}
+			batch = batch[:0]
+		}
+	}
+	if err := r.repo.InsertRows(scratch, manifest.Columns, batch); err != nil {
+		return err
+	}
+
+	if rows != file.Rows {
+		return fmt.Errorf("row count mismatch for %s: manifest has %d, file has %d", file.Key, file.Rows, rows)
+	}
+	return nil
+}
+
+// columnValue converts an archived JSON value back into an SQL argument. Numbers
+// keep their exact text; objects and arrays are JSON column values.
+func columnValue(raw json.RawMessage) (interface{}, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+
+	switch raw[0] {
+	case '"':
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return nil, err
+		}
+		return s, nil
+	case '{', '[':
+		return string(raw), nil
+	case 't', 'f':
+		return string(raw) == "true", nil
+	default:
+		return string(raw), nil
+	}
+}
@@ -0,0 +1,33 @@
+package archive
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"platform/internal/config"
+)
+
+// ErrNotFound is returned by Store.Get for keys that do not exist
+var ErrNotFound = errors.New("archive object not found")
+
+// Store is where archive files and manifests are kept. Keys are slash-separated
+// paths relative to the store's root.
+type Store interface {
+	// Put stores size bytes read from body under key. sha256Hex is the hex
+	// SHA-256 of the content, which object stores use to verify the upload.
+	Put(ctx context.Context, key string, body io.ReadSeeker, size int64, sha256Hex string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+}
+
+// NewStore returns the store selected by the archive configuration
+func NewStore(cfg config.ArchiveConfig) (Store, error) {
+	switch cfg.Store {
+	case "local":
+		return NewLocalStore(cfg.Dir)
+	case "s3":
+		return NewS3Store(cfg.S3)
+	default:
+		return nil, fmt.Errorf("unknown archive store %q", cfg.Store)
+	}
+}
@@ -0,0 +1,47 @@
+package attribution
+
+import (
+	"context"
+	"platform/internal/models"
+	"platform/internal/repository/mysql"
+	"platform/pkg/logger"
+	"time"
+)
+
+// Backfill parses the referrer and UTM parameters of stored request logs with IDs
+// above afterID, batch by batch. Unless all is set, only rows not parsed yet are
+// visited; set it to reparse everything after the source lists change.
+func Backfill(ctx context.Context, repo *mysql.RequestRepository, batchSize int, afterID int64, all bool) (*models.AttributionBackfillReport, error) {
+	report := &models.AttributionBackfillReport{
+		LastID:    afterID,
+		StartedAt: time.Now().UTC(),
+	}
+
+	for {
+		if ctx.Err() != nil {
+			return report, ctx.Err()
+		}
+
+		requests, err := repo.GetAttributionBatch(report.LastID, batchSize, all)
+		if err != nil {
+			return report, err
+		}
+		if len(requests) == 0 {
+			break
+		}
+
+		for i := range requests {
+			requests[i].Attribution = Parse(&requests[i])
+		}
+		if err := repo.SetAttributions(requests); err != nil {
+			return report, err
+		}
+
+		report.Parsed += int64(len(requests))
+		report.LastID = requests[len(requests)-1].ID
+		logger.Info("Parsed stored attributions", "last_id", report.LastID, "parsed", report.Parsed)
+	}
+
+	report.FinishedAt = time.Now().UTC()
+	return report, nil
+}
@@ -0,0 +1,132 @@
+package attribution
+
+import (
+	"net/url"
+	"platform/internal/models"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// Column widths of request_logs
+const (
+	maxURLLength   = 2048
+	maxValueLength = 255
+)
+
+// Referrer domains by source category. A domain also matches its subdomains.
+// Email is checked first, so webmail hosts of search companies count as email.
+var (
+	emailDomains = []string{
+		"mail.google.com", "outlook.live.com", "outlook.office.com", "outlook.office365.com",
+		"mail.yahoo.com", "mail.aol.com", "mail.proton.me", "mail.yandex.ru", "e.mail.ru",
+		"mail.zoho.com", "webmail.gmx.net", "navigator.web.de",
+	}
+	searchDomains = []string{
+		"bing.com", "duckduckgo.com", "search.yahoo.com", "baidu.com", "ecosia.org",
+		"ask.com", "naver.com", "startpage.com", "qwant.com", "search.brave.com",
+		"seznam.cz", "so.com", "sogou.com",
+	}
+	socialDomains = []string{
+		"facebook.com", "fb.com", "fb.me", "instagram.com", "twitter.com", "x.com", "t.co",
+		"linkedin.com", "lnkd.in", "reddit.com", "pinterest.com", "tiktok.com", "youtube.com",
+		"youtu.be", "snapchat.com", "tumblr.com", "vk.com", "threads.net", "quora.com",
+		"t.me", "telegram.org", "whatsapp.com", "discord.com", "mastodon.social", "bsky.app",
+	}
+)
+
+// searchHosts matches search engines served from many country domains
+var searchHosts = regexp.MustCompile(`(^|\.)(google|yandex)\.[a-z]{2,3}(\.[a-z]{2})?$`)
+
+// Sources and mediums of UTM parameters by category, for clicks whose referrer
+// does not say more
+var (
+	mediumCategories = map[string]string{
+		"email": models.SourceEmail, "e-mail": models.SourceEmail, "newsletter": models.SourceEmail,
+		"social": models.SourceSocial, "social-media": models.SourceSocial, "social_media": models.SourceSocial,
+		"cpc": models.SourceSearch, "ppc": models.SourceSearch, "paidsearch": models.SourceSearch,
+		"paid_search": models.SourceSearch, "organic": models.SourceSearch, "search": models.SourceSearch,
+	}
+	sourceCategories = map[string]string{
+		"google": models.SourceSearch, "bing": models.SourceSearch, "duckduckgo": models.SourceSearch,
+		"yahoo": models.SourceSearch, "yandex": models.SourceSearch, "baidu": models.SourceSearch,
+		"facebook": models.SourceSocial, "fb": models.SourceSocial, "instagram": models.SourceSocial,
+		"twitter": models.SourceSocial, "x": models.SourceSocial, "linkedin": models.SourceSocial,
+		"reddit": models.SourceSocial, "pinterest": models.SourceSocial, "tiktok": models.SourceSocial,
+		"youtube": models.SourceSocial, "telegram": models.SourceSocial, "whatsapp": models.SourceSocial,
+		"newsletter": models.SourceEmail, "email": models.SourceEmail, "mailchimp": models.SourceEmail,
+	}
+)
+
+// Parse extracts the referrer and the UTM parameters of a stored request. The
+// referrer is only known if the privacy policy keeps the Referer header.
+func Parse(request *models.Request) *models.Attribution {
+	a := &models.Attribution{}
+
+	if referrer := strings.TrimSpace(request.Header("Referer")); referrer != "" {
+		if parsed, err := url.Parse(referrer); err == nil && parsed.Hostname() != "" {
+			a.ReferrerURL = truncate(referrer, maxURLLength)
+			a.ReferrerHost = truncate(strings.ToLower(parsed.Hostname()), maxValueLength)
+		}
+	}
+
+	if parsed, err := url.Parse(request.RequestURL); err == nil {
+		query := parsed.Query()
+		a.UTMSource = truncate(query.Get("utm_source"), maxValueLength)
+		a.UTMMedium = truncate(query.Get("utm_medium"), maxValueLength)
+		a.UTMCampaign = truncate(query.Get("utm_campaign"), maxValueLength)
+		a.UTMTerm = truncate(query.Get("utm_term"), maxValueLength)
+		a.UTMContent = truncate(query.Get("utm_content"), maxValueLength)
+	}
+
+	a.SourceCategory = category(a)
+	return a
+}
+
+// category prefers what the link's owner declared in utm_medium, then the
+// referrer's host, then utm_source
+func category(a *models.Attribution) string {
+	if c, ok := mediumCategories[strings.ToLower(a.UTMMedium)]; ok {
+		return c
+	}
+
+	if a.ReferrerHost != "" {
+		host := strings.TrimPrefix(a.ReferrerHost, "www.")
+		switch {
+		case matchDomain(host, emailDomains):
+			return models.SourceEmail
+		case searchHosts.MatchString(host) || matchDomain(host, searchDomains):
+			return models.SourceSearch
+		case matchDomain(host, socialDomains):
+			return models.SourceSocial
+		}
+	}
+
+	if c, ok := sourceCategories[strings.ToLower(a.UTMSource)]; ok {
+		return c
+	}
+	if a.ReferrerHost == "" && a.UTMSource == "" && a.UTMMedium == "" && a.UTMCampaign == "" {
+		return models.SourceDirect
+	}
+	return models.SourceOther
+}
+
+func matchDomain(host string, domains []string) bool {
+	for _, domain := range domains {
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// truncate shortens s to at most n bytes without splitting a UTF-8 character
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
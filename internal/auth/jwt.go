@@ -2,52 +2,145 @@ package auth
 
 import (
 	"fmt"
-	"os"
+	"platform/internal/config"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 )
 
-var jwtSecret = []byte(os.Getenv("JWT_SECRET"))
-
+// Claims of an access token. The JWT ID (jti) lets the token be revoked and
+// SessionID ties it to the refresh token family it was issued with.
 type Claims struct {
-	ClientID int64  `json:"client_id"`
-	Username string `json:"username"`
+	ClientID  int64  `json:"client_id"`
+	Username  string `json:"username"`
+	SessionID string `json:"sid"`
 	jwt.RegisteredClaims
 }
 
-func GenerateToken(clientID int64, username string) (string, error) {
+// Signer signs access tokens with the configured key and verifies them with
+// that key or any verification key, chosen by the token's kid header
+type Signer struct {
+	signing  *jwtKey
+	keys     map[string]*jwtKey
+	issuer   string
+	audience string
+}
+
+// NewSigner loads the keys of a JWT configuration, failing if the
+// configuration is incomplete or a key is unusable
+func NewSigner(cfg config.JWTConfig) (*Signer, error) {
+	if cfg.KeyID == "" {
+		return nil, fmt.Errorf("JWT key ID is required")
+	}
+	if cfg.Issuer == "" || cfg.Audience == "" {
+		return nil, fmt.Errorf("JWT issuer and audience are required")
+	}
+
+	signing, err := loadSigningKey(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Signer{
+		signing:  signing,
+		keys:     map[string]*jwtKey{signing.id: signing},
+		issuer:   cfg.Issuer,
+		audience: cfg.Audience,
+	}
+
+	for _, entry := range cfg.VerificationKeys {
+		id, path, err := splitKeyEntry(entry)
+		if err != nil {
+			return nil, err
+		}
+		key, err := loadPublicKey(id, path)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.addKey(key); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, entry := range cfg.PreviousSecrets {
+		id, secret, err := splitKeyEntry(entry)
+		if err != nil {
+			return nil, err
+		}
+		key, err := hmacKey(id, secret)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.addKey(key); err != nil {
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+func (s *Signer) addKey(key *jwtKey) error {
+	if _, exists := s.keys[key.id]; exists {
+		return fmt.Errorf("duplicate JWT key ID %q", key.id)
+	}
+	s.keys[key.id] = key
+	return nil
+}
+
+// GenerateToken issues an access token valid for ttl within a session
+func (s *Signer) GenerateToken(clientID int64, username, sessionID string, ttl time.Duration) (string, *Claims, error) {
 	// Set token claims
-	claims := Claims{
-		ClientID: clientID,
-		Username: username,
+	now := time.Now()
+	claims := &Claims{
+		ClientID:  clientID,
+		Username:  username,
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)), // Token expires in 24 hours
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ID:        uuid.New().String(),
+			Issuer:    s.issuer,
+			Audience:  jwt.ClaimStrings{s.audience},
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+			IssuedAt:  jwt.NewNumericDate(now),
 		},
 	}
 
 	// Create token
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	token := jwt.NewWithClaims(s.signing.method, claims)
+	token.Header["kid"] = s.signing.id
 
 	// Generate encoded token
-	tokenString, err := token.SignedString(jwtSecret)
+	tokenString, err := token.SignedString(s.signing.private)
 	if err != nil {
-		return "", fmt.Errorf("failed to generate token: %w", err)
+		return "", nil, fmt.Errorf("failed to generate token: %w", err)
 	}
 
-	return tokenString, nil
+	return tokenString, claims, nil
 }
 
-func ValidateToken(tokenString string) (*Claims, error) {
+// ValidateToken verifies a token's signature, expiry, issuer and audience.
+// Tokens without a kid header are verified with the signing key.
+func (s *Signer) ValidateToken(tokenString string) (*Claims, error) {
 	// Parse token
 	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
+		key := s.signing
+		if kid, ok := token.Header["kid"]; ok {
+			id, _ := kid.(string)
+			if key, ok = s.keys[id]; !ok {
+				return nil, fmt.Errorf("unknown key ID: %v", kid)
+			}
+		}
+
 		// Verify signing method
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+		if token.Method.Alg() != key.method.Alg() {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
-		return jwtSecret, nil
-	})
+		return key.public, nil
+	},
+		jwt.WithIssuer(s.issuer),
+		jwt.WithAudience(s.audience),
+		jwt.WithExpirationRequired(),
+	)
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse token: %w", err)
@@ -55,8 +148,24 @@ func ValidateToken(tokenString string) (*Claims, error) {
 
 	// Validate token
 	if claims, ok := token.Claims.(*Claims); ok && token.Valid {
+		// Tokens without an ID cannot be revoked and are no longer accepted
+		if claims.ID == "" || claims.SessionID == "" {
+			return nil, fmt.Errorf("token is not revocable")
+		}
 		return claims, nil
 	}
 
 	return nil, fmt.Errorf("invalid token")
-} 
\ No newline at end of file
+}
+
+// JWKS returns the public keys that verify tokens, for other services. HMAC
+// secrets are never published.
+func (s *Signer) JWKS() *JWKS {
+	set := &JWKS{Keys: []JWK{}}
+	for _, id := range sortedKeyIDs(s.keys) {
+		if jwk, ok := s.keys[id].jwk(); ok {
+			set.Keys = append(set.Keys, jwk)
+		}
+	}
+	return set
+}
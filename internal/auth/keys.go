@@ -0,0 +1,166 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"platform/internal/config"
+	"sort"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// minSecretLength is the shortest HS256 secret accepted, matching the size of
+// the SHA-256 output
+const minSecretLength = 32
+
+// jwtKey is a key that verifies tokens, and signs them when private is set
+type jwtKey struct {
+	id      string
+	method  jwt.SigningMethod
+	private interface{}
+	public  interface{}
+}
+
+// JWK is a public key in JSON Web Key format
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// Ed25519
+	Curve string `json:"crv,omitempty"`
+	X     string `json:"x,omitempty"`
+}
+
+// JWKS is a JSON Web Key Set
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+func loadSigningKey(cfg config.JWTConfig) (*jwtKey, error) {
+	switch cfg.Algorithm {
+	case "HS256":
+		return hmacKey(cfg.KeyID, cfg.Secret)
+	case "RS256", "EdDSA":
+		if cfg.PrivateKeyFile == "" {
+			return nil, fmt.Errorf("JWT private key file is required for %s", cfg.Algorithm)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported JWT algorithm %q", cfg.Algorithm)
+	}
+
+	block, err := readPEM(cfg.PrivateKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	private, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		rsaKey, rsaErr := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if rsaErr != nil {
+			return nil, fmt.Errorf("failed to parse JWT private key %s: %w", cfg.PrivateKeyFile, err)
+		}
+		private = rsaKey
+	}
+
+	key := &jwtKey{id: cfg.KeyID, private: private}
+	switch private := private.(type) {
+	case *rsa.PrivateKey:
+		key.method, key.public = jwt.SigningMethodRS256, &private.PublicKey
+	case ed25519.PrivateKey:
+		key.method, key.public = jwt.SigningMethodEdDSA, private.Public()
+	default:
+		return nil, fmt.Errorf("unsupported JWT private key type %T", private)
+	}
+	if key.method.Alg() != cfg.Algorithm {
+		return nil, fmt.Errorf("JWT private key is a %s key, not %s", key.method.Alg(), cfg.Algorithm)
+	}
+	return key, nil
+}
+
+func loadPublicKey(id, path string) (*jwtKey, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+	public, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse JWT public key %s: %w", path, err)
+	}
+
+	key := &jwtKey{id: id, public: public}
+	switch public.(type) {
+	case *rsa.PublicKey:
+		key.method = jwt.SigningMethodRS256
+	case ed25519.PublicKey:
+		key.method = jwt.SigningMethodEdDSA
+	default:
+		return nil, fmt.Errorf("unsupported JWT public key type %T in %s", public, path)
+	}
+	return key, nil
+}
+
+func hmacKey(id, secret string) (*jwtKey, error) {
+	if len(secret) < minSecretLength {
+		return nil, fmt.Errorf("JWT secret %q must be at least %d bytes", id, minSecretLength)
+	}
+	return &jwtKey{id: id, method: jwt.SigningMethodHS256, private: []byte(secret), public: []byte(secret)}, nil
+}
+
+func readPEM(path string) (*pem.Block, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JWT key: %w", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data in JWT key %s", path)
+	}
+	return block, nil
+}
+
+// splitKeyEntry splits a "kid=value" configuration entry. The entry is left
+// out of errors as it may hold a secret.
+func splitKeyEntry(entry string) (string, string, error) {
+	id, value, ok := strings.Cut(strings.TrimSpace(entry), "=")
+	if !ok || id == "" || value == "" {
+		return "", "", fmt.Errorf("invalid JWT key entry, expected kid=value")
+	}
+	return id, value, nil
+}
+
+// jwk returns the key in JWK format, or false for HMAC secrets
+func (k *jwtKey) jwk() (JWK, bool) {
+	jwk := JWK{KeyID: k.id, Use: "sig", Algorithm: k.method.Alg()}
+	switch public := k.public.(type) {
+	case *rsa.PublicKey:
+		jwk.KeyType = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+	case ed25519.PublicKey:
+		jwk.KeyType = "OKP"
+		jwk.Curve = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(public)
+	default:
+		return JWK{}, false
+	}
+	return jwk, true
+}
+
+func sortedKeyIDs(keys map[string]*jwtKey) []string {
+	ids := make([]string, 0, len(keys))
+	for id := range keys {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
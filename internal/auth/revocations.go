@@ -0,0 +1,106 @@
+package auth
+
+import (
+	"context"
+	"platform/internal/models"
+	"platform/internal/repository/mysql"
+	"platform/pkg/logger"
+	"sync"
+	"time"
+)
+
+type cachedRevocation struct {
+	revoked bool
+	until   time.Time
+}
+
+// Revocations answers whether an access token was revoked. Revoked tokens are
+// remembered until they expire; tokens found valid are rechecked after ttl, so
+// a revocation made through another gateway replica applies within ttl.
+type Revocations struct {
+	repo *mysql.TokenRepository
+	ttl  time.Duration
+
+	mu    sync.Mutex
+	cache map[string]cachedRevocation
+}
+
+func NewRevocations(repo *mysql.TokenRepository, ttl time.Duration) *Revocations {
+	return &Revocations{
+		repo:  repo,
+		ttl:   ttl,
+		cache: make(map[string]cachedRevocation),
+	}
+}
+
+// IsRevoked reports whether the access token with the given ID and expiry was
+// revoked
+func (r *Revocations) IsRevoked(jti string, expiresAt time.Time) (bool, error) {
+	now := time.Now()
+	r.mu.Lock()
+	cached, ok := r.cache[jti]
+	r.mu.Unlock()
+	if ok && now.Before(cached.until) {
+		return cached.revoked, nil
+	}
+
+	revoked, err := r.repo.IsRevoked(jti)
+	if err != nil {
+		return false, err
+	}
+
+	until := now.Add(r.ttl)
+	if revoked || until.After(expiresAt) {
+		until = expiresAt
+	}
+	r.mu.Lock()
+	r.cache[jti] = cachedRevocation{revoked: revoked, until: until}
+	r.mu.Unlock()
+	return revoked, nil
+}
+
+// Remember records revocations made through this replica so they apply at once
+func (r *Revocations) Remember(tokens []models.RevokedToken) {
+	r.mu.Lock()
+	for _, token := range tokens {
+		r.cache[token.JTI] = cachedRevocation{revoked: true, until: token.ExpiresAt}
+	}
+	r.mu.Unlock()
+}
+
+// Watch drops expired entries from the cache and expired tokens from the
+// database every interval until ctx is done
+func (r *Revocations) Watch(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				r.prune(time.Now())
+			}
+		}
+	}()
+}
+
+func (r *Revocations) prune(now time.Time) {
+	r.mu.Lock()
+	for jti, cached := range r.cache {
+		if !now.Before(cached.until) {
+			delete(r.cache, jti)
+		}
+	}
+	r.mu.Unlock()
+
+	deleted, err := r.repo.DeleteExpired(now)
+	if err != nil {
+		logger.Error("Failed to delete expired tokens", "error", err)
+		return
+	}
+	if deleted > 0 {
+		logger.Info("Deleted expired tokens", "count", deleted)
+	}
+}
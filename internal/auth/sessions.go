@@ -0,0 +1,161 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"platform/internal/config"
+	"platform/internal/models"
+	"platform/internal/repository/mysql"
+	"platform/pkg/logger"
+	"time"
+)
+
+const refreshTokenPrefix = "rt_"
+
+var (
+	// ErrInvalidRefreshToken is returned for unknown, expired and revoked
+	// refresh tokens
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	// ErrRefreshTokenReused is returned when a refresh token is presented after
+	// it was already exchanged. The whole session is revoked.
+	ErrRefreshTokenReused = errors.New("refresh token reused")
+)
+
+// Sessions issues access and refresh tokens. A session starts at login and is
+// a family of refresh tokens, each exchanged exactly once for an access token
+// and the next refresh token.
+type Sessions struct {
+	signer      *Signer
+	tokenRepo   *mysql.TokenRepository
+	clientRepo  *mysql.ClientRepository
+	revocations *Revocations
+	accessTTL   time.Duration
+	refreshTTL  time.Duration
+}
+
+func NewSessions(signer *Signer, tokenRepo *mysql.TokenRepository, clientRepo *mysql.ClientRepository, revocations *Revocations, cfg config.AuthConfig) *Sessions {
+	return &Sessions{
+		signer:      signer,
+		tokenRepo:   tokenRepo,
+		clientRepo:  clientRepo,
+		revocations: revocations,
+		accessTTL:   cfg.AccessTokenTTL,
+		refreshTTL:  cfg.RefreshTokenTTL,
+	}
+}
+
+// Start opens a new session for a client that just authenticated
+func (s *Sessions) Start(client *models.Client) (*models.TokenPair, error) {
+	pair, refresh, err := s.issue(client, uuid.New().String())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.tokenRepo.CreateRefreshToken(refresh); err != nil {
+		return nil, err
+	}
+	return pair, nil
+}
+
+// Refresh exchanges a refresh token for a new token pair in the same session.
+// Presenting a token that was already exchanged revokes the session, since
+// either the client or someone who stole the token holds a newer one.
+func (s *Sessions) Refresh(token string) (*models.TokenPair, error) {
+	stored, err := s.tokenRepo.GetRefreshToken(HashOpaqueToken(token))
+	if err != nil {
+		return nil, err
+	}
+	if stored == nil || stored.RevokedAt != nil || !time.Now().Before(stored.ExpiresAt) {
+		return nil, ErrInvalidRefreshToken
+	}
+	if stored.UsedAt != nil {
+		return nil, s.revokeReused(stored)
+	}
+
+	client, err := s.clientRepo.GetByID(stored.ClientID)
+	if err != nil {
+		return nil, err
+	}
+	if client == nil || client.SuspendedAt != nil {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	pair, next, err := s.issue(client, stored.FamilyID)
+	if err != nil {
+		return nil, err
+	}
+
+	rotated, err := s.tokenRepo.RotateRefreshToken(stored.ID, next)
+	if err != nil {
+		return nil, err
+	}
+	if !rotated {
+		// Exchanged concurrently by another request
+		return nil, s.revokeReused(stored)
+	}
+	return pair, nil
+}
+
+// End revokes the session an access token belongs to, along with every access
+// token issued in it that has not expired yet
+func (s *Sessions) End(claims *Claims) error {
+	revoked, err := s.tokenRepo.RevokeFamily(claims.ClientID, claims.SessionID)
+	if err != nil {
+		return err
+	}
+
+	s.revocations.Remember(revoked)
+	s.revocations.Remember([]models.RevokedToken{{JTI: claims.ID, ExpiresAt: claims.ExpiresAt.Time}})
+	return nil
+}
+
+// EndAll revokes every session of a client but exceptSessionID, which may be
+// empty to end them all
+func (s *Sessions) EndAll(clientID int64, exceptSessionID string) error {
+	revoked, err := s.tokenRepo.RevokeClient(clientID, exceptSessionID)
+	if err != nil {
+		return err
+	}
+
+	s.revocations.Remember(revoked)
+	return nil
+}
+
+func (s *Sessions) revokeReused(stored *models.RefreshToken) error {
+	logger.Error("Refresh token reused, revoking session", "client_id", stored.ClientID, "family_id", stored.FamilyID)
+
+	revoked, err := s.tokenRepo.RevokeFamily(stored.ClientID, stored.FamilyID)
+	if err != nil {
+		return err
+	}
+	s.revocations.Remember(revoked)
+	return ErrRefreshTokenReused
+}
+
+func (s *Sessions) issue(client *models.Client, familyID string) (*models.TokenPair, *models.RefreshToken, error) {
+	access, claims, err := s.signer.GenerateToken(client.ID, client.Username, familyID, s.accessTTL)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	refresh, refreshHash, err := GenerateOpaqueToken(refreshTokenPrefix)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+
+	pair := &models.TokenPair{
+		Token:        access,
+		ExpiresIn:    int64(s.accessTTL / time.Second),
+		RefreshToken: refresh,
+	}
+	stored := &models.RefreshToken{
+		ClientID:        client.ID,
+		FamilyID:        familyID,
+		TokenHash:       refreshHash,
+		AccessJTI:       claims.ID,
+		AccessExpiresAt: claims.ExpiresAt.Time,
+		ExpiresAt:       time.Now().Add(s.refreshTTL),
+	}
+	return pair, stored, nil
+}
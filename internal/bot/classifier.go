@@ -0,0 +1,63 @@
+package bot
+
+import (
+	"context"
+	"platform/internal/config"
+	"platform/internal/models"
+	"platform/pkg/metrics"
+)
+
+var (
+	classified     = metrics.Map("bot_clicks")
+	reloads        = metrics.Int("bot_crawler_ranges_reloads")
+	reloadFailures = metrics.Int("bot_crawler_ranges_reload_failures")
+)
+
+// Classifier flags automated clicks in the database worker. It combines the
+// User-Agent signatures of the parser, the ranges of known crawlers and bursts
+// of clicks from one IP. Classification sees the IP as stored, so ranges only
+// match IPs the privacy policy keeps whole or truncates within the range, and
+// a truncated IP shares its burst counter with its network.
+type Classifier struct {
+	cfg    config.BotConfig
+	ranges *Ranges
+	burst  *WindowCounter
+}
+
+func NewClassifier(cfg config.BotConfig) *Classifier {
+	return &Classifier{
+		cfg:    cfg,
+		ranges: LoadRanges(cfg.CrawlerRangesFile),
+		burst:  NewWindowCounter(cfg.BurstWindow),
+	}
+}
+
+// Watch reloads the crawler ranges when their file changes until the context is cancelled
+func (c *Classifier) Watch(ctx context.Context) {
+	go c.ranges.Watch(ctx, c.cfg.ReloadInterval)
+}
+
+// Classify reports whether a click looks automated and the first signal that
+// matched. The request's User-Agent must already be parsed. Every click counts
+// towards the burst of its IP, whether or not another signal matched.
+func (c *Classifier) Classify(event *models.ClickEvent) (bool, string) {
+	request := &event.Request
+	clicks := c.burst.Add(request.IPAddress, event.Timestamp)
+
+	reason := ""
+	switch {
+	case request.UserAgent != nil && request.UserAgent.IsBot:
+		reason = models.BotReasonUserAgent
+	case c.cfg.FlagMissingUserAgent && request.Header("User-Agent") == "":
+		reason = models.BotReasonNoUserAgent
+	case c.ranges.Contains(request.IPAddress):
+		reason = models.BotReasonCrawlerIP
+	case c.cfg.BurstThreshold > 0 && clicks > c.cfg.BurstThreshold:
+		reason = models.BotReasonBurst
+	default:
+		return false, ""
+	}
+
+	classified.Add(reason, 1)
+	return true, reason
+}
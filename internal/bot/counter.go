@@ -0,0 +1,51 @@
+package bot
+
+import (
+	"sync"
+	"time"
+)
+
+// WindowCounter counts clicks per key, such as an IP, in fixed windows of
+// click time. Only the latest window is kept: clicks arriving late for an
+// earlier window are not counted against the current one.
+type WindowCounter struct {
+	window time.Duration
+
+	mu      sync.Mutex
+	current time.Time
+	counts  map[string]int
+}
+
+// NewWindowCounter returns a counter with windows of the given length. A zero
+// window disables counting.
+func NewWindowCounter(window time.Duration) *WindowCounter {
+	return &WindowCounter{
+		window: window,
+		counts: make(map[string]int),
+	}
+}
+
+// Add counts a click of key at t and returns the key's clicks in that window so
+// far, or 0 when the click was not counted
+func (c *WindowCounter) Add(key string, t time.Time) int {
+	if c.window <= 0 || key == "" {
+		return 0
+	}
+
+	start := t.Truncate(c.window)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	switch {
+	case start.After(c.current):
+		// Counts of past windows are no longer needed
+		c.current = start
+		c.counts = make(map[string]int)
+	case start.Before(c.current):
+		return 0
+	}
+
+	c.counts[key]++
+	return c.counts[key]
+}
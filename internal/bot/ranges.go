@@ -0,0 +1,151 @@
+package bot
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"platform/pkg/logger"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Ranges is a list of known crawler networks read from a file with one CIDR
+// range or single IP per line, optionally followed by a label such as the
+// crawler's name; blank lines and text after # are ignored. Like the GeoIP
+// databases it is reloaded when the file changes, and a file that fails to
+// parse leaves the previous list active.
+type Ranges struct {
+	path    string
+	current atomic.Pointer[[]*net.IPNet]
+
+	mu          sync.Mutex
+	modTime     time.Time
+	size        int64
+	statFailing bool
+}
+
+// LoadRanges reads the ranges file at path. A missing file is not an error:
+// no IP matches until it appears. An empty path disables the check.
+func LoadRanges(path string) *Ranges {
+	r := &Ranges{path: path}
+	if path != "" {
+		r.Reload()
+	}
+	return r
+}
+
+// Reload reads the file again if its size or modification time changed since
+// the last load, reporting whether a new list was activated
+func (r *Ranges) Reload() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	info, err := os.Stat(r.path)
+	if err != nil {
+		if !r.statFailing {
+			logger.Error("Crawler ranges unavailable", "path", r.path, "error", err)
+		}
+		r.statFailing = true
+		return false
+	}
+	r.statFailing = false
+	if info.ModTime().Equal(r.modTime) && info.Size() == r.size {
+		return false
+	}
+	r.modTime = info.ModTime()
+	r.size = info.Size()
+
+	data, err := os.ReadFile(r.path)
+	if err != nil {
+		reloadFailures.Add(1)
+		logger.Error("Failed to read crawler ranges", "path", r.path, "error", err)
+		return false
+	}
+	networks, err := parseRanges(data)
+	if err != nil {
+		reloadFailures.Add(1)
+		logger.Error("Failed to load crawler ranges", "path", r.path, "error", err)
+		return false
+	}
+
+	r.current.Store(&networks)
+	reloads.Add(1)
+	logger.Info("Loaded crawler ranges", "path", r.path, "ranges", len(networks))
+	return true
+}
+
+// Watch checks the file for changes once per interval until the context is cancelled
+func (r *Ranges) Watch(ctx context.Context, interval time.Duration) {
+	if r.path == "" || interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.Reload()
+		}
+	}
+}
+
+// Contains reports whether ip falls in one of the ranges. Values that are not
+// IPs, such as hashed IPs, never match.
+func (r *Ranges) Contains(ip string) bool {
+	networks := r.current.Load()
+	if networks == nil {
+		return false
+	}
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	for _, network := range *networks {
+		if network.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
+
+func parseRanges(data []byte) ([]*net.IPNet, error) {
+	var networks []*net.IPNet
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		if i := strings.IndexByte(text, '#'); i >= 0 {
+			text = text[:i]
+		}
+		fields := strings.Fields(text)
+		if len(fields) == 0 {
+			continue
+		}
+		text = fields[0]
+
+		if !strings.Contains(text, "/") {
+			if strings.Contains(text, ":") {
+				text += "/128"
+			} else {
+				text += "/32"
+			}
+		}
+		_, network, err := net.ParseCIDR(text)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		networks = append(networks, network)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return networks, nil
+}
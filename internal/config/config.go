@@ -3,12 +3,35 @@ package config
 import (
 	"fmt"
 	"github.com/spf13/viper"
+	"os"
+	"platform/pkg/logger"
+	"strconv"
+	"time"
 )
 
 type Config struct {
-	Server   ServerConfig
-	MySQL    MySQLConfig
-	RabbitMQ RabbitMQConfig
+	Server    ServerConfig
+	MySQL     MySQLConfig
+	RabbitMQ  RabbitMQConfig
+	Webhook   WebhookConfig
+	Rollup    RollupConfig
+	Stream    StreamConfig
+	Retention RetentionConfig
+	Archive   ArchiveConfig
+	Privacy   PrivacyConfig
+	UTM       UTMConfig
+	UserAgent UserAgentConfig
+	GeoIP     GeoIPConfig
+	Bot       BotConfig
+	Fraud     FraudConfig
+	Visitor   VisitorConfig
+	Lifecycle LifecycleConfig
+	Auth      AuthConfig
+	JWT       JWTConfig
+	Org       OrgConfig
+	Account   AccountConfig
+	Mail      MailConfig
+	Metrics   MetricsConfig
 }
 
 type ServerConfig struct {
@@ -29,6 +52,286 @@ type RabbitMQConfig struct {
 	User     string
 	Password string
 	Queue    string
+	Exchange string
+}
+
+// WebhookConfig controls how the database worker delivers outbound webhooks
+type WebhookConfig struct {
+	MaxAttempts      int
+	InitialBackoff   time.Duration
+	MaxBackoff       time.Duration
+	Timeout          time.Duration
+	FailureThreshold int
+	PollInterval     time.Duration
+	BatchSize        int
+}
+
+// RollupConfig controls how often the database worker writes click rollups
+type RollupConfig struct {
+	FlushInterval time.Duration
+}
+
+// StreamConfig controls the live click stream served by every API gateway replica
+type StreamConfig struct {
+	HeartbeatInterval time.Duration
+	// BufferSize is the number of events buffered per stream client; events
+	// for a client whose buffer is full are dropped
+	BufferSize int
+	// QueueMaxLength bounds the replica's subscription queue in RabbitMQ
+	QueueMaxLength int
+}
+
+// RetentionConfig controls the database worker's purge of raw click data.
+// Zero days keeps a table's data forever unless a client overrides it.
+type RetentionConfig struct {
+	Enabled             bool
+	DryRun              bool
+	Interval            time.Duration
+	RequestLogsDays     int
+	RedirectHistoryDays int
+	// MaxDays bounds the overrides clients can set
+	MaxDays    int
+	BatchSize  int
+	BatchPause time.Duration
+	// Partitioned drops whole monthly partitions of request_logs and creates
+	// PartitionsAhead months of partitions in advance
+	Partitioned     bool
+	PartitionsAhead int
+	// RequireArchive keeps rows until the day they belong to has been archived
+	RequireArchive bool
+}
+
+// ArchiveConfig controls the database worker's export of raw click data
+type ArchiveConfig struct {
+	Enabled  bool
+	Interval time.Duration
+	// Lag is how long after its end a UTC day is considered closed
+	Lag         time.Duration
+	RowsPerFile int
+	// Store is "local" (files under Dir) or "s3" (an S3-compatible bucket)
+	Store string
+	Dir   string
+	S3    S3Config
+}
+
+type S3Config struct {
+	Endpoint  string
+	Region    string
+	Bucket    string
+	Prefix    string
+	AccessKey string
+	SecretKey string
+	// PathStyle addresses the bucket as endpoint/bucket, as MinIO expects
+	PathStyle bool
+}
+
+// PrivacyConfig is the platform privacy policy the API gateway applies to request
+// logs before publishing them. Clients can tighten it but not loosen it.
+type PrivacyConfig struct {
+	// HeaderMode "deny" stores every header except Headers, "allow" only Headers
+	HeaderMode string
+	Headers    []string
+	// IPMode is "full", "truncate" (to IPv4Prefix or IPv6Prefix bits) or "hash"
+	// (keyed with IPHashKey)
+	IPMode     string
+	IPv4Prefix int
+	IPv6Prefix int
+	IPHashKey  string
+	// SettingsCacheTTL bounds how long the gateway caches a client's settings
+	SettingsCacheTTL time.Duration
+}
+
+// UTMConfig controls how the API gateway applies clients' default UTM parameters
+type UTMConfig struct {
+	// SettingsCacheTTL bounds how long the gateway caches a client's defaults
+	SettingsCacheTTL time.Duration
+}
+
+// UserAgentConfig controls how the database worker parses User-Agents. An empty
+// RulesFile uses the rules built into the binary.
+type UserAgentConfig struct {
+	RulesFile string
+}
+
+// GeoIPConfig points to local MaxMind-format (MMDB) databases used to locate
+// clicks without calling outside services. Empty paths disable a database; the
+// files are checked for changes every ReloadInterval.
+type GeoIPConfig struct {
+	CityDatabase   string
+	ASNDatabase    string
+	ReloadInterval time.Duration
+}
+
+// BotConfig controls how the database worker flags automated clicks.
+// CrawlerRangesFile lists CIDR ranges of known crawlers, one per line, and is
+// checked for changes every ReloadInterval. An IP producing more than
+// BurstThreshold clicks within BurstWindow is flagged; 0 disables the check.
+// FlagMissingUserAgent should be turned off when the privacy policy drops the
+// User-Agent header, as every click would then look header-less.
+type BotConfig struct {
+	CrawlerRangesFile    string
+	ReloadInterval       time.Duration
+	BurstWindow          time.Duration
+	BurstThreshold       int
+	FlagMissingUserAgent bool
+}
+
+// FraudConfig controls the fraud score the database worker computes per click.
+// Clicks scoring FlagScore or more count as flagged. An IP with more than
+// VelocityThreshold clicks within VelocityWindow fires the velocity rule (0
+// disables it); clicks from DatacenterASNs or lacking any of RequiredHeaders
+// fire their rules too.
+type FraudConfig struct {
+	FlagScore         int
+	VelocityWindow    time.Duration
+	VelocityThreshold int
+	DatacenterASNs    []uint32
+	RequiredHeaders   []string
+}
+
+// VisitorConfig controls how visitors are told apart. The API gateway identifies
+// them by the CookieName cookie, or for requests without it by a hash of IP and
+// User-Agent salted per UTC day, and sets the cookie to that hash; with
+// SetCookie off it only hashes. The database worker marks a click unique when
+// its visitor had no other click on the mapping within UniqueWindow before it.
+type VisitorConfig struct {
+	SetCookie    bool
+	CookieName   string
+	CookieDomain string
+	CookieMaxAge time.Duration
+	CookieSecure bool
+	UniqueWindow time.Duration
+}
+
+// LifecycleConfig sets what the API gateway serves for mappings that are not
+// active, unless a mapping sets its own: "fallback" redirects to the mapping's
+// fallback URL, "gone" answers 410 and "holding" serves the HoldingPage HTML
+// file, or a built-in page when it is empty. Drafts are served like paused
+// mappings.
+type LifecycleConfig struct {
+	PausedResponse   string
+	ArchivedResponse string
+	HoldingPage      string
+}
+
+// AuthConfig controls the tokens the API gateway issues. Access tokens expire
+// after AccessTokenTTL; refresh tokens expire when unused for RefreshTokenTTL.
+// The gateway caches that an access token is not revoked for up to
+// RevocationCacheTTL and deletes expired tokens every PruneInterval.
+type AuthConfig struct {
+	AccessTokenTTL     time.Duration
+	RefreshTokenTTL    time.Duration
+	RevocationCacheTTL time.Duration
+	PruneInterval      time.Duration
+}
+
+// JWTConfig controls how the API gateway signs and verifies access tokens.
+// Algorithm is "HS256", signing with Secret, or "RS256" or "EdDSA", signing with
+// the PEM private key in PrivateKeyFile. Tokens carry KeyID as their kid header.
+// VerificationKeys ("kid=file" PEM public keys) and PreviousSecrets
+// ("kid=secret") are also accepted, so tokens signed before a key rotation
+// stay valid until they expire. Tokens must name Issuer and Audience.
+type JWTConfig struct {
+	Algorithm        string
+	Secret           string
+	PrivateKeyFile   string
+	KeyID            string
+	VerificationKeys []string
+	PreviousSecrets  []string
+	Issuer           string
+	Audience         string
+}
+
+// OrgConfig controls organizations. Invitations expire after InvitationTTL.
+type OrgConfig struct {
+	InvitationTTL time.Duration
+}
+
+// AccountConfig controls email verification and password resets. Tokens
+// expire after VerificationTTL and PasswordResetTTL; emails link to AppURL,
+// when set, and otherwise only carry the token. UnverifiedPolicy is "allow",
+// "limit", capping unverified accounts at UnverifiedMaxMappings mappings and
+// keeping them from creating API keys, webhooks and organizations, or "block",
+// keeping them out of the API until they verify their email.
+type AccountConfig struct {
+	VerificationTTL       time.Duration
+	PasswordResetTTL      time.Duration
+	AppURL                string
+	UnverifiedPolicy      string
+	UnverifiedMaxMappings int
+}
+
+// MailConfig controls how the API gateway sends email. Driver is "smtp",
+// "file", appending messages to File, or "stdout". SMTPTLS is "auto", using
+// STARTTLS when the server offers it, "starttls" or "none".
+type MailConfig struct {
+	Driver       string
+	From         string
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUsername string
+	SMTPPassword string
+	SMTPTLS      string
+	File         string
+}
+
+// MetricsConfig controls where the database worker serves its metrics.
+// An empty address disables the listener.
+type MetricsConfig struct {
+	Addr string
+}
+
+// validate rejects intervals that must be positive; a ticker panics on zero or
+// a negative duration
+func (c *Config) validate() error {
+	type interval struct {
+		env   string
+		value time.Duration
+	}
+	intervals := []interval{
+		{"WEBHOOK_TIMEOUT", c.Webhook.Timeout},
+		{"WEBHOOK_POLL_INTERVAL", c.Webhook.PollInterval},
+		{"ROLLUP_FLUSH_INTERVAL", c.Rollup.FlushInterval},
+		{"STREAM_HEARTBEAT_INTERVAL", c.Stream.HeartbeatInterval},
+		{"AUTH_PRUNE_INTERVAL", c.Auth.PruneInterval},
+	}
+	if c.Retention.Enabled {
+		intervals = append(intervals, interval{"RETENTION_INTERVAL", c.Retention.Interval})
+	}
+	if c.Archive.Enabled {
+		intervals = append(intervals, interval{"ARCHIVE_INTERVAL", c.Archive.Interval})
+	}
+
+	for _, i := range intervals {
+		if i.value <= 0 {
+			return fmt.Errorf("invalid config: %s must be greater than zero, got %s", i.env, i.value)
+		}
+	}
+	return nil
+}
+
+// applyJWTExpirationHours honours JWT_EXPIRATION_HOURS, which AUTH_ACCESS_TOKEN_TTL
+// replaced, as the access token TTL for deployments that still set it. When both
+// are set, AUTH_ACCESS_TOKEN_TTL wins.
+func (c *Config) applyJWTExpirationHours() error {
+	raw := os.Getenv("JWT_EXPIRATION_HOURS")
+	if raw == "" {
+		return nil
+	}
+
+	hours, err := strconv.Atoi(raw)
+	if err != nil || hours < 1 {
+		return fmt.Errorf("invalid config: JWT_EXPIRATION_HOURS must be a positive number of hours, got %q", raw)
+	}
+	if os.Getenv("AUTH_ACCESS_TOKEN_TTL") != "" || viper.InConfig("auth.accesstokenttl") {
+		logger.Info("Ignoring deprecated JWT_EXPIRATION_HOURS; AUTH_ACCESS_TOKEN_TTL is set")
+		return nil
+	}
+
+	c.Auth.AccessTokenTTL = time.Duration(hours) * time.Hour
+	logger.Info("JWT_EXPIRATION_HOURS is deprecated, use AUTH_ACCESS_TOKEN_TTL instead",
+		"access_token_ttl", c.Auth.AccessTokenTTL.String())
+	return nil
 }
 
 func LoadConfig() (*Config, error) {
@@ -49,6 +352,91 @@ func LoadConfig() (*Config, error) {
 	viper.SetDefault("rabbitmq.user", "guest")
 	viper.SetDefault("rabbitmq.password", "guest")
 	viper.SetDefault("rabbitmq.queue", "request_queue")
+	viper.SetDefault("rabbitmq.exchange", "click_events")
+	viper.SetDefault("webhook.maxattempts", 8)
+	viper.SetDefault("webhook.initialbackoff", "30s")
+	viper.SetDefault("webhook.maxbackoff", "6h")
+	viper.SetDefault("webhook.timeout", "10s")
+	viper.SetDefault("webhook.failurethreshold", 20)
+	viper.SetDefault("webhook.pollinterval", "5s")
+	viper.SetDefault("webhook.batchsize", 50)
+	viper.SetDefault("rollup.flushinterval", "10s")
+	viper.SetDefault("stream.heartbeatinterval", "15s")
+	viper.SetDefault("stream.buffersize", 64)
+	viper.SetDefault("stream.queuemaxlength", 10000)
+	viper.SetDefault("retention.enabled", false)
+	viper.SetDefault("retention.dryrun", false)
+	viper.SetDefault("retention.interval", "1h")
+	viper.SetDefault("retention.requestlogsdays", 0)
+	viper.SetDefault("retention.redirecthistorydays", 0)
+	viper.SetDefault("retention.maxdays", 730)
+	viper.SetDefault("retention.batchsize", 1000)
+	viper.SetDefault("retention.batchpause", "100ms")
+	viper.SetDefault("retention.partitioned", false)
+	viper.SetDefault("retention.partitionsahead", 3)
+	viper.SetDefault("retention.requirearchive", false)
+	viper.SetDefault("archive.enabled", false)
+	viper.SetDefault("archive.interval", "1h")
+	viper.SetDefault("archive.lag", "6h")
+	viper.SetDefault("archive.rowsperfile", 100000)
+	viper.SetDefault("archive.store", "local")
+	viper.SetDefault("archive.dir", "/var/lib/platform/archive")
+	viper.SetDefault("archive.s3.region", "us-east-1")
+	viper.SetDefault("archive.s3.pathstyle", true)
+	viper.SetDefault("privacy.headermode", "deny")
+	viper.SetDefault("privacy.headers", []string{
+		"Authorization", "Cookie", "Proxy-Authorization", "X-Api-Key",
+		"Forwarded", "X-Forwarded-For", "X-Real-Ip", "True-Client-Ip", "Cf-Connecting-Ip",
+	})
+	viper.SetDefault("privacy.ipmode", "full")
+	viper.SetDefault("privacy.ipv4prefix", 24)
+	viper.SetDefault("privacy.ipv6prefix", 48)
+	viper.SetDefault("privacy.settingscachettl", "1m")
+	viper.SetDefault("utm.settingscachettl", "1m")
+	viper.SetDefault("geoip.reloadinterval", "1m")
+	viper.SetDefault("bot.reloadinterval", "1m")
+	viper.SetDefault("bot.burstwindow", "10s")
+	viper.SetDefault("bot.burstthreshold", 20)
+	viper.SetDefault("bot.flagmissinguseragent", true)
+	viper.SetDefault("fraud.flagscore", 50)
+	viper.SetDefault("fraud.velocitywindow", "1h")
+	viper.SetDefault("fraud.velocitythreshold", 100)
+	// Large cloud and hosting providers: AWS, Google Cloud, Azure, DigitalOcean,
+	// OVH, Hetzner, Linode, Vultr, Oracle Cloud, Alibaba Cloud, Contabo, Scaleway
+	viper.SetDefault("fraud.datacenterasns", []uint32{
+		14618, 16509, 396982, 8075, 14061, 16276, 24940, 63949, 20473, 31898, 45102, 51167, 12876,
+	})
+	viper.SetDefault("fraud.requiredheaders", []string{"User-Agent", "Accept", "Accept-Language"})
+	viper.SetDefault("visitor.setcookie", true)
+	viper.SetDefault("visitor.cookiename", "visitor_id")
+	viper.SetDefault("visitor.cookiemaxage", "8760h")
+	viper.SetDefault("visitor.cookiesecure", false)
+	viper.SetDefault("visitor.uniquewindow", "24h")
+	viper.SetDefault("lifecycle.pausedresponse", "holding")
+	viper.SetDefault("lifecycle.archivedresponse", "gone")
+	viper.SetDefault("auth.accesstokenttl", "15m")
+	viper.SetDefault("auth.refreshtokenttl", "720h")
+	viper.SetDefault("auth.revocationcachettl", "30s")
+	viper.SetDefault("auth.pruneinterval", "1h")
+	viper.SetDefault("jwt.algorithm", "HS256")
+	viper.SetDefault("jwt.keyid", "1")
+	viper.SetDefault("jwt.issuer", "platform")
+	viper.SetDefault("jwt.audience", "platform-api")
+	viper.SetDefault("org.invitationttl", "168h")
+	viper.SetDefault("account.verificationttl", "48h")
+	viper.SetDefault("account.passwordresetttl", "1h")
+	viper.SetDefault("account.appurl", "")
+	viper.SetDefault("account.unverifiedpolicy", "limit")
+	viper.SetDefault("account.unverifiedmaxmappings", 3)
+	viper.SetDefault("mail.driver", "stdout")
+	viper.SetDefault("mail.from", "Platform <no-reply@localhost>")
+	viper.SetDefault("mail.smtphost", "localhost")
+	viper.SetDefault("mail.smtpport", 25)
+	viper.SetDefault("mail.smtpusername", "")
+	viper.SetDefault("mail.smtppassword", "")
+	viper.SetDefault("mail.smtptls", "auto")
+	viper.SetDefault("mail.file", "mail.log")
+	viper.SetDefault("metrics.addr", ":9090")
 
 	// Read environment variables
 	viper.BindEnv("mysql.host", "MYSQL_HOST")
@@ -61,6 +449,100 @@ func LoadConfig() (*Config, error) {
 	viper.BindEnv("rabbitmq.user", "RABBITMQ_USER")
 	viper.BindEnv("rabbitmq.password", "RABBITMQ_PASSWORD")
 	viper.BindEnv("rabbitmq.queue", "RABBITMQ_QUEUE")
+	viper.BindEnv("rabbitmq.exchange", "RABBITMQ_EXCHANGE")
+	viper.BindEnv("webhook.maxattempts", "WEBHOOK_MAX_ATTEMPTS")
+	viper.BindEnv("webhook.initialbackoff", "WEBHOOK_INITIAL_BACKOFF")
+	viper.BindEnv("webhook.maxbackoff", "WEBHOOK_MAX_BACKOFF")
+	viper.BindEnv("webhook.timeout", "WEBHOOK_TIMEOUT")
+	viper.BindEnv("webhook.failurethreshold", "WEBHOOK_FAILURE_THRESHOLD")
+	viper.BindEnv("webhook.pollinterval", "WEBHOOK_POLL_INTERVAL")
+	viper.BindEnv("webhook.batchsize", "WEBHOOK_BATCH_SIZE")
+	viper.BindEnv("rollup.flushinterval", "ROLLUP_FLUSH_INTERVAL")
+	viper.BindEnv("stream.heartbeatinterval", "STREAM_HEARTBEAT_INTERVAL")
+	viper.BindEnv("stream.buffersize", "STREAM_BUFFER_SIZE")
+	viper.BindEnv("stream.queuemaxlength", "STREAM_QUEUE_MAX_LENGTH")
+	viper.BindEnv("retention.enabled", "RETENTION_ENABLED")
+	viper.BindEnv("retention.dryrun", "RETENTION_DRY_RUN")
+	viper.BindEnv("retention.interval", "RETENTION_INTERVAL")
+	viper.BindEnv("retention.requestlogsdays", "RETENTION_REQUEST_LOGS_DAYS")
+	viper.BindEnv("retention.redirecthistorydays", "RETENTION_REDIRECT_HISTORY_DAYS")
+	viper.BindEnv("retention.maxdays", "RETENTION_MAX_DAYS")
+	viper.BindEnv("retention.batchsize", "RETENTION_BATCH_SIZE")
+	viper.BindEnv("retention.batchpause", "RETENTION_BATCH_PAUSE")
+	viper.BindEnv("retention.partitioned", "RETENTION_PARTITIONED")
+	viper.BindEnv("retention.partitionsahead", "RETENTION_PARTITIONS_AHEAD")
+	viper.BindEnv("retention.requirearchive", "RETENTION_REQUIRE_ARCHIVE")
+	viper.BindEnv("archive.enabled", "ARCHIVE_ENABLED")
+	viper.BindEnv("archive.interval", "ARCHIVE_INTERVAL")
+	viper.BindEnv("archive.lag", "ARCHIVE_LAG")
+	viper.BindEnv("archive.rowsperfile", "ARCHIVE_ROWS_PER_FILE")
+	viper.BindEnv("archive.store", "ARCHIVE_STORE")
+	viper.BindEnv("archive.dir", "ARCHIVE_DIR")
+	viper.BindEnv("archive.s3.endpoint", "ARCHIVE_S3_ENDPOINT")
+	viper.BindEnv("archive.s3.region", "ARCHIVE_S3_REGION")
+	viper.BindEnv("archive.s3.bucket", "ARCHIVE_S3_BUCKET")
+	viper.BindEnv("archive.s3.prefix", "ARCHIVE_S3_PREFIX")
+	viper.BindEnv("archive.s3.accesskey", "ARCHIVE_S3_ACCESS_KEY")
+	viper.BindEnv("archive.s3.secretkey", "ARCHIVE_S3_SECRET_KEY")
+	viper.BindEnv("archive.s3.pathstyle", "ARCHIVE_S3_PATH_STYLE")
+	viper.BindEnv("privacy.headermode", "PRIVACY_HEADER_MODE")
+	viper.BindEnv("privacy.headers", "PRIVACY_HEADERS")
+	viper.BindEnv("privacy.ipmode", "PRIVACY_IP_MODE")
+	viper.BindEnv("privacy.ipv4prefix", "PRIVACY_IPV4_PREFIX")
+	viper.BindEnv("privacy.ipv6prefix", "PRIVACY_IPV6_PREFIX")
+	viper.BindEnv("privacy.iphashkey", "PRIVACY_IP_HASH_KEY")
+	viper.BindEnv("privacy.settingscachettl", "PRIVACY_SETTINGS_CACHE_TTL")
+	viper.BindEnv("utm.settingscachettl", "UTM_SETTINGS_CACHE_TTL")
+	viper.BindEnv("useragent.rulesfile", "USER_AGENT_RULES_FILE")
+	viper.BindEnv("geoip.citydatabase", "GEOIP_CITY_DATABASE")
+	viper.BindEnv("geoip.asndatabase", "GEOIP_ASN_DATABASE")
+	viper.BindEnv("geoip.reloadinterval", "GEOIP_RELOAD_INTERVAL")
+	viper.BindEnv("bot.crawlerrangesfile", "BOT_CRAWLER_RANGES_FILE")
+	viper.BindEnv("bot.reloadinterval", "BOT_RELOAD_INTERVAL")
+	viper.BindEnv("bot.burstwindow", "BOT_BURST_WINDOW")
+	viper.BindEnv("bot.burstthreshold", "BOT_BURST_THRESHOLD")
+	viper.BindEnv("bot.flagmissinguseragent", "BOT_FLAG_MISSING_USER_AGENT")
+	viper.BindEnv("fraud.flagscore", "FRAUD_FLAG_SCORE")
+	viper.BindEnv("fraud.velocitywindow", "FRAUD_VELOCITY_WINDOW")
+	viper.BindEnv("fraud.velocitythreshold", "FRAUD_VELOCITY_THRESHOLD")
+	viper.BindEnv("fraud.datacenterasns", "FRAUD_DATACENTER_ASNS")
+	viper.BindEnv("fraud.requiredheaders", "FRAUD_REQUIRED_HEADERS")
+	viper.BindEnv("visitor.setcookie", "VISITOR_SET_COOKIE")
+	viper.BindEnv("visitor.cookiename", "VISITOR_COOKIE_NAME")
+	viper.BindEnv("visitor.cookiedomain", "VISITOR_COOKIE_DOMAIN")
+	viper.BindEnv("visitor.cookiemaxage", "VISITOR_COOKIE_MAX_AGE")
+	viper.BindEnv("visitor.cookiesecure", "VISITOR_COOKIE_SECURE")
+	viper.BindEnv("visitor.uniquewindow", "VISITOR_UNIQUE_WINDOW")
+	viper.BindEnv("lifecycle.pausedresponse", "LIFECYCLE_PAUSED_RESPONSE")
+	viper.BindEnv("lifecycle.archivedresponse", "LIFECYCLE_ARCHIVED_RESPONSE")
+	viper.BindEnv("lifecycle.holdingpage", "LIFECYCLE_HOLDING_PAGE")
+	viper.BindEnv("auth.accesstokenttl", "AUTH_ACCESS_TOKEN_TTL")
+	viper.BindEnv("auth.refreshtokenttl", "AUTH_REFRESH_TOKEN_TTL")
+	viper.BindEnv("auth.revocationcachettl", "AUTH_REVOCATION_CACHE_TTL")
+	viper.BindEnv("auth.pruneinterval", "AUTH_PRUNE_INTERVAL")
+	viper.BindEnv("jwt.algorithm", "JWT_ALGORITHM")
+	viper.BindEnv("jwt.secret", "JWT_SECRET")
+	viper.BindEnv("jwt.privatekeyfile", "JWT_PRIVATE_KEY_FILE")
+	viper.BindEnv("jwt.keyid", "JWT_KEY_ID")
+	viper.BindEnv("jwt.verificationkeys", "JWT_VERIFICATION_KEYS")
+	viper.BindEnv("jwt.previoussecrets", "JWT_PREVIOUS_SECRETS")
+	viper.BindEnv("jwt.issuer", "JWT_ISSUER")
+	viper.BindEnv("jwt.audience", "JWT_AUDIENCE")
+	viper.BindEnv("org.invitationttl", "ORG_INVITATION_TTL")
+	viper.BindEnv("account.verificationttl", "ACCOUNT_VERIFICATION_TTL")
+	viper.BindEnv("account.passwordresetttl", "ACCOUNT_PASSWORD_RESET_TTL")
+	viper.BindEnv("account.appurl", "ACCOUNT_APP_URL")
+	viper.BindEnv("account.unverifiedpolicy", "ACCOUNT_UNVERIFIED_POLICY")
+	viper.BindEnv("account.unverifiedmaxmappings", "ACCOUNT_UNVERIFIED_MAX_MAPPINGS")
+	viper.BindEnv("mail.driver", "MAIL_DRIVER")
+	viper.BindEnv("mail.from", "MAIL_FROM")
+	viper.BindEnv("mail.smtphost", "MAIL_SMTP_HOST")
+	viper.BindEnv("mail.smtpport", "MAIL_SMTP_PORT")
+	viper.BindEnv("mail.smtpusername", "MAIL_SMTP_USERNAME")
+	viper.BindEnv("mail.smtppassword", "MAIL_SMTP_PASSWORD")
+	viper.BindEnv("mail.smtptls", "MAIL_SMTP_TLS")
+	viper.BindEnv("mail.file", "MAIL_FILE")
+	viper.BindEnv("metrics.addr", "METRICS_ADDR")
 
 	// Read config file if it exists
 	if err := viper.ReadInConfig(); err != nil {
@@ -73,6 +555,12 @@ func LoadConfig() (*Config, error) {
 	if err := viper.Unmarshal(&config); err != nil {
 		return nil, fmt.Errorf("error unmarshaling config: %w", err)
 	}
+	if err := config.applyJWTExpirationHours(); err != nil {
+		return nil, err
+	}
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
 
 	return &config, nil
 } 
\ No newline at end of file
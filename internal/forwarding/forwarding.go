@@ -0,0 +1,101 @@
+package forwarding
+
+import (
+	"fmt"
+	"net/url"
+	"platform/internal/models"
+	"sort"
+)
+
+// ReservedParam is set by the gateway on every destination and never forwarded
+const ReservedParam = "click_id"
+
+// Check normalizes a forwarding policy, an empty policy meaning none, and
+// reports why it cannot be applied, if it cannot. Two parameters cannot be
+// renamed to the same name, so every forwarded name has a single source.
+func Check(f *models.QueryForwarding) error {
+	if f.Policy == "" {
+		f.Policy = models.ForwardNone
+	}
+	if f.Policy == models.ForwardNone {
+		if len(f.Params) > 0 || len(f.Renames) > 0 {
+			return fmt.Errorf("params and renames require the all or allowlist policy")
+		}
+		return nil
+	}
+	if f.Policy == models.ForwardAllowlist && len(f.Params) == 0 && len(f.Renames) == 0 {
+		return fmt.Errorf("the allowlist policy requires params or renames")
+	}
+
+	for _, param := range f.Params {
+		if param == ReservedParam {
+			return fmt.Errorf("%s cannot be forwarded", ReservedParam)
+		}
+	}
+	targets := make(map[string]string, len(f.Renames))
+	for _, from := range sortedKeys(f.Renames) {
+		to := f.Renames[from]
+		if from == ReservedParam || to == ReservedParam {
+			return fmt.Errorf("%s cannot be renamed", ReservedParam)
+		}
+		if other, ok := targets[to]; ok {
+			return fmt.Errorf("%s and %s are both renamed to %s", other, from, to)
+		}
+		targets[to] = from
+	}
+	return nil
+}
+
+// Apply copies the parameters of a click that the policy forwards into the
+// destination query and returns them under their forwarded names, or nil if
+// none are forwarded. Conflicts are resolved in this order:
+//   - click_id is never forwarded, the gateway sets it last
+//   - a renamed parameter replaces an incoming parameter of its new name
+//   - forwarded parameters replace those already on the destination
+func Apply(query, incoming url.Values, f models.QueryForwarding) url.Values {
+	if f.Policy != models.ForwardAll && f.Policy != models.ForwardAllowlist {
+		return nil
+	}
+
+	allowed := make(map[string]bool, len(f.Params))
+	for _, param := range f.Params {
+		allowed[param] = true
+	}
+
+	keys := sortedKeys(incoming)
+	forwarded := url.Values{}
+	// Parameters kept under their own name first, so renamed ones replace them
+	for _, key := range keys {
+		if _, renamed := f.Renames[key]; renamed || key == ReservedParam {
+			continue
+		}
+		if f.Policy == models.ForwardAllowlist && !allowed[key] {
+			continue
+		}
+		forwarded[key] = incoming[key]
+	}
+	for _, key := range keys {
+		to, renamed := f.Renames[key]
+		if !renamed || to == ReservedParam {
+			continue
+		}
+		forwarded[to] = incoming[key]
+	}
+
+	if len(forwarded) == 0 {
+		return nil
+	}
+	for key, values := range forwarded {
+		query[key] = values
+	}
+	return forwarded
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,93 @@
+package fraud
+
+import (
+	"fmt"
+	"platform/internal/bot"
+	"platform/internal/config"
+	"platform/internal/models"
+	"platform/internal/repository/mysql"
+	"platform/pkg/metrics"
+)
+
+// weights are the points each rule adds to a click's score
+var weights = map[string]int{
+	models.FraudRuleDuplicateClickID: 50,
+	models.FraudRuleIPVelocity:       30,
+	models.FraudRuleDatacenterASN:    30,
+	models.FraudRuleMissingHeaders:   20,
+}
+
+var (
+	fired   = metrics.Map("fraud_rules_fired")
+	flagged = metrics.Int("fraud_clicks_flagged")
+)
+
+// Scorer computes the fraud score of clicks in the database worker. Like the
+// bot classifier it sees the IP and headers as stored by the privacy policy.
+type Scorer struct {
+	cfg          config.FraudConfig
+	redirectRepo *mysql.RedirectRepository
+	velocity     *bot.WindowCounter
+	datacenter   map[uint32]bool
+}
+
+func NewScorer(cfg config.FraudConfig, redirectRepo *mysql.RedirectRepository) *Scorer {
+	datacenter := make(map[uint32]bool, len(cfg.DatacenterASNs))
+	for _, asn := range cfg.DatacenterASNs {
+		datacenter[asn] = true
+	}
+	return &Scorer{
+		cfg:          cfg,
+		redirectRepo: redirectRepo,
+		velocity:     bot.NewWindowCounter(cfg.VelocityWindow),
+		datacenter:   datacenter,
+	}
+}
+
+// Score returns a matched click's score and the rules that fired, in rule
+// order. The request must be saved first: its ID tells the click apart from
+// earlier clicks, so a redelivered click is not counted as its own duplicate.
+func (s *Scorer) Score(event *models.ClickEvent) (int, []string, error) {
+	request := &event.Request
+	var rules []string
+
+	if event.ClickID != "" {
+		seen, err := s.redirectRepo.ClickIDSeen(event.ClientID, event.ClickID, request.ID)
+		if err != nil {
+			return 0, nil, fmt.Errorf("failed to check for duplicate click id: %w", err)
+		}
+		if seen {
+			rules = append(rules, models.FraudRuleDuplicateClickID)
+		}
+	}
+
+	clicks := s.velocity.Add(request.IPAddress, event.Timestamp)
+	if s.cfg.VelocityThreshold > 0 && clicks > s.cfg.VelocityThreshold {
+		rules = append(rules, models.FraudRuleIPVelocity)
+	}
+
+	if request.Geo != nil && s.datacenter[request.Geo.ASN] {
+		rules = append(rules, models.FraudRuleDatacenterASN)
+	}
+
+	headers := models.DecodeHeaders(request.RequestHeaders)
+	for _, name := range s.cfg.RequiredHeaders {
+		if headers.Get(name) == "" {
+			rules = append(rules, models.FraudRuleMissingHeaders)
+			break
+		}
+	}
+
+	score := 0
+	for _, rule := range rules {
+		score += weights[rule]
+		fired.Add(rule, 1)
+	}
+	if score > models.MaxFraudScore {
+		score = models.MaxFraudScore
+	}
+	if score > 0 && score >= s.cfg.FlagScore {
+		flagged.Add(1)
+	}
+	return score, rules, nil
+}
@@ -0,0 +1,138 @@
+package geoip
+
+import (
+	"context"
+	"expvar"
+	"fmt"
+	"net"
+	"os"
+	"platform/pkg/logger"
+	"platform/pkg/metrics"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	databaseMissing = metrics.Map("geoip_database_missing")
+	reloads         = metrics.Map("geoip_reloads")
+	reloadFailures  = metrics.Map("geoip_reload_failures")
+)
+
+// Database is an MMDB file loaded into memory. It is reloaded in place when the
+// file changes on disk, so a new release can be copied over the old one while
+// lookups continue; a file that fails to load leaves the previous version active.
+type Database struct {
+	name    string
+	path    string
+	current atomic.Pointer[mmdb]
+	missing *expvar.Int
+
+	mu          sync.Mutex
+	modTime     time.Time
+	size        int64
+	statFailing bool
+}
+
+// OpenDatabase loads the MMDB file at path. A missing or unreadable file is not
+// an error: lookups return nothing until a valid file appears. An empty path
+// disables the database.
+func OpenDatabase(name, path string) *Database {
+	db := &Database{name: name, path: path, missing: new(expvar.Int)}
+	databaseMissing.Set(name, db.missing)
+	if path == "" {
+		db.missing.Set(1)
+		return db
+	}
+	db.Reload()
+	return db
+}
+
+// Available reports whether a database is loaded
+func (d *Database) Available() bool {
+	return d.current.Load() != nil
+}
+
+// Reload loads the file again if its size or modification time changed since
+// the last load, reporting whether a new version was activated
+func (d *Database) Reload() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	defer func() {
+		if d.current.Load() == nil {
+			d.missing.Set(1)
+		} else {
+			d.missing.Set(0)
+		}
+	}()
+
+	info, err := os.Stat(d.path)
+	if err != nil {
+		// Log once per outage rather than on every poll
+		if !d.statFailing {
+			logger.Error("GeoIP database unavailable", "database", d.name, "path", d.path, "error", err)
+		}
+		d.statFailing = true
+		return false
+	}
+	d.statFailing = false
+	if info.ModTime().Equal(d.modTime) && info.Size() == d.size {
+		return false
+	}
+
+	// Remember this version even if it is broken, so it is not parsed again on every poll
+	d.modTime = info.ModTime()
+	d.size = info.Size()
+
+	buf, err := os.ReadFile(d.path)
+	if err != nil {
+		reloadFailures.Add(d.name, 1)
+		logger.Error("Failed to read GeoIP database", "database", d.name, "path", d.path, "error", err)
+		return false
+	}
+	db, err := parseMMDB(buf)
+	if err != nil {
+		reloadFailures.Add(d.name, 1)
+		logger.Error("Failed to load GeoIP database", "database", d.name, "path", d.path, "error", err)
+		return false
+	}
+
+	d.current.Store(db)
+	reloads.Add(d.name, 1)
+	logger.Info("Loaded GeoIP database", "database", d.name, "type", db.databaseType, "built_at", time.Unix(int64(db.buildEpoch), 0).UTC())
+	return true
+}
+
+// Watch checks the file for changes once per interval until the context is cancelled
+func (d *Database) Watch(ctx context.Context, interval time.Duration) {
+	if d.path == "" || interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			d.Reload()
+		}
+	}
+}
+
+// lookup returns the record of an IP, or nil if none is available
+func (d *Database) lookup(ip net.IP) (map[string]interface{}, error) {
+	db := d.current.Load()
+	if db == nil {
+		return nil, nil
+	}
+
+	value, err := db.lookup(ip)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up %s in %s database: %w", ip, d.name, err)
+	}
+	record, _ := value.(map[string]interface{})
+	return record, nil
+}
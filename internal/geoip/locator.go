@@ -0,0 +1,95 @@
+package geoip
+
+import (
+	"context"
+	"net"
+	"platform/internal/config"
+	"platform/internal/models"
+	"platform/pkg/metrics"
+	"strings"
+)
+
+var (
+	lookupsSkipped = metrics.Map("geoip_lookups_skipped")
+	lookupErrors   = metrics.Map("geoip_lookup_errors")
+)
+
+// Locator resolves client IPs with a City (or Country) database and an ASN
+// database, both in MaxMind's MMDB format. Either may be missing.
+type Locator struct {
+	city *Database
+	asn  *Database
+	cfg  config.GeoIPConfig
+}
+
+func NewLocator(cfg config.GeoIPConfig) *Locator {
+	return &Locator{
+		city: OpenDatabase("city", cfg.CityDatabase),
+		asn:  OpenDatabase("asn", cfg.ASNDatabase),
+		cfg:  cfg,
+	}
+}
+
+// Watch reloads the databases when their files change until the context is cancelled
+func (l *Locator) Watch(ctx context.Context) {
+	go l.city.Watch(ctx, l.cfg.ReloadInterval)
+	go l.asn.Watch(ctx, l.cfg.ReloadInterval)
+}
+
+// Lookup returns what the databases know about an IP, or nil when they know
+// nothing or are unavailable. Values that are not IPs, such as hashed IPs, are skipped.
+func (l *Locator) Lookup(ip string) *models.Geo {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return nil
+	}
+
+	geo := &models.Geo{}
+	found := false
+
+	if !l.city.Available() {
+		lookupsSkipped.Add("city", 1)
+	} else if record, err := l.city.lookup(parsed); err != nil {
+		lookupErrors.Add("city", 1)
+	} else if record != nil {
+		geo.CountryCode = strings.ToUpper(stringAt(record, "country", "iso_code"))
+		if subdivisions, ok := record["subdivisions"].([]interface{}); ok && len(subdivisions) > 0 {
+			if first, ok := subdivisions[0].(map[string]interface{}); ok {
+				geo.Region = stringAt(first, "names", "en")
+			}
+		}
+		geo.City = stringAt(record, "city", "names", "en")
+		found = true
+	}
+
+	if !l.asn.Available() {
+		lookupsSkipped.Add("asn", 1)
+	} else if record, err := l.asn.lookup(parsed); err != nil {
+		lookupErrors.Add("asn", 1)
+	} else if record != nil {
+		if number, ok := record["autonomous_system_number"].(uint64); ok && number <= 0xFFFFFFFF {
+			geo.ASN = uint32(number)
+		}
+		geo.ASOrg, _ = record["autonomous_system_organization"].(string)
+		found = true
+	}
+
+	if !found {
+		return nil
+	}
+	return geo
+}
+
+// stringAt follows a path of map keys to a string, returning "" if any is missing
+func stringAt(record map[string]interface{}, path ...string) string {
+	var value interface{} = record
+	for _, key := range path {
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return ""
+		}
+		value = m[key]
+	}
+	s, _ := value.(string)
+	return s
+}
@@ -0,0 +1,344 @@
+package geoip
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+)
+
+// metadataMarker precedes the metadata map at the end of an MMDB file
+var metadataMarker = []byte("\xAB\xCD\xEFMaxMind.com")
+
+// dataSectionSeparator is the number of zero bytes between the search tree and the data section
+const dataSectionSeparator = 16
+
+// maxDecodeDepth bounds nesting and pointer chains in corrupt files
+const maxDecodeDepth = 32
+
+var errCorrupt = errors.New("corrupt MMDB data")
+
+// mmdb reads a MaxMind DB file held in memory. See
+// https://maxmind.github.io/MaxMind-DB/ for the format.
+type mmdb struct {
+	buf          []byte
+	nodeCount    uint
+	recordSize   uint
+	ipVersion    uint
+	databaseType string
+	buildEpoch   uint64
+	treeSize     uint
+	data         []byte
+	ipv4Start    uint
+}
+
+func parseMMDB(buf []byte) (*mmdb, error) {
+	start := bytes.LastIndex(buf, metadataMarker)
+	if start < 0 {
+		return nil, fmt.Errorf("not an MMDB file: metadata not found")
+	}
+
+	meta, _, err := (&decoder{buf: buf[start+len(metadataMarker):]}).decode(0, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode MMDB metadata: %w", err)
+	}
+	metadata, ok := meta.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("failed to decode MMDB metadata: %w", errCorrupt)
+	}
+
+	db := &mmdb{buf: buf}
+	db.nodeCount = uint(toUint(metadata["node_count"]))
+	db.recordSize = uint(toUint(metadata["record_size"]))
+	db.ipVersion = uint(toUint(metadata["ip_version"]))
+	db.databaseType, _ = metadata["database_type"].(string)
+	db.buildEpoch = toUint(metadata["build_epoch"])
+
+	switch db.recordSize {
+	case 24, 28, 32:
+	default:
+		return nil, fmt.Errorf("unsupported MMDB record size %d", db.recordSize)
+	}
+	if db.ipVersion != 4 && db.ipVersion != 6 {
+		return nil, fmt.Errorf("unsupported MMDB IP version %d", db.ipVersion)
+	}
+
+	db.treeSize = db.nodeCount * db.recordSize / 4
+	dataStart := db.treeSize + dataSectionSeparator
+	if dataStart > uint(start) {
+		return nil, fmt.Errorf("MMDB search tree exceeds the file: %w", errCorrupt)
+	}
+	db.data = buf[dataStart:start]
+
+	// IPv4 addresses live under ::/96 of an IPv6 tree
+	if db.ipVersion == 6 {
+		node := uint(0)
+		for i := 0; i < 96 && node < db.nodeCount; i++ {
+			node = db.record(node, 0)
+		}
+		db.ipv4Start = node
+	}
+
+	return db, nil
+}
+
+// record returns the left (bit 0) or right (bit 1) record of a search tree node
+func (db *mmdb) record(node uint, bit uint) uint {
+	offset := node * db.recordSize / 4
+	b := db.buf[offset : offset+db.recordSize/4]
+
+	switch db.recordSize {
+	case 24:
+		b = b[bit*3:]
+		return uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])
+	case 28:
+		if bit == 0 {
+			return uint(b[3]&0xF0)<<20 | uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])
+		}
+		return uint(b[3]&0x0F)<<24 | uint(b[4])<<16 | uint(b[5])<<8 | uint(b[6])
+	default:
+		b = b[bit*4:]
+		return uint(binary.BigEndian.Uint32(b))
+	}
+}
+
+// lookup returns the data record of an IP, or nil if the database has none
+func (db *mmdb) lookup(ip net.IP) (interface{}, error) {
+	node := uint(0)
+	bits := 128
+	if v4 := ip.To4(); v4 != nil {
+		ip = v4
+		bits = 32
+		if db.ipVersion == 6 {
+			node = db.ipv4Start
+		}
+	} else if db.ipVersion == 4 {
+		return nil, nil
+	}
+
+	for i := 0; i < bits && node < db.nodeCount; i++ {
+		bit := uint(ip[i>>3]>>(7-uint(i&7))) & 1
+		node = db.record(node, bit)
+	}
+
+	switch {
+	case node == db.nodeCount:
+		return nil, nil
+	case node < db.nodeCount:
+		return nil, fmt.Errorf("MMDB search tree is deeper than the address: %w", errCorrupt)
+	}
+
+	offset := node - db.nodeCount - dataSectionSeparator
+	if offset >= uint(len(db.data)) {
+		return nil, fmt.Errorf("MMDB data pointer out of range: %w", errCorrupt)
+	}
+	value, _, err := (&decoder{buf: db.data}).decode(offset, 0)
+	return value, err
+}
+
+// decoder decodes the MMDB data section format. Maps become
+// map[string]interface{}, arrays []interface{}, and integers uint64 or int64.
+type decoder struct {
+	buf []byte
+}
+
+const (
+	typeExtended = iota
+	typePointer
+	typeString
+	typeDouble
+	typeBytes
+	typeUint16
+	typeUint32
+	typeMap
+	typeInt32
+	typeUint64
+	typeUint128
+	typeArray
+	typeContainer
+	typeEndMarker
+	typeBool
+	typeFloat
+)
+
+// decode returns the value at offset and the offset after it
+func (d *decoder) decode(offset uint, depth int) (interface{}, uint, error) {
+	if depth > maxDecodeDepth {
+		return nil, 0, fmt.Errorf("MMDB data nested too deeply: %w", errCorrupt)
+	}
+
+	kind, size, offset, err := d.control(offset)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if kind == typePointer {
+		target, next, err := d.pointer(size, offset)
+		if err != nil {
+			return nil, 0, err
+		}
+		value, _, err := d.decode(target, depth+1)
+		return value, next, err
+	}
+
+	switch kind {
+	case typeMap:
+		m := make(map[string]interface{}, size)
+		for i := uint(0); i < size; i++ {
+			var key, value interface{}
+			if key, offset, err = d.decode(offset, depth+1); err != nil {
+				return nil, 0, err
+			}
+			if value, offset, err = d.decode(offset, depth+1); err != nil {
+				return nil, 0, err
+			}
+			name, ok := key.(string)
+			if !ok {
+				return nil, 0, fmt.Errorf("MMDB map key is not a string: %w", errCorrupt)
+			}
+			m[name] = value
+		}
+		return m, offset, nil
+	case typeArray:
+		a := make([]interface{}, 0, size)
+		for i := uint(0); i < size; i++ {
+			var value interface{}
+			if value, offset, err = d.decode(offset, depth+1); err != nil {
+				return nil, 0, err
+			}
+			a = append(a, value)
+		}
+		return a, offset, nil
+	case typeBool:
+		return size != 0, offset, nil
+	}
+
+	end := offset + size
+	if end > uint(len(d.buf)) {
+		return nil, 0, fmt.Errorf("MMDB value exceeds the data section: %w", errCorrupt)
+	}
+	b := d.buf[offset:end]
+
+	switch kind {
+	case typeString:
+		return string(b), end, nil
+	case typeBytes:
+		return append([]byte(nil), b...), end, nil
+	case typeDouble:
+		if size != 8 {
+			return nil, 0, fmt.Errorf("MMDB double of %d bytes: %w", size, errCorrupt)
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(b)), end, nil
+	case typeFloat:
+		if size != 4 {
+			return nil, 0, fmt.Errorf("MMDB float of %d bytes: %w", size, errCorrupt)
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(b))), end, nil
+	case typeUint16, typeUint32, typeUint64:
+		if size > 8 {
+			return nil, 0, fmt.Errorf("MMDB integer of %d bytes: %w", size, errCorrupt)
+		}
+		var v uint64
+		for _, c := range b {
+			v = v<<8 | uint64(c)
+		}
+		return v, end, nil
+	case typeInt32:
+		if size > 4 {
+			return nil, 0, fmt.Errorf("MMDB int32 of %d bytes: %w", size, errCorrupt)
+		}
+		var v uint32
+		for _, c := range b {
+			v = v<<8 | uint32(c)
+		}
+		return int64(int32(v)), end, nil
+	case typeUint128:
+		// Too wide for the fields read here; kept as raw bytes
+		return append([]byte(nil), b...), end, nil
+	default:
+		return nil, 0, fmt.Errorf("unsupported MMDB data type %d: %w", kind, errCorrupt)
+	}
+}
+
+// control reads a control byte and returns the type, the payload size and the
+// offset of the payload
+func (d *decoder) control(offset uint) (int, uint, uint, error) {
+	if offset >= uint(len(d.buf)) {
+		return 0, 0, 0, fmt.Errorf("MMDB offset out of range: %w", errCorrupt)
+	}
+	ctrl := d.buf[offset]
+	offset++
+
+	kind := int(ctrl >> 5)
+	if kind == typeExtended {
+		if offset >= uint(len(d.buf)) {
+			return 0, 0, 0, fmt.Errorf("MMDB offset out of range: %w", errCorrupt)
+		}
+		kind = 7 + int(d.buf[offset])
+		offset++
+	}
+
+	size := uint(ctrl & 0x1F)
+	if kind == typePointer || size < 29 {
+		return kind, size, offset, nil
+	}
+
+	extra := size - 28
+	if offset+extra > uint(len(d.buf)) {
+		return 0, 0, 0, fmt.Errorf("MMDB size exceeds the data section: %w", errCorrupt)
+	}
+	var n uint
+	for _, c := range d.buf[offset : offset+extra] {
+		n = n<<8 | uint(c)
+	}
+	switch extra {
+	case 1:
+		size = 29 + n
+	case 2:
+		size = 285 + n
+	default:
+		size = 65821 + n
+	}
+	return kind, size, offset + extra, nil
+}
+
+// pointer resolves a pointer whose control byte carried sizeBits, returning the
+// target offset and the offset after the pointer
+func (d *decoder) pointer(sizeBits uint, offset uint) (uint, uint, error) {
+	length := (sizeBits>>3)&0x3 + 1
+	if offset+length > uint(len(d.buf)) {
+		return 0, 0, fmt.Errorf("MMDB pointer exceeds the data section: %w", errCorrupt)
+	}
+
+	var n uint
+	for _, c := range d.buf[offset : offset+length] {
+		n = n<<8 | uint(c)
+	}
+
+	var target uint
+	switch length {
+	case 1:
+		target = (sizeBits&0x7)<<8 | n
+	case 2:
+		target = ((sizeBits&0x7)<<16 | n) + 2048
+	case 3:
+		target = ((sizeBits&0x7)<<24 | n) + 526336
+	default:
+		target = n
+	}
+	return target, offset + length, nil
+}
+
+func toUint(v interface{}) uint64 {
+	switch v := v.(type) {
+	case uint64:
+		return v
+	case int64:
+		if v > 0 {
+			return uint64(v)
+		}
+	}
+	return 0
+}
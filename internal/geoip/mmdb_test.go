@@ -0,0 +1,404 @@
+package geoip
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+	"net"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// control encodes the control byte, extended type and size bytes of a value
+func control(kind int, size int) []byte {
+	var sizeBits int
+	var extra []byte
+	switch {
+	case size < 29:
+		sizeBits = size
+	case size < 285:
+		sizeBits, extra = 29, []byte{byte(size - 29)}
+	case size < 65821:
+		sizeBits, extra = 30, []byte{byte((size - 285) >> 8), byte(size - 285)}
+	default:
+		n := size - 65821
+		sizeBits, extra = 31, []byte{byte(n >> 16), byte(n >> 8), byte(n)}
+	}
+
+	var b []byte
+	if kind <= typeMap {
+		b = []byte{byte(kind<<5 | sizeBits)}
+	} else {
+		b = []byte{byte(sizeBits), byte(kind - 7)}
+	}
+	return append(b, extra...)
+}
+
+// uintBytes returns v big endian without leading zero bytes
+func uintBytes(v uint64) []byte {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], v)
+	i := 0
+	for i < len(b) && b[i] == 0 {
+		i++
+	}
+	return b[i:]
+}
+
+// encode encodes v in the MMDB data section format
+func encode(v interface{}) []byte {
+	switch v := v.(type) {
+	case string:
+		return append(control(typeString, len(v)), v...)
+	case []byte:
+		return append(control(typeBytes, len(v)), v...)
+	case float64:
+		return binary.BigEndian.AppendUint64(control(typeDouble, 8), math.Float64bits(v))
+	case float32:
+		return binary.BigEndian.AppendUint32(control(typeFloat, 4), math.Float32bits(v))
+	case bool:
+		if v {
+			return control(typeBool, 1)
+		}
+		return control(typeBool, 0)
+	case uint16:
+		b := uintBytes(uint64(v))
+		return append(control(typeUint16, len(b)), b...)
+	case uint32:
+		b := uintBytes(uint64(v))
+		return append(control(typeUint32, len(b)), b...)
+	case uint64:
+		b := uintBytes(v)
+		return append(control(typeUint64, len(b)), b...)
+	case int32:
+		return binary.BigEndian.AppendUint32(control(typeInt32, 4), uint32(v))
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		b := control(typeMap, len(v))
+		for _, key := range keys {
+			b = append(b, encode(key)...)
+			b = append(b, encode(v[key])...)
+		}
+		return b
+	case []interface{}:
+		b := control(typeArray, len(v))
+		for _, value := range v {
+			b = append(b, encode(value)...)
+		}
+		return b
+	default:
+		panic("unsupported fixture value")
+	}
+}
+
+type network struct {
+	cidr   string
+	record map[string]interface{}
+}
+
+// buildMMDB returns a database holding the records of the given networks.
+// Networks must not overlap.
+func buildMMDB(ipVersion, recordSize uint, networks []network) []byte {
+	// Children are node indexes, -1 for no record, or -2-offset for a record
+	// at offset in the data section
+	nodes := [][2]int{{-1, -1}}
+	var data []byte
+
+	for _, n := range networks {
+		_, ipNet, err := net.ParseCIDR(n.cidr)
+		if err != nil {
+			panic(err)
+		}
+		ones, _ := ipNet.Mask.Size()
+		ip := ipNet.IP.To4()
+		if ipVersion == 6 {
+			if ip != nil {
+				// IPv4 networks live under ::/96
+				mapped := make(net.IP, net.IPv6len)
+				copy(mapped[12:], ip)
+				ip, ones = mapped, ones+96
+			} else {
+				ip = ipNet.IP.To16()
+			}
+		}
+
+		node := 0
+		for i := 0; i < ones; i++ {
+			bit := int(ip[i>>3]>>(7-uint(i&7))) & 1
+			if i == ones-1 {
+				nodes[node][bit] = -2 - len(data)
+				break
+			}
+			if nodes[node][bit] < 0 {
+				nodes = append(nodes, [2]int{-1, -1})
+				nodes[node][bit] = len(nodes) - 1
+			}
+			node = nodes[node][bit]
+		}
+		data = append(data, encode(n.record)...)
+	}
+
+	nodeCount := uint(len(nodes))
+	record := func(child int) uint {
+		switch {
+		case child == -1:
+			return nodeCount
+		case child < 0:
+			return nodeCount + dataSectionSeparator + uint(-2-child)
+		default:
+			return uint(child)
+		}
+	}
+
+	var buf []byte
+	for _, n := range nodes {
+		left, right := record(n[0]), record(n[1])
+		switch recordSize {
+		case 24:
+			buf = append(buf, byte(left>>16), byte(left>>8), byte(left))
+			buf = append(buf, byte(right>>16), byte(right>>8), byte(right))
+		case 28:
+			buf = append(buf, byte(left>>16), byte(left>>8), byte(left))
+			buf = append(buf, byte(left>>24)<<4|byte(right>>24)&0x0F)
+			buf = append(buf, byte(right>>16), byte(right>>8), byte(right))
+		case 32:
+			buf = binary.BigEndian.AppendUint32(buf, uint32(left))
+			buf = binary.BigEndian.AppendUint32(buf, uint32(right))
+		}
+	}
+	buf = append(buf, make([]byte, dataSectionSeparator)...)
+	buf = append(buf, data...)
+	buf = append(buf, metadataMarker...)
+	return append(buf, encode(map[string]interface{}{
+		"node_count":    uint32(nodeCount),
+		"record_size":   uint16(recordSize),
+		"ip_version":    uint16(ipVersion),
+		"database_type": "Test-City",
+		"build_epoch":   uint64(1700000000),
+	})...)
+}
+
+var (
+	usRecord = map[string]interface{}{
+		"country": map[string]interface{}{
+			"iso_code": "US",
+			"names":    map[string]interface{}{"en": "United States"},
+		},
+		"location": map[string]interface{}{
+			"latitude":  37.751,
+			"longitude": -97.822,
+		},
+	}
+	deRecord = map[string]interface{}{
+		"country": map[string]interface{}{"iso_code": "DE"},
+		"subdivisions": []interface{}{
+			map[string]interface{}{"iso_code": "BE"},
+		},
+	}
+	jpRecord = map[string]interface{}{
+		"country": map[string]interface{}{"iso_code": "JP"},
+	}
+)
+
+func TestLookup(t *testing.T) {
+	networks := []network{
+		{cidr: "1.2.3.0/24", record: usRecord},
+		{cidr: "8.0.0.0/8", record: deRecord},
+	}
+	networksV6 := append(networks, network{cidr: "2001:db8::/32", record: jpRecord})
+
+	databases := []struct {
+		name       string
+		ipVersion  uint
+		recordSize uint
+		networks   []network
+	}{
+		{name: "ipv4 24-bit", ipVersion: 4, recordSize: 24, networks: networks},
+		{name: "ipv4 28-bit", ipVersion: 4, recordSize: 28, networks: networks},
+		{name: "ipv4 32-bit", ipVersion: 4, recordSize: 32, networks: networks},
+		{name: "ipv6 24-bit", ipVersion: 6, recordSize: 24, networks: networksV6},
+		{name: "ipv6 28-bit", ipVersion: 6, recordSize: 28, networks: networksV6},
+		{name: "ipv6 32-bit", ipVersion: 6, recordSize: 32, networks: networksV6},
+	}
+
+	lookups := []struct {
+		ip   string
+		want map[string]interface{}
+		// ipv6Only marks addresses only an IPv6 database has records for
+		ipv6Only bool
+	}{
+		{ip: "1.2.3.4", want: usRecord},
+		{ip: "1.2.3.255", want: usRecord},
+		{ip: "8.8.8.8", want: deRecord},
+		{ip: "1.2.4.1", want: nil},
+		{ip: "9.0.0.1", want: nil},
+		{ip: "::ffff:8.8.4.4", want: deRecord},
+		{ip: "2001:db8::1", want: jpRecord, ipv6Only: true},
+		{ip: "2001:db9::1", want: nil},
+	}
+
+	for _, tt := range databases {
+		t.Run(tt.name, func(t *testing.T) {
+			db, err := parseMMDB(buildMMDB(tt.ipVersion, tt.recordSize, tt.networks))
+			if err != nil {
+				t.Fatalf("parseMMDB() error = %v", err)
+			}
+			if db.databaseType != "Test-City" || db.buildEpoch != 1700000000 {
+				t.Errorf("metadata = %q built %d, want %q built %d",
+					db.databaseType, db.buildEpoch, "Test-City", 1700000000)
+			}
+
+			for _, l := range lookups {
+				want := l.want
+				if l.ipv6Only && tt.ipVersion == 4 {
+					want = nil
+				}
+
+				value, err := db.lookup(net.ParseIP(l.ip))
+				if err != nil {
+					t.Errorf("lookup(%s) error = %v", l.ip, err)
+					continue
+				}
+				record, _ := value.(map[string]interface{})
+				if !reflect.DeepEqual(record, want) {
+					t.Errorf("lookup(%s) = %v, want %v", l.ip, value, want)
+				}
+			}
+		})
+	}
+}
+
+func TestDecode(t *testing.T) {
+	long := strings.Repeat("a", 300)
+	longer := strings.Repeat("b", 70000)
+
+	tests := []struct {
+		name   string
+		data   []byte
+		offset uint
+		want   interface{}
+	}{
+		{name: "string", data: encode("Berlin"), want: "Berlin"},
+		{name: "empty string", data: encode(""), want: ""},
+		{name: "string with one size byte", data: encode(strings.Repeat("a", 100)), want: strings.Repeat("a", 100)},
+		{name: "string with two size bytes", data: encode(long), want: long},
+		{name: "string with three size bytes", data: encode(longer), want: longer},
+		{name: "double", data: encode(-97.822), want: -97.822},
+		{name: "float", data: encode(float32(1.5)), want: 1.5},
+		{name: "bytes", data: encode([]byte{1, 2, 3}), want: []byte{1, 2, 3}},
+		{name: "uint16", data: encode(uint16(443)), want: uint64(443)},
+		{name: "uint32 zero", data: encode(uint32(0)), want: uint64(0)},
+		{name: "uint32", data: encode(uint32(4294967295)), want: uint64(4294967295)},
+		{name: "uint64", data: encode(uint64(1) << 40), want: uint64(1) << 40},
+		{name: "int32", data: encode(int32(-5)), want: int64(-5)},
+		{name: "uint128", data: append(control(typeUint128, 2), 1, 2), want: []byte{1, 2}},
+		{name: "true", data: encode(true), want: true},
+		{name: "false", data: encode(false), want: false},
+		{name: "array", data: encode([]interface{}{"a", uint32(1)}), want: []interface{}{"a", uint64(1)}},
+		{name: "empty map", data: encode(map[string]interface{}{}), want: map[string]interface{}{}},
+		{name: "nested map", data: encode(usRecord), want: usRecord},
+		{
+			// A map whose value points back to a string earlier in the data
+			name:   "pointer",
+			data:   append(encode("US"), append(control(typeMap, 1), append(encode("iso_code"), 0x20, 0x00)...)...),
+			offset: uint(len(encode("US"))),
+			want:   map[string]interface{}{"iso_code": "US"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value, next, err := (&decoder{buf: tt.data}).decode(tt.offset, 0)
+			if err != nil {
+				t.Fatalf("decode() error = %v", err)
+			}
+			if !reflect.DeepEqual(value, tt.want) {
+				t.Errorf("decode() = %#v, want %#v", value, tt.want)
+			}
+			if next != uint(len(tt.data)) {
+				t.Errorf("decode() next offset = %d, want %d", next, len(tt.data))
+			}
+		})
+	}
+}
+
+func TestDecodeRejectsCorruptData(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{name: "empty", data: nil},
+		{name: "truncated string", data: encode("Berlin")[:4]},
+		{name: "truncated size", data: control(typeString, 300)[:1]},
+		{name: "truncated extended type", data: []byte{0x01}},
+		{name: "truncated map", data: control(typeMap, 2)},
+		{name: "truncated pointer", data: []byte{0x28, 0x00}},
+		{name: "pointer loop", data: []byte{0x20, 0x00}},
+		{name: "map key not a string", data: append(control(typeMap, 1), append(encode(uint32(1)), encode("x")...)...)},
+		{name: "short double", data: append(control(typeDouble, 4), 0, 0, 0, 0)},
+		{name: "short float", data: append(control(typeFloat, 2), 0, 0)},
+		{name: "wide uint64", data: append(control(typeUint64, 9), make([]byte, 9)...)},
+		{name: "wide int32", data: append(control(typeInt32, 5), make([]byte, 5)...)},
+		{name: "container", data: control(typeContainer, 0)},
+		{name: "end marker", data: control(typeEndMarker, 0)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := (&decoder{buf: tt.data}).decode(0, 0)
+			if !errors.Is(err, errCorrupt) {
+				t.Errorf("decode(%v) error = %v, want %v", tt.data, err, errCorrupt)
+			}
+		})
+	}
+}
+
+func TestParseMMDBRejectsInvalidFiles(t *testing.T) {
+	valid := buildMMDB(4, 24, []network{{cidr: "1.2.3.0/24", record: usRecord}})
+	withMetadata := func(metadata map[string]interface{}) []byte {
+		return append(append([]byte(nil), metadataMarker...), encode(metadata)...)
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{name: "empty", data: nil},
+		{name: "no metadata", data: valid[:bytes.LastIndex(valid, metadataMarker)]},
+		{name: "truncated metadata", data: valid[:bytes.LastIndex(valid, metadataMarker)+len(metadataMarker)+4]},
+		{name: "metadata not a map", data: append(append([]byte(nil), metadataMarker...), encode("Test-City")...)},
+		{
+			name: "record size",
+			data: withMetadata(map[string]interface{}{
+				"node_count": uint32(0), "record_size": uint16(16), "ip_version": uint16(4),
+			}),
+		},
+		{
+			name: "ip version",
+			data: withMetadata(map[string]interface{}{
+				"node_count": uint32(0), "record_size": uint16(24), "ip_version": uint16(5),
+			}),
+		},
+		{
+			name: "tree exceeds file",
+			data: withMetadata(map[string]interface{}{
+				"node_count": uint32(1000), "record_size": uint16(24), "ip_version": uint16(4),
+			}),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := parseMMDB(tt.data); err == nil {
+				t.Errorf("parseMMDB() succeeded, want an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+// Package hll implements HyperLogLog sketches, which estimate the number of
+// distinct values added to them in a fixed amount of memory and can be merged,
+// so the distinct count of a union is the estimate of the merged sketches.
+package hll
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/bits"
+)
+
+// Precision is the number of hash bits that pick a register. 2^12 registers
+// give a standard error of about 1.6%.
+const Precision = 12
+
+const registerCount = 1 << Precision
+
+// Encodings of a marshalled sketch. Sparse sketches store only the non-zero
+// registers as (index, value) pairs, which keeps the many small sketches of
+// quiet links and hours short.
+const (
+	encodingDense  = 1
+	encodingSparse = 2
+)
+
+// sparseEntrySize is the size of a sparse (uint16 index, uint8 value) pair
+const sparseEntrySize = 3
+
+// Sketch is a HyperLogLog sketch. The zero value is not usable; use New.
+type Sketch struct {
+	registers []uint8
+}
+
+func New() *Sketch {
+	return &Sketch{registers: make([]uint8, registerCount)}
+}
+
+// Add adds a value to the sketch
+func (s *Sketch) Add(value string) {
+	sum := sha256.Sum256([]byte(value))
+	s.addHash(binary.BigEndian.Uint64(sum[:8]))
+}
+
+func (s *Sketch) addHash(hash uint64) {
+	index := hash >> (64 - Precision)
+	// The rank is the position of the first set bit of the remaining bits
+	rank := uint8(bits.LeadingZeros64(hash<<Precision|1<<(Precision-1))) + 1
+	if rank > s.registers[index] {
+		s.registers[index] = rank
+	}
+}
+
+// Merge folds another sketch into this one
+func (s *Sketch) Merge(other *Sketch) {
+	for i, value := range other.registers {
+		if value > s.registers[i] {
+			s.registers[i] = value
+		}
+	}
+}
+
+// Estimate returns the estimated number of distinct values added
+func (s *Sketch) Estimate() uint64 {
+	m := float64(registerCount)
+	sum := 0.0
+	zeros := 0
+	for _, value := range s.registers {
+		sum += 1 / float64(uint64(1)<<value)
+		if value == 0 {
+			zeros++
+		}
+	}
+
+	alpha := 0.7213 / (1 + 1.079/m)
+	estimate := alpha * m * m / sum
+	// Small cardinalities are estimated more accurately by linear counting
+	if estimate <= 2.5*m && zeros > 0 {
+		estimate = m * math.Log(m/float64(zeros))
+	}
+	return uint64(estimate + 0.5)
+}
+
+// MarshalBinary encodes the sketch, sparsely when that is shorter
+func (s *Sketch) MarshalBinary() ([]byte, error) {
+	nonZero := 0
+	for _, value := range s.registers {
+		if value != 0 {
+			nonZero++
+		}
+	}
+
+	if 4+nonZero*sparseEntrySize >= 2+registerCount {
+		data := make([]byte, 2, 2+registerCount)
+		data[0], data[1] = encodingDense, Precision
+		return append(data, s.registers...), nil
+	}
+
+	data := make([]byte, 4, 4+nonZero*sparseEntrySize)
+	data[0], data[1] = encodingSparse, Precision
+	binary.BigEndian.PutUint16(data[2:], uint16(nonZero))
+	for i, value := range s.registers {
+		if value != 0 {
+			data = binary.BigEndian.AppendUint16(data, uint16(i))
+			data = append(data, value)
+		}
+	}
+	return data, nil
+}
+
+// UnmarshalBinary decodes a sketch encoded by MarshalBinary
+func (s *Sketch) UnmarshalBinary(data []byte) error {
+	if len(data) < 2 {
+		return fmt.Errorf("hll: sketch too short")
+	}
+	if data[1] != Precision {
+		return fmt.Errorf("hll: unsupported precision %d", data[1])
+	}
+	registers := make([]uint8, registerCount)
+
+	switch data[0] {
+	case encodingDense:
+		if len(data) != 2+registerCount {
+			return fmt.Errorf("hll: dense sketch has %d bytes", len(data))
+		}
+		copy(registers, data[2:])
+	case encodingSparse:
+		if len(data) < 4 {
+			return fmt.Errorf("hll: sketch too short")
+		}
+		count := int(binary.BigEndian.Uint16(data[2:]))
+		if len(data) != 4+count*sparseEntrySize {
+			return fmt.Errorf("hll: sparse sketch has %d bytes for %d registers", len(data), count)
+		}
+		for entry := data[4:]; len(entry) > 0; entry = entry[sparseEntrySize:] {
+			index := binary.BigEndian.Uint16(entry)
+			if int(index) >= registerCount {
+				return fmt.Errorf("hll: register %d out of range", index)
+			}
+			registers[index] = entry[2]
+		}
+	default:
+		return fmt.Errorf("hll: unknown encoding %d", data[0])
+	}
+
+	s.registers = registers
+	return nil
+}
@@ -0,0 +1,172 @@
+package hll
+
+import (
+	"bytes"
+	"math"
+	"strconv"
+	"testing"
+)
+
+// sketchOf returns a sketch of the n distinct values prefix<from> onwards
+func sketchOf(prefix string, from, n int) *Sketch {
+	s := New()
+	for i := from; i < from+n; i++ {
+		s.Add(prefix + strconv.Itoa(i))
+	}
+	return s
+}
+
+func TestEstimateErrorBounds(t *testing.T) {
+	tests := []struct {
+		distinct int
+		// maxError is the largest relative error accepted: linear counting is
+		// nearly exact for small sketches, and larger ones stay within three
+		// standard errors
+		maxError float64
+	}{
+		{distinct: 1, maxError: 0},
+		{distinct: 10, maxError: 0},
+		{distinct: 100, maxError: 0.02},
+		{distinct: 1000, maxError: 0.03},
+		{distinct: 10000, maxError: 0.05},
+		{distinct: 100000, maxError: 0.05},
+		{distinct: 500000, maxError: 0.05},
+	}
+
+	for _, tt := range tests {
+		t.Run(strconv.Itoa(tt.distinct), func(t *testing.T) {
+			s := sketchOf("visitor-", 0, tt.distinct)
+			estimate := s.Estimate()
+
+			relative := math.Abs(float64(estimate)-float64(tt.distinct)) / float64(tt.distinct)
+			if relative > tt.maxError {
+				t.Errorf("Estimate() = %d for %d distinct values, error %.2f%% exceeds %.2f%%",
+					estimate, tt.distinct, relative*100, tt.maxError*100)
+			}
+		})
+	}
+}
+
+func TestEstimateEmpty(t *testing.T) {
+	if estimate := New().Estimate(); estimate != 0 {
+		t.Errorf("Estimate() of an empty sketch = %d, want 0", estimate)
+	}
+}
+
+func TestEstimateIgnoresDuplicates(t *testing.T) {
+	once := sketchOf("visitor-", 0, 1000)
+	repeated := New()
+	for round := 0; round < 5; round++ {
+		for i := 0; i < 1000; i++ {
+			repeated.Add("visitor-" + strconv.Itoa(i))
+		}
+	}
+
+	if once.Estimate() != repeated.Estimate() {
+		t.Errorf("Estimate() with duplicates = %d, want %d", repeated.Estimate(), once.Estimate())
+	}
+}
+
+func TestMerge(t *testing.T) {
+	tests := []struct {
+		name         string
+		aFrom, aSize int
+		bFrom, bSize int
+		union        int
+	}{
+		{name: "disjoint", aFrom: 0, aSize: 5000, bFrom: 5000, bSize: 5000, union: 10000},
+		{name: "overlapping", aFrom: 0, aSize: 6000, bFrom: 4000, bSize: 6000, union: 10000},
+		{name: "subset", aFrom: 0, aSize: 10000, bFrom: 2000, bSize: 3000, union: 10000},
+		{name: "empty", aFrom: 0, aSize: 10000, bFrom: 0, bSize: 0, union: 10000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merged := sketchOf("visitor-", tt.aFrom, tt.aSize)
+			merged.Merge(sketchOf("visitor-", tt.bFrom, tt.bSize))
+
+			// Merging is lossless: the result is the sketch of the union
+			union := sketchOf("visitor-", 0, tt.union)
+			if !bytes.Equal(merged.registers, union.registers) {
+				t.Fatalf("merged registers differ from the registers of the union")
+			}
+
+			estimate := merged.Estimate()
+			relative := math.Abs(float64(estimate)-float64(tt.union)) / float64(tt.union)
+			if relative > 0.05 {
+				t.Errorf("Estimate() after Merge = %d, want %d within 5%%", estimate, tt.union)
+			}
+		})
+	}
+}
+
+func TestMergeIsCommutative(t *testing.T) {
+	a := sketchOf("a-", 0, 3000)
+	b := sketchOf("b-", 0, 7000)
+
+	ab := New()
+	ab.Merge(a)
+	ab.Merge(b)
+	ba := New()
+	ba.Merge(b)
+	ba.Merge(a)
+
+	if !bytes.Equal(ab.registers, ba.registers) {
+		t.Errorf("Merge order changed the registers")
+	}
+}
+
+func TestMarshalRoundTrip(t *testing.T) {
+	tests := []struct {
+		distinct int
+		encoding byte
+	}{
+		{distinct: 0, encoding: encodingSparse},
+		{distinct: 50, encoding: encodingSparse},
+		{distinct: 100000, encoding: encodingDense},
+	}
+
+	for _, tt := range tests {
+		t.Run(strconv.Itoa(tt.distinct), func(t *testing.T) {
+			s := sketchOf("visitor-", 0, tt.distinct)
+			data, err := s.MarshalBinary()
+			if err != nil {
+				t.Fatalf("MarshalBinary() error = %v", err)
+			}
+			if data[0] != tt.encoding {
+				t.Errorf("encoding = %d, want %d", data[0], tt.encoding)
+			}
+
+			decoded := New()
+			if err := decoded.UnmarshalBinary(data); err != nil {
+				t.Fatalf("UnmarshalBinary() error = %v", err)
+			}
+			if !bytes.Equal(decoded.registers, s.registers) {
+				t.Errorf("decoded registers differ from the original")
+			}
+		})
+	}
+}
+
+func TestUnmarshalBinaryRejectsInvalidData(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{name: "empty", data: nil},
+		{name: "other precision", data: []byte{encodingDense, Precision + 1}},
+		{name: "unknown encoding", data: []byte{9, Precision}},
+		{name: "short dense", data: []byte{encodingDense, Precision, 1, 2, 3}},
+		{name: "short sparse header", data: []byte{encodingSparse, Precision, 0}},
+		{name: "sparse count mismatch", data: []byte{encodingSparse, Precision, 0, 2, 0, 1, 5}},
+		{name: "sparse index out of range", data: []byte{encodingSparse, Precision, 0, 1, 0x10, 0x00, 5}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := New().UnmarshalBinary(tt.data); err == nil {
+				t.Errorf("UnmarshalBinary(%v) succeeded, want an error", tt.data)
+			}
+		})
+	}
+}
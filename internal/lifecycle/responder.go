@@ -0,0 +1,69 @@
+package lifecycle
+
+import (
+	_ "embed"
+	"fmt"
+	"os"
+	"platform/internal/config"
+	"platform/internal/models"
+)
+
+// defaultHoldingPage is served unless a holding page file is configured
+//
+//go:embed holding.html
+var defaultHoldingPage []byte
+
+// Responder decides what the gateway serves for mappings that are not active
+type Responder struct {
+	paused      string
+	archived    string
+	holdingPage []byte
+}
+
+// NewResponder validates the platform defaults and loads the holding page
+func NewResponder(cfg config.LifecycleConfig) (*Responder, error) {
+	for _, response := range []string{cfg.PausedResponse, cfg.ArchivedResponse} {
+		if !validResponse(response) {
+			return nil, fmt.Errorf("invalid inactive response %q", response)
+		}
+	}
+
+	page := defaultHoldingPage
+	if cfg.HoldingPage != "" {
+		var err error
+		if page, err = os.ReadFile(cfg.HoldingPage); err != nil {
+			return nil, fmt.Errorf("failed to read holding page: %w", err)
+		}
+	}
+
+	return &Responder{
+		paused:      cfg.PausedResponse,
+		archived:    cfg.ArchivedResponse,
+		holdingPage: page,
+	}, nil
+}
+
+// ResponseFor returns the inactive response of a mapping: its own, else the
+// platform default for its status. Drafts are served like paused mappings.
+func (r *Responder) ResponseFor(mapping *models.RedirectMapping) string {
+	if mapping.InactiveResponse != "" {
+		return mapping.InactiveResponse
+	}
+	if mapping.Status == models.MappingStatusArchived {
+		return r.archived
+	}
+	return r.paused
+}
+
+// HoldingPage returns the HTML of the holding page
+func (r *Responder) HoldingPage() []byte {
+	return r.holdingPage
+}
+
+func validResponse(response string) bool {
+	switch response {
+	case models.InactiveResponseFallback, models.InactiveResponseGone, models.InactiveResponseHolding:
+		return true
+	}
+	return false
+}
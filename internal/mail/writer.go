@@ -0,0 +1,53 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"io"
+	netmail "net/mail"
+	"os"
+	"sync"
+	"time"
+)
+
+// WriterMailer writes messages to a writer instead of sending them, for
+// development and tests
+type WriterMailer struct {
+	mu   sync.Mutex
+	w    io.Writer
+	from *netmail.Address
+}
+
+func NewWriterMailer(w io.Writer, from *netmail.Address) *WriterMailer {
+	return &WriterMailer{
+		w:    w,
+		from: from,
+	}
+}
+
+// NewFileMailer appends messages to a file
+func NewFileMailer(path string, from *netmail.Address) (*WriterMailer, error) {
+	if path == "" {
+		return nil, fmt.Errorf("mail file is required")
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open mail file: %w", err)
+	}
+	return NewWriterMailer(file, from), nil
+}
+
+// Send writes the message followed by a blank line
+func (m *WriterMailer) Send(ctx context.Context, msg Message) error {
+	data, err := format(m.from, msg, time.Now())
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, err := m.w.Write(append(data, "\r\n"...)); err != nil {
+		return fmt.Errorf("failed to write message: %w", err)
+	}
+	return nil
+}
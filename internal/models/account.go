@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+// Purposes of account tokens
+const (
+	AccountTokenVerifyEmail   = "verify_email"
+	AccountTokenResetPassword = "reset_password"
+)
+
+// Policies for accounts that have not verified their email address
+const (
+	UnverifiedPolicyAllow = "allow"
+	UnverifiedPolicyLimit = "limit"
+	UnverifiedPolicyBlock = "block"
+)
+
+// AccountToken is a single-use token emailed to a client to verify its email
+// address or reset its password. Only its hash is stored.
+type AccountToken struct {
+	ID        int64
+	ClientID  int64
+	Purpose   string
+	Email     string
+	TokenHash string
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+type EmailVerification struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type PasswordResetRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type PasswordReset struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required,min=6"`
+}
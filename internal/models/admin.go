@@ -0,0 +1,78 @@
+package models
+
+import "time"
+
+// Admin actions recorded in the audit log
+const (
+	AdminActionSuspend       = "client.suspend"
+	AdminActionUnsuspend     = "client.unsuspend"
+	AdminActionPasswordReset = "client.password_reset"
+	AdminActionTakedown      = "mapping.takedown"
+)
+
+// Targets of admin actions
+const (
+	AdminTargetClient  = "client"
+	AdminTargetMapping = "mapping"
+)
+
+// AdminAction is an entry of the admin audit log
+type AdminAction struct {
+	ID         int64     `json:"id"`
+	AdminID    int64     `json:"admin_id"`
+	Action     string    `json:"action"`
+	TargetType string    `json:"target_type"`
+	TargetID   int64     `json:"target_id"`
+	Reason     string    `json:"reason,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// AdminActionFilter selects audit log entries. Zero fields match everything.
+type AdminActionFilter struct {
+	AdminID    int64
+	TargetType string
+	TargetID   int64
+	Limit      int
+}
+
+// ClientSearch selects clients whose username or email contains Query.
+// Status is "active", "suspended" or empty for both.
+type ClientSearch struct {
+	Query  string
+	Status string
+	Limit  int
+}
+
+// AdminReason is the body of admin actions that need a reason
+type AdminReason struct {
+	Reason string `json:"reason" binding:"required,max=255"`
+}
+
+// ClientOverview is what an admin sees of a client: the account, its mappings
+// by status, its clicks over the last Days days and what else it owns
+type ClientOverview struct {
+	Client
+	Mappings      map[string]int64 `json:"mappings"`
+	Clicks        int64            `json:"clicks"`
+	Days          int              `json:"days"`
+	APIKeys       int64            `json:"api_keys"`
+	Organizations int64            `json:"organizations"`
+}
+
+// PlatformUsage summarizes the platform over the last Days days
+type PlatformUsage struct {
+	Clients          int64            `json:"clients"`
+	SuspendedClients int64            `json:"suspended_clients"`
+	Organizations    int64            `json:"organizations"`
+	Mappings         map[string]int64 `json:"mappings"`
+	Clicks           int64            `json:"clicks"`
+	Days             int              `json:"days"`
+	TopClients       []ClientClicks   `json:"top_clients"`
+}
+
+// ClientClicks counts the clicks on the mappings a client created
+type ClientClicks struct {
+	ClientID int64  `json:"client_id"`
+	Username string `json:"username"`
+	Clicks   int64  `json:"clicks"`
+}
@@ -0,0 +1,52 @@
+package models
+
+import "time"
+
+// API key scopes. Requests made with a login session have every scope.
+const (
+	ScopeRedirectsRead  = "redirects:read"
+	ScopeRedirectsWrite = "redirects:write"
+	ScopeStatsRead      = "stats:read"
+)
+
+// APIKey lets a client's scripts and integrations call the API without logging
+// in. Only the SHA-256 hash of the key is stored; Prefix is kept so the client
+// can tell its keys apart.
+type APIKey struct {
+	ID         int64      `json:"id"`
+	ClientID   int64      `json:"client_id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	KeyHash    string     `json:"-"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// HasScope reports whether the key grants a scope
+func (k *APIKey) HasScope(scope string) bool {
+	for _, s := range k.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Expired reports whether the key has expired at now
+func (k *APIKey) Expired(now time.Time) bool {
+	return k.ExpiresAt != nil && !now.Before(*k.ExpiresAt)
+}
+
+type APIKeyCreate struct {
+	Name      string     `json:"name" binding:"required,max=100"`
+	Scopes    []string   `json:"scopes" binding:"required,min=1,dive,oneof=redirects:read redirects:write stats:read"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// APIKeyCreated is returned once when a key is created, with the key itself
+type APIKeyCreated struct {
+	APIKey
+	Key string `json:"key"`
+}
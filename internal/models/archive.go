@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+// ArchiveFormat is the only archive file format: gzip-compressed NDJSON with one
+// JSON object per row, keyed by column name
+const ArchiveFormat = "ndjson+gzip"
+
+// ArchiveManifest describes one archived range of a table. It is written after
+// all of its files, so an archive without a manifest is incomplete.
+type ArchiveManifest struct {
+	Table     string        `json:"table"`
+	From      time.Time     `json:"from"`
+	To        time.Time     `json:"to"`
+	Format    string        `json:"format"`
+	Columns   []string      `json:"columns"`
+	Rows      int64         `json:"rows"`
+	Files     []ArchiveFile `json:"files"`
+	CreatedAt time.Time     `json:"created_at"`
+}
+
+type ArchiveFile struct {
+	Key    string `json:"key"`
+	Rows   int64  `json:"rows"`
+	Bytes  int64  `json:"bytes"`
+	SHA256 string `json:"sha256"`
+}
+
+// ArchiveRun records that a range of a table has been archived
+type ArchiveRun struct {
+	Table       string
+	RangeStart  time.Time
+	RangeEnd    time.Time
+	ManifestKey string
+	Rows        int64
+}
@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// Source categories derived from the referrer and UTM parameters of a click
+const (
+	SourceSearch = "search"
+	SourceSocial = "social"
+	SourceEmail  = "email"
+	SourceDirect = "direct"
+	SourceOther  = "other"
+)
+
+// Attribution holds where a click came from: the Referer header and the UTM
+// parameters of the short link's URL
+type Attribution struct {
+	ReferrerURL    string `json:"referrer_url,omitempty"`
+	ReferrerHost   string `json:"referrer_host,omitempty"`
+	SourceCategory string `json:"source_category"`
+	UTMSource      string `json:"utm_source,omitempty"`
+	UTMMedium      string `json:"utm_medium,omitempty"`
+	UTMCampaign    string `json:"utm_campaign,omitempty"`
+	UTMTerm        string `json:"utm_term,omitempty"`
+	UTMContent     string `json:"utm_content,omitempty"`
+}
+
+// AttributionBackfillReport summarizes a pass of attribution parsing over stored request logs
+type AttributionBackfillReport struct {
+	Parsed     int64     `json:"parsed"`
+	LastID     int64     `json:"last_id"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+}
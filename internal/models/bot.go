@@ -0,0 +1,9 @@
+package models
+
+// Reasons a click was classified as automated, in the order they are checked
+const (
+	BotReasonUserAgent   = "user_agent"
+	BotReasonNoUserAgent = "missing_user_agent"
+	BotReasonCrawlerIP   = "crawler_ip"
+	BotReasonBurst       = "burst"
+)
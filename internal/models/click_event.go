@@ -0,0 +1,48 @@
+package models
+
+import "time"
+
+// ClickEvent is published by the API gateway for every request to a short link.
+// It records what was actually served, so the database worker can persist it
+// without resolving the hash again.
+type ClickEvent struct {
+	EventID     string    `json:"event_id"`
+	RequestID   string    `json:"request_id"`
+	ClickID     string    `json:"click_id"`
+	Hash        string    `json:"hash"`
+	MappingID   int64     `json:"mapping_id,omitempty"`
+	ClientID    int64     `json:"client_id,omitempty"`
+	Destination string    `json:"destination,omitempty"`
+	Variant     string    `json:"variant,omitempty"`
+	StatusCode  int       `json:"status_code"`
+	Timestamp   time.Time `json:"timestamp"`
+	Request     Request   `json:"request"`
+	// OrganizationID is the organization owning the mapping, if any; ClientID
+	// is the member who created it
+	OrganizationID int64 `json:"organization_id,omitempty"`
+	// VisitorID identifies the visitor by the gateway's cookie, or by a
+	// daily-salted hash of IP and User-Agent for requests without it
+	VisitorID string `json:"visitor_id,omitempty"`
+	// ForwardedParams are the query parameters of the click passed on to the
+	// destination, under the names they were forwarded as
+	ForwardedParams map[string][]string `json:"forwarded_params,omitempty"`
+	// IsBot and BotReason are set by the database worker's classifier,
+	// FraudScore and FraudRules by its fraud scorer, and Unique when the
+	// visitor had no earlier click on the mapping within the unique window
+	IsBot      bool     `json:"is_bot,omitempty"`
+	BotReason  string   `json:"bot_reason,omitempty"`
+	FraudScore int      `json:"fraud_score,omitempty"`
+	FraudRules []string `json:"fraud_rules,omitempty"`
+	Unique     bool     `json:"unique,omitempty"`
+}
+
+// Matched reports whether the hash resolved to a redirect mapping
+func (e *ClickEvent) Matched() bool {
+	return e.MappingID != 0
+}
+
+// Owner returns the scope that owns the clicked mapping: its organization, or
+// the client that created it
+func (e *ClickEvent) Owner() Scope {
+	return Scope{ClientID: e.ClientID, OrganizationID: e.OrganizationID}
+}
@@ -3,12 +3,17 @@ package models
 import "time"
 
 type Client struct {
-	ID           int64     `json:"id"`
-	Username     string    `json:"username"`
-	PasswordHash string    `json:"-"`
-	Email        string    `json:"email"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
+	ID                    int64      `json:"id"`
+	Username              string     `json:"username"`
+	PasswordHash          string     `json:"-"`
+	Email                 string     `json:"email"`
+	EmailVerifiedAt       *time.Time `json:"email_verified_at,omitempty"`
+	IsAdmin               bool       `json:"is_admin"`
+	SuspendedAt           *time.Time `json:"suspended_at,omitempty"`
+	SuspendedReason       string     `json:"suspended_reason,omitempty"`
+	PasswordResetRequired bool       `json:"password_reset_required"`
+	CreatedAt             time.Time  `json:"created_at"`
+	UpdatedAt             time.Time  `json:"updated_at"`
 }
 
 type ClientRegistration struct {
@@ -22,10 +27,19 @@ type ClientLogin struct {
 	Password string `json:"password" binding:"required"`
 }
 
+// PasswordChange sets a new password in place of the current one
+type PasswordChange struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required,min=6"`
+}
+
 type ClientResponse struct {
-	ID        int64     `json:"id"`
-	Username  string    `json:"username"`
-	Email     string    `json:"email"`
-	CreatedAt time.Time `json:"created_at"`
-	Token     string    `json:"token"`
+	ID            int64     `json:"id"`
+	Username      string    `json:"username"`
+	Email         string    `json:"email"`
+	EmailVerified bool      `json:"email_verified"`
+	CreatedAt     time.Time `json:"created_at"`
+	Token         string    `json:"token"`
+	ExpiresIn     int64     `json:"expires_in"`
+	RefreshToken  string    `json:"refresh_token"`
 } 
\ No newline at end of file
@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+type Conversion struct {
+	ID                int64     `json:"id"`
+	ClientID          int64     `json:"client_id"`
+	OrganizationID    *int64    `json:"organization_id,omitempty"`
+	MappingID         int64     `json:"mapping_id"`
+	RedirectHistoryID int64     `json:"redirect_history_id"`
+	ClickID           string    `json:"click_id"`
+	EventName         string    `json:"event_name"`
+	Variant           string    `json:"variant"`
+	Payout            *float64  `json:"payout,omitempty"`
+	Currency          string    `json:"currency,omitempty"`
+	IPAddress         string    `json:"ip_address"`
+	CreatedAt         time.Time `json:"created_at"`
+}
+
+// PostbackRequest is accepted from the query string, a form body or JSON
+type PostbackRequest struct {
+	ClickID   string   `form:"click_id" json:"click_id" binding:"required,max=255"`
+	EventName string   `form:"event" json:"event" binding:"omitempty,max=64"`
+	Payout    *float64 `form:"payout" json:"payout" binding:"omitempty,min=0"`
+	Currency  string   `form:"currency" json:"currency" binding:"omitempty,len=3,alpha"`
+}
+
+// ConvertedClick is the click a postback is attributed to
+type ConvertedClick struct {
+	RedirectHistoryID int64
+	MappingID         int64
+	OrganizationID    *int64
+	Variant           string
+}
+
+type ConversionStats struct {
+	MappingID      int64              `json:"mapping_id"`
+	Hash           string             `json:"hash"`
+	Variant        string             `json:"variant"`
+	Clicks         int64              `json:"clicks"`
+	Conversions    int64              `json:"conversions"`
+	ConversionRate float64            `json:"conversion_rate"`
+	Payouts        map[string]float64 `json:"payouts"`
+}
@@ -0,0 +1,18 @@
+package models
+
+// Forwarding policies decide which query parameters of a click on a short link
+// are passed on to the destination
+const (
+	ForwardNone      = "none"
+	ForwardAll       = "all"
+	ForwardAllowlist = "allowlist"
+)
+
+// QueryForwarding is a mapping's forwarding policy. Params lists the parameters
+// forwarded under the allowlist policy; Renames forwards a parameter under
+// another name (e.g. sub1 as aff_sub) under both the all and allowlist policies.
+type QueryForwarding struct {
+	Policy  string            `json:"policy" binding:"omitempty,oneof=none all allowlist"`
+	Params  []string          `json:"params,omitempty" binding:"max=100,dive,min=1,max=255"`
+	Renames map[string]string `json:"renames,omitempty" binding:"max=100,dive,keys,min=1,max=255,endkeys,min=1,max=255"`
+}
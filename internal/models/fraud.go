@@ -0,0 +1,12 @@
+package models
+
+// Rules of the fraud score. A click's score is the sum of the weights of the
+// rules it fired, capped at MaxFraudScore.
+const (
+	FraudRuleDuplicateClickID = "duplicate_click_id"
+	FraudRuleIPVelocity       = "ip_velocity"
+	FraudRuleDatacenterASN    = "datacenter_asn"
+	FraudRuleMissingHeaders   = "missing_headers"
+
+	MaxFraudScore = 100
+)
@@ -0,0 +1,10 @@
+package models
+
+// Geo holds the location and network of a client IP from the offline GeoIP databases
+type Geo struct {
+	CountryCode string `json:"country_code,omitempty"`
+	Region      string `json:"region,omitempty"`
+	City        string `json:"city,omitempty"`
+	ASN         uint32 `json:"asn,omitempty"`
+	ASOrg       string `json:"as_org,omitempty"`
+}
@@ -0,0 +1,79 @@
+package models
+
+import "time"
+
+// Mapping statuses. Only active mappings redirect to their destination; the
+// others serve the inactive response.
+const (
+	MappingStatusDraft    = "draft"
+	MappingStatusActive   = "active"
+	MappingStatusPaused   = "paused"
+	MappingStatusArchived = "archived"
+)
+
+// Mapping actions move a mapping between statuses
+const (
+	MappingActionActivate = "activate"
+	MappingActionPause    = "pause"
+	MappingActionResume   = "resume"
+	MappingActionArchive  = "archive"
+)
+
+// Inactive responses are served for mappings that are not active: a redirect to
+// the mapping's fallback URL (redirect_url_black), 410 Gone, or a holding page
+const (
+	InactiveResponseFallback = "fallback"
+	InactiveResponseGone     = "gone"
+	InactiveResponseHolding  = "holding"
+)
+
+// VariantInactive marks clicks on a mapping that was not active and did not
+// redirect
+const VariantInactive = "inactive"
+
+type mappingAction struct {
+	from []string
+	to   string
+}
+
+// mappingActions lists the statuses each action applies to. Archiving is final.
+var mappingActions = map[string]mappingAction{
+	MappingActionActivate: {from: []string{MappingStatusDraft}, to: MappingStatusActive},
+	MappingActionPause:    {from: []string{MappingStatusActive}, to: MappingStatusPaused},
+	MappingActionResume:   {from: []string{MappingStatusPaused}, to: MappingStatusActive},
+	MappingActionArchive:  {from: []string{MappingStatusDraft, MappingStatusActive, MappingStatusPaused}, to: MappingStatusArchived},
+}
+
+// NextStatus returns the status an action moves a mapping in the given status
+// to, and false if the action does not apply to that status
+func NextStatus(action, status string) (string, bool) {
+	transition, ok := mappingActions[action]
+	if !ok {
+		return "", false
+	}
+	for _, from := range transition.from {
+		if from == status {
+			return transition.to, true
+		}
+	}
+	return "", false
+}
+
+// MappingTransitionRequest is the optional body of a mapping action.
+// InactiveResponse replaces the mapping's inactive response along the way.
+type MappingTransitionRequest struct {
+	Reason           string `json:"reason" binding:"max=255"`
+	InactiveResponse string `json:"inactive_response" binding:"omitempty,oneof=fallback gone holding"`
+}
+
+// MappingStatusChange is an entry of a mapping's audit trail. FromStatus is
+// empty for the status the mapping was created with.
+type MappingStatusChange struct {
+	ID         int64     `json:"id"`
+	MappingID  int64     `json:"mapping_id"`
+	ClientID   int64     `json:"client_id"`
+	FromStatus string    `json:"from_status,omitempty"`
+	ToStatus   string    `json:"to_status"`
+	Reason     string    `json:"reason,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
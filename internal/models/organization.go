@@ -0,0 +1,123 @@
+package models
+
+import "time"
+
+// Organization roles, from the most to the least privileged
+const (
+	OrgRoleOwner  = "owner"
+	OrgRoleAdmin  = "admin"
+	OrgRoleEditor = "editor"
+	OrgRoleViewer = "viewer"
+)
+
+// Permissions checked against an organization role
+const (
+	// PermissionViewMappings reads mappings, their history and stats
+	PermissionViewMappings = "mappings:view"
+	// PermissionEditMappings creates and changes mappings
+	PermissionEditMappings = "mappings:edit"
+	// PermissionManageMembers invites, removes and changes the role of members
+	// other than owners
+	PermissionManageMembers = "members:manage"
+	// PermissionManageOwners grants and revokes the owner role and deletes the
+	// organization
+	PermissionManageOwners = "owners:manage"
+)
+
+var rolePermissions = map[string][]string{
+	OrgRoleOwner:  {PermissionViewMappings, PermissionEditMappings, PermissionManageMembers, PermissionManageOwners},
+	OrgRoleAdmin:  {PermissionViewMappings, PermissionEditMappings, PermissionManageMembers},
+	OrgRoleEditor: {PermissionViewMappings, PermissionEditMappings},
+	OrgRoleViewer: {PermissionViewMappings},
+}
+
+// RoleAllows reports whether an organization role grants a permission
+func RoleAllows(role, permission string) bool {
+	for _, p := range rolePermissions[role] {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// RolesAllowing returns the organization roles that grant a permission
+func RolesAllowing(permission string) []string {
+	var roles []string
+	for _, role := range []string{OrgRoleOwner, OrgRoleAdmin, OrgRoleEditor, OrgRoleViewer} {
+		if RoleAllows(role, permission) {
+			roles = append(roles, role)
+		}
+	}
+	return roles
+}
+
+// Scope is who a request acts for: a member of an organization with a role,
+// or, with no OrganizationID, a client on its personal mappings
+type Scope struct {
+	ClientID       int64
+	OrganizationID int64
+	Role           string
+}
+
+// Allows reports whether the scope grants a permission. Clients have every
+// permission on their personal mappings.
+func (s Scope) Allows(permission string) bool {
+	return s.OrganizationID == 0 || RoleAllows(s.Role, permission)
+}
+
+type Organization struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	CreatedBy int64     `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+	// Role is the requesting client's role, when listing its organizations
+	Role string `json:"role,omitempty"`
+}
+
+type OrganizationCreate struct {
+	Name string `json:"name" binding:"required,max=100"`
+}
+
+type OrganizationMember struct {
+	OrganizationID int64     `json:"organization_id"`
+	ClientID       int64     `json:"client_id"`
+	Username       string    `json:"username"`
+	Email          string    `json:"email"`
+	Role           string    `json:"role"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+type OrganizationMemberUpdate struct {
+	Role string `json:"role" binding:"required,oneof=owner admin editor viewer"`
+}
+
+// OrganizationInvitation invites whoever holds the account with Email to join
+// an organization. Only the hash of its token is stored.
+type OrganizationInvitation struct {
+	ID             int64      `json:"id"`
+	OrganizationID int64      `json:"organization_id"`
+	Email          string     `json:"email"`
+	Role           string     `json:"role"`
+	TokenHash      string     `json:"-"`
+	InvitedBy      int64      `json:"invited_by"`
+	ExpiresAt      time.Time  `json:"expires_at"`
+	AcceptedAt     *time.Time `json:"accepted_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+type OrganizationInvitationCreate struct {
+	Email string `json:"email" binding:"required,email"`
+	Role  string `json:"role" binding:"required,oneof=owner admin editor viewer"`
+}
+
+// OrganizationInvitationCreated is returned once when an invitation is
+// created, with its token
+type OrganizationInvitationCreated struct {
+	OrganizationInvitation
+	Token string `json:"token"`
+}
+
+type OrganizationInvitationAccept struct {
+	Token string `json:"token" binding:"required"`
+}
@@ -0,0 +1,57 @@
+package models
+
+import "time"
+
+// Header modes of a privacy policy
+const (
+	// HeaderModeDeny stores every header except the listed ones
+	HeaderModeDeny = "deny"
+	// HeaderModeAllow stores only the listed headers
+	HeaderModeAllow = "allow"
+)
+
+// IP modes of a privacy policy
+const (
+	IPModeFull     = "full"
+	IPModeTruncate = "truncate"
+	IPModeHash     = "hash"
+)
+
+// PrivacySettings is a client's override of the platform privacy policy. Nil
+// fields inherit the platform policy.
+type PrivacySettings struct {
+	ClientID   int64     `json:"client_id"`
+	HeaderMode *string   `json:"header_mode,omitempty"`
+	Headers    []string  `json:"headers,omitempty"`
+	IPMode     *string   `json:"ip_mode,omitempty"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+type PrivacySettingsUpdate struct {
+	HeaderMode *string  `json:"header_mode" binding:"omitempty,oneof=allow deny"`
+	Headers    []string `json:"headers" binding:"omitempty,max=100,dive,min=1,max=100"`
+	IPMode     *string  `json:"ip_mode" binding:"omitempty,oneof=full truncate hash"`
+}
+
+// PrivacyPolicyView describes a privacy policy in API responses
+type PrivacyPolicyView struct {
+	HeaderMode string   `json:"header_mode"`
+	Headers    []string `json:"headers"`
+	IPMode     string   `json:"ip_mode"`
+}
+
+// PrivacySetting describes the platform policy and the client's override
+type PrivacySetting struct {
+	Platform PrivacyPolicyView `json:"platform"`
+	Override *PrivacySettings  `json:"override,omitempty"`
+}
+
+// ScrubReport summarizes a pass of the privacy policy over stored request logs
+type ScrubReport struct {
+	DryRun     bool      `json:"dry_run"`
+	Scanned    int64     `json:"scanned"`
+	Changed    int64     `json:"changed"`
+	LastID     int64     `json:"last_id"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+}
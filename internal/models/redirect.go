@@ -2,28 +2,68 @@ package models
 
 import "time"
 
+// Variants identify which of a mapping's destinations served a click
+const (
+	VariantPrimary = "primary"
+	VariantBlack   = "black"
+)
+
 type Redirect struct {
 	ID               int64     `json:"id"`
 	RequestLogID     int64     `json:"request_log_id"`
+	MappingID        int64     `json:"mapping_id"`
+	ClientID         int64     `json:"client_id"`
+	OrganizationID   *int64    `json:"organization_id,omitempty"`
+	ClickID          string    `json:"click_id"`
 	OriginalURL      string    `json:"original_url"`
 	RedirectURL      string    `json:"redirect_url"`
+	Variant          string    `json:"variant"`
 	RedirectType     string    `json:"redirect_type"`
 	RedirectStatus   int       `json:"redirect_status"`
 	RedirectTimestamp time.Time `json:"redirect_timestamp"`
+	IsBot            bool      `json:"is_bot"`
+	BotReason        string    `json:"bot_reason,omitempty"`
+	FraudScore       int       `json:"fraud_score"`
+	FraudRules       []string  `json:"fraud_rules,omitempty"`
+	ForwardedParams  map[string][]string `json:"forwarded_params,omitempty"`
+	VisitorID        string    `json:"visitor_id,omitempty"`
+	IsUnique         bool      `json:"is_unique"`
 	CreatedAt        time.Time `json:"created_at"`
 }
 
 type RedirectMapping struct {
 	ID              int64     `json:"id"`
 	ClientID        int64     `json:"client_id"`
+	OrganizationID  *int64    `json:"organization_id,omitempty"`
 	Hash            string    `json:"hash"`
 	RedirectURL     string    `json:"redirect_url"`
 	RedirectURLBlack string    `json:"redirect_url_black"`
+	UTM             UTMParams `json:"utm"`
+	UTMPolicy       string    `json:"utm_policy,omitempty"`
+	Forwarding      QueryForwarding `json:"forwarding"`
+	Status          string    `json:"status"`
+	InactiveResponse string   `json:"inactive_response,omitempty"`
 	CreatedAt       time.Time `json:"created_at"`
 	UpdatedAt       time.Time `json:"updated_at"`
 }
 
+// SettingsClientID returns the client whose privacy, UTM and retention settings
+// apply to the mapping's clicks: its creator for a personal mapping, and none
+// for an organization's mapping, which follows the platform defaults whichever
+// member created it
+func (m *RedirectMapping) SettingsClientID() int64 {
+	if m.OrganizationID != nil {
+		return 0
+	}
+	return m.ClientID
+}
+
 type RedirectMappingCreate struct {
 	RedirectURL     string `json:"redirect_url" binding:"required,url"`
 	RedirectURLBlack string `json:"redirect_url_black" binding:"required,url"`
+	UTM             UTMParams `json:"utm"`
+	UTMPolicy       string `json:"utm_policy" binding:"omitempty,oneof=keep override"`
+	Forwarding      QueryForwarding `json:"forwarding"`
+	Status          string `json:"status" binding:"omitempty,oneof=draft active"`
+	InactiveResponse string `json:"inactive_response" binding:"omitempty,oneof=fallback gone holding"`
 } 
\ No newline at end of file
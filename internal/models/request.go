@@ -1,15 +1,63 @@
 package models
 
-import "time"
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
 
 type Request struct {
 	ID              int64     `json:"id"`
+	RequestID       string    `json:"request_id"`
+	EventID         string    `json:"event_id,omitempty"`
+	ClientID        int64     `json:"client_id,omitempty"`
+	OrganizationID  int64     `json:"organization_id,omitempty"`
 	Timestamp       time.Time `json:"timestamp"`
 	IPAddress       string    `json:"ip_address"`
 	RequestURL      string    `json:"request_url"`
 	RequestMethod   string    `json:"request_method"`
 	RequestHeaders  []byte    `json:"request_headers"`
+	UserAgent       *UserAgent `json:"user_agent,omitempty"`
+	Geo             *Geo       `json:"geo,omitempty"`
+	Attribution     *Attribution `json:"attribution,omitempty"`
 	ProcessingStatus string    `json:"processing_status"`
 	CreatedAt       time.Time `json:"created_at"`
 	UpdatedAt       time.Time `json:"updated_at"`
-} 
\ No newline at end of file
+}
+
+// Header returns the first stored value of a request header, or "" if absent
+func (r *Request) Header(name string) string {
+	return DecodeHeaders(r.RequestHeaders).Get(name)
+}
+
+// SettingsClientID returns the client whose settings apply to the request, like
+// RedirectMapping.SettingsClientID
+func (r *Request) SettingsClientID() int64 {
+	if r.OrganizationID != 0 {
+		return 0
+	}
+	return r.ClientID
+}
+
+// DecodeHeaders parses stored request headers. Rows logged before headers were
+// captured with all their values hold a single string per header.
+func DecodeHeaders(data []byte) http.Header {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return http.Header{}
+	}
+
+	headers := make(http.Header, len(raw))
+	for name, value := range raw {
+		var values []string
+		if err := json.Unmarshal(value, &values); err != nil {
+			var single string
+			if err := json.Unmarshal(value, &single); err != nil {
+				continue
+			}
+			values = []string{single}
+		}
+		headers[http.CanonicalHeaderKey(name)] = values
+	}
+	return headers
+}
\ No newline at end of file
@@ -0,0 +1,89 @@
+package models
+
+import "time"
+
+// Raw click tables covered by retention policies
+const (
+	RetentionRequestLogs     = "request_logs"
+	RetentionRedirectHistory = "redirect_history"
+)
+
+// RetentionTables lists the tables in the order they are purged. Redirect history
+// goes first because it references request logs.
+var RetentionTables = []string{RetentionRedirectHistory, RetentionRequestLogs}
+
+// IsRetentionTable reports whether a table name is covered by retention policies
+func IsRetentionTable(name string) bool {
+	for _, t := range RetentionTables {
+		if t == name {
+			return true
+		}
+	}
+	return false
+}
+
+// RetentionPolicy is a per-client override of a table's retention
+type RetentionPolicy struct {
+	ClientID  int64     `json:"client_id"`
+	Table     string    `json:"table"`
+	Days      int       `json:"retention_days"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type RetentionPolicyUpdate struct {
+	Table string `json:"table" binding:"required,oneof=request_logs redirect_history"`
+	Days  int    `json:"retention_days" binding:"required,min=1"`
+}
+
+// RetentionSetting describes how long one table keeps a client's data.
+// Zero days means the data is kept forever.
+type RetentionSetting struct {
+	Table         string `json:"table"`
+	DefaultDays   int    `json:"default_days"`
+	OverrideDays  *int   `json:"override_days,omitempty"`
+	EffectiveDays int    `json:"effective_days"`
+}
+
+// RetentionScope selects the expired rows of a table purged in one pass: the
+// rows of one client's personal mappings, or with a zero ClientID the rows of
+// every client without an override and of every organization mapping
+type RetentionScope struct {
+	ClientID         int64
+	ExcludeClientIDs []int64
+	Cutoff           time.Time
+}
+
+// RetentionScopeReport is the outcome of purging one scope. Rows counts the
+// deleted rows, or the rows that would be deleted in a dry run.
+type RetentionScopeReport struct {
+	Table    string    `json:"table"`
+	ClientID int64     `json:"client_id,omitempty"`
+	Days     int       `json:"retention_days"`
+	Cutoff   time.Time `json:"cutoff"`
+	Rows     int64     `json:"rows"`
+}
+
+// Partition describes one range partition; a nil LessThan is the MAXVALUE catch-all
+type Partition struct {
+	Name     string
+	LessThan *time.Time
+	Rows     int64
+}
+
+// RetentionPartitionReport records a partition created or dropped by the
+// retention job. Rows is the estimated row count of dropped partitions.
+type RetentionPartitionReport struct {
+	Table     string    `json:"table"`
+	Partition string    `json:"partition"`
+	LessThan  time.Time `json:"less_than"`
+	Action    string    `json:"action"`
+	Rows      int64     `json:"rows,omitempty"`
+}
+
+type RetentionReport struct {
+	StartedAt  time.Time                  `json:"started_at"`
+	FinishedAt time.Time                  `json:"finished_at"`
+	DryRun     bool                       `json:"dry_run"`
+	Partitions []RetentionPartitionReport `json:"partitions,omitempty"`
+	Scopes     []RetentionScopeReport     `json:"scopes"`
+}
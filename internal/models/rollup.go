@@ -0,0 +1,85 @@
+package models
+
+import (
+	"platform/internal/hll"
+	"time"
+)
+
+// RollupKey identifies one hourly click counter. Daily counters use the same
+// dimensions with the hour truncated to its UTC day. OrganizationID is 0 for
+// personal mappings.
+type RollupKey struct {
+	Hour           time.Time
+	MappingID      int64
+	ClientID       int64
+	OrganizationID int64
+	Variant        string
+	Country        string
+	DeviceClass    string
+	IsBot          bool
+}
+
+// Day returns the UTC day the key's hour belongs to
+func (k RollupKey) Day() time.Time {
+	h := k.Hour.UTC()
+	return time.Date(h.Year(), h.Month(), h.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// RollupCounts are the values of one click counter. UniqueClicks counts the
+// clicks the worker marked unique.
+type RollupCounts struct {
+	Clicks       int64
+	UniqueClicks int64
+}
+
+// VisitorKey identifies one hourly sketch of the distinct visitors of a mapping.
+// Daily sketches use the hour truncated to its UTC day.
+type VisitorKey struct {
+	Hour           time.Time
+	MappingID      int64
+	ClientID       int64
+	OrganizationID int64
+	IsBot          bool
+}
+
+// Day returns the UTC day the key's hour belongs to
+func (k VisitorKey) Day() time.Time {
+	h := k.Hour.UTC()
+	return time.Date(h.Year(), h.Month(), h.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// RollupBatch holds the counters and visitor sketches added to the rollups by
+// one flush or one rebuilt day
+type RollupBatch struct {
+	Counts   map[RollupKey]RollupCounts
+	Visitors map[VisitorKey]*hll.Sketch
+}
+
+func NewRollupBatch() *RollupBatch {
+	return &RollupBatch{
+		Counts:   make(map[RollupKey]RollupCounts),
+		Visitors: make(map[VisitorKey]*hll.Sketch),
+	}
+}
+
+// Empty reports whether the batch adds nothing
+func (b *RollupBatch) Empty() bool {
+	return len(b.Counts) == 0 && len(b.Visitors) == 0
+}
+
+// Merge adds another batch to this one
+func (b *RollupBatch) Merge(other *RollupBatch) {
+	for key, counts := range other.Counts {
+		merged := b.Counts[key]
+		merged.Clicks += counts.Clicks
+		merged.UniqueClicks += counts.UniqueClicks
+		b.Counts[key] = merged
+	}
+	for key, sketch := range other.Visitors {
+		if existing, ok := b.Visitors[key]; ok {
+			existing.Merge(sketch)
+		} else {
+			b.Visitors[key] = sketch
+		}
+	}
+}
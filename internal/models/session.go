@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// RefreshToken is a stored refresh token. Only its hash is kept. Every refresh
+// replaces the token with a new one in the same family (the login session), so
+// a token that was already used and is presented again signals theft.
+type RefreshToken struct {
+	ID        int64
+	ClientID  int64
+	FamilyID  string
+	TokenHash string
+	// AccessJTI and AccessExpiresAt identify the access token issued along with
+	// the refresh token, so it can be revoked with the family
+	AccessJTI       string
+	AccessExpiresAt time.Time
+	ExpiresAt       time.Time
+	UsedAt          *time.Time
+	RevokedAt       *time.Time
+	CreatedAt       time.Time
+}
+
+// RevokedToken is an access token denied before its expiry
+type RevokedToken struct {
+	JTI       string
+	ExpiresAt time.Time
+}
+
+// TokenPair is an access token with the refresh token that renews it.
+// ExpiresIn is the access token's lifetime in seconds.
+type TokenPair struct {
+	Token        string `json:"token"`
+	ExpiresIn    int64  `json:"expires_in"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+type TokenRefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
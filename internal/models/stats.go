@@ -0,0 +1,58 @@
+package models
+
+import "time"
+
+// StatsFilter selects the clicks a stats query covers. A zero MappingID means
+// every mapping within the scope. Clicks classified as bots are left out unless
+// IncludeBots is set.
+type StatsFilter struct {
+	Scope       Scope
+	MappingID   int64
+	From        time.Time
+	To          time.Time
+	IncludeBots bool
+}
+
+// HourlyCount is the number of clicks, and of unique clicks, in the UTC hour
+// starting at Hour
+type HourlyCount struct {
+	Hour         time.Time
+	Clicks       int64
+	UniqueClicks int64
+}
+
+type SeriesPoint struct {
+	Start        time.Time `json:"start"`
+	Clicks       int64     `json:"clicks"`
+	UniqueClicks int64     `json:"unique_clicks"`
+}
+
+type BreakdownItem struct {
+	Value  string  `json:"value"`
+	Clicks int64   `json:"clicks"`
+	Share  float64 `json:"share"`
+}
+
+type PeriodStats struct {
+	From        time.Time `json:"from"`
+	To          time.Time `json:"to"`
+	TotalClicks int64     `json:"total_clicks"`
+	// UniqueClicks adds up the clicks marked unique; UniqueVisitors counts
+	// distinct visitors over the whole period, estimated unless exact counts
+	// were requested
+	UniqueClicks   int64         `json:"unique_clicks"`
+	UniqueVisitors int64         `json:"unique_visitors"`
+	Series         []SeriesPoint `json:"series"`
+}
+
+type StatsReport struct {
+	MappingID  int64                      `json:"mapping_id,omitempty"`
+	Interval   string                     `json:"interval"`
+	TimeZone   string                     `json:"time_zone"`
+	Current    PeriodStats                `json:"current"`
+	Breakdowns map[string][]BreakdownItem `json:"breakdowns,omitempty"`
+	Previous   *PeriodStats               `json:"previous,omitempty"`
+	// Change is the relative change of total clicks against the previous period,
+	// omitted when the previous period had no clicks
+	Change *float64 `json:"change,omitempty"`
+}
@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// Device classes derived from the User-Agent
+const (
+	DeviceDesktop = "desktop"
+	DeviceMobile  = "mobile"
+	DeviceTablet  = "tablet"
+	DeviceBot     = "bot"
+	DeviceUnknown = "unknown"
+)
+
+// UnknownFamily is the browser or OS family of a User-Agent no rule matches
+const UnknownFamily = "Other"
+
+// UserAgent holds the fields parsed from a request's User-Agent header
+type UserAgent struct {
+	BrowserFamily  string `json:"browser_family"`
+	BrowserVersion string `json:"browser_version,omitempty"`
+	OSFamily       string `json:"os_family"`
+	OSVersion      string `json:"os_version,omitempty"`
+	DeviceClass    string `json:"device_class"`
+	IsBot          bool   `json:"is_bot"`
+}
+
+// UserAgentBackfillReport summarizes a pass of the User-Agent parser over stored request logs
+type UserAgentBackfillReport struct {
+	Parsed     int64     `json:"parsed"`
+	LastID     int64     `json:"last_id"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+}
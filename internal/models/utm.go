@@ -0,0 +1,60 @@
+package models
+
+import "time"
+
+// UTM policies decide what happens to a UTM parameter the destination URL
+// already carries: keep leaves it, override replaces it
+const (
+	UTMPolicyKeep     = "keep"
+	UTMPolicyOverride = "override"
+)
+
+// UTMParams are the UTM parameters merged into a destination URL. Empty values
+// are not set; on a mapping they inherit the client's defaults.
+type UTMParams struct {
+	Source   string `json:"utm_source,omitempty" binding:"max=255"`
+	Medium   string `json:"utm_medium,omitempty" binding:"max=255"`
+	Campaign string `json:"utm_campaign,omitempty" binding:"max=255"`
+	Term     string `json:"utm_term,omitempty" binding:"max=255"`
+	Content  string `json:"utm_content,omitempty" binding:"max=255"`
+}
+
+// Inherit fills the empty parameters from defaults
+func (p UTMParams) Inherit(defaults UTMParams) UTMParams {
+	if p.Source == "" {
+		p.Source = defaults.Source
+	}
+	if p.Medium == "" {
+		p.Medium = defaults.Medium
+	}
+	if p.Campaign == "" {
+		p.Campaign = defaults.Campaign
+	}
+	if p.Term == "" {
+		p.Term = defaults.Term
+	}
+	if p.Content == "" {
+		p.Content = defaults.Content
+	}
+	return p
+}
+
+// UTMSettings are a client's default UTM parameters and policy for its mappings
+type UTMSettings struct {
+	ClientID int64 `json:"client_id"`
+	UTMParams
+	Policy    string    `json:"policy,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type UTMSettingsUpdate struct {
+	UTMParams
+	Policy string `json:"policy" binding:"omitempty,oneof=keep override"`
+}
+
+// MappingUTMUpdate replaces the UTM parameters and policy of a mapping. An
+// empty policy inherits the client's.
+type MappingUTMUpdate struct {
+	UTM       UTMParams `json:"utm"`
+	UTMPolicy string    `json:"utm_policy" binding:"omitempty,oneof=keep override"`
+}
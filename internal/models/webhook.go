@@ -0,0 +1,142 @@
+package models
+
+import "time"
+
+const (
+	WebhookEventClick = "click.created"
+
+	WebhookStatusPending   = "pending"
+	WebhookStatusSucceeded = "succeeded"
+	WebhookStatusFailed    = "failed"
+)
+
+type WebhookEndpoint struct {
+	ID                  int64      `json:"id"`
+	ClientID            int64      `json:"client_id"`
+	OrganizationID      *int64     `json:"organization_id,omitempty"`
+	URL                 string     `json:"url"`
+	Secret              string     `json:"secret,omitempty"`
+	EventTypes          []string   `json:"event_types"`
+	MappingIDs          []int64    `json:"mapping_ids,omitempty"`
+	MinFraudScore       *int       `json:"min_fraud_score,omitempty"`
+	MaxFraudScore       *int       `json:"max_fraud_score,omitempty"`
+	IsActive            bool       `json:"is_active"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	DisabledAt          *time.Time `json:"disabled_at,omitempty"`
+	DisabledReason      string     `json:"disabled_reason,omitempty"`
+	CreatedAt           time.Time  `json:"created_at"`
+	UpdatedAt           time.Time  `json:"updated_at"`
+}
+
+// Accepts reports whether the endpoint's filters match an event
+func (e *WebhookEndpoint) Accepts(eventType string, mappingID int64, fraudScore int) bool {
+	typeMatch := false
+	for _, t := range e.EventTypes {
+		if t == eventType || t == "*" {
+			typeMatch = true
+			break
+		}
+	}
+	if !typeMatch {
+		return false
+	}
+
+	if e.MinFraudScore != nil && fraudScore < *e.MinFraudScore {
+		return false
+	}
+	if e.MaxFraudScore != nil && fraudScore > *e.MaxFraudScore {
+		return false
+	}
+
+	if len(e.MappingIDs) == 0 {
+		return true
+	}
+	for _, id := range e.MappingIDs {
+		if id == mappingID {
+			return true
+		}
+	}
+	return false
+}
+
+type WebhookEndpointCreate struct {
+	URL        string   `json:"url" binding:"required,http_url"`
+	EventTypes []string `json:"event_types" binding:"required,min=1,dive,oneof=click.created *"`
+	MappingIDs []int64  `json:"mapping_ids" binding:"max=100"`
+	// Only clicks scoring within [MinFraudScore, MaxFraudScore] are delivered
+	MinFraudScore *int `json:"min_fraud_score" binding:"omitempty,min=0,max=100"`
+	MaxFraudScore *int `json:"max_fraud_score" binding:"omitempty,min=0,max=100"`
+}
+
+type WebhookDelivery struct {
+	ID             int64      `json:"id"`
+	EndpointID     int64      `json:"endpoint_id"`
+	EventID        string     `json:"event_id"`
+	EventType      string     `json:"event_type"`
+	ReplayOf       *int64     `json:"replay_of,omitempty"`
+	Payload        []byte     `json:"-"`
+	Status         string     `json:"status"`
+	Attempts       int        `json:"attempts"`
+	NextAttemptAt  time.Time  `json:"next_attempt_at"`
+	LastAttemptAt  *time.Time `json:"last_attempt_at,omitempty"`
+	ResponseStatus *int       `json:"response_status,omitempty"`
+	LastError      string     `json:"last_error,omitempty"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+
+	// Populated when a delivery is claimed for sending
+	URL    string `json:"-"`
+	Secret string `json:"-"`
+}
+
+// WebhookEvent is the JSON envelope POSTed to webhook endpoints
+type WebhookEvent struct {
+	ID        string      `json:"id"`
+	Type      string      `json:"type"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+// WebhookClickData is the data of a click.created event
+type WebhookClickData struct {
+	MappingID   int64        `json:"mapping_id"`
+	Hash        string       `json:"hash"`
+	ClickID     string       `json:"click_id"`
+	RequestID   string       `json:"request_id"`
+	Destination string       `json:"destination"`
+	Variant     string       `json:"variant"`
+	StatusCode  int          `json:"status_code"`
+	IPAddress   string       `json:"ip_address"`
+	RequestURL  string       `json:"request_url"`
+	Geo         *Geo         `json:"geo,omitempty"`
+	Attribution *Attribution `json:"attribution,omitempty"`
+	IsBot       bool         `json:"is_bot"`
+	BotReason   string       `json:"bot_reason,omitempty"`
+	FraudScore  int          `json:"fraud_score"`
+	FraudRules  []string     `json:"fraud_rules,omitempty"`
+	Unique      bool         `json:"unique"`
+	Timestamp   time.Time    `json:"timestamp"`
+}
+
+// NewWebhookClickData builds the click.created payload from a click event
+func NewWebhookClickData(event *ClickEvent) *WebhookClickData {
+	return &WebhookClickData{
+		MappingID:   event.MappingID,
+		Hash:        event.Hash,
+		ClickID:     event.ClickID,
+		RequestID:   event.RequestID,
+		Destination: event.Destination,
+		Variant:     event.Variant,
+		StatusCode:  event.StatusCode,
+		IPAddress:   event.Request.IPAddress,
+		RequestURL:  event.Request.RequestURL,
+		Geo:         event.Request.Geo,
+		Attribution: event.Request.Attribution,
+		IsBot:       event.IsBot,
+		BotReason:   event.BotReason,
+		FraudScore:  event.FraudScore,
+		FraudRules:  event.FraudRules,
+		Unique:      event.Unique,
+		Timestamp:   event.Timestamp,
+	}
+}
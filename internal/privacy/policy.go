@@ -0,0 +1,212 @@
+package privacy
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"platform/internal/config"
+	"platform/internal/models"
+	"sort"
+)
+
+// hashedIPLength is the number of hex characters kept of a keyed IP hash; it
+// fits the ip_address column and is never a valid IP, so hashing is idempotent
+const hashedIPLength = 32
+
+// headerRule keeps or drops the listed headers
+type headerRule struct {
+	mode  string
+	names map[string]bool
+}
+
+func newHeaderRule(mode string, names []string) headerRule {
+	rule := headerRule{mode: mode, names: make(map[string]bool, len(names))}
+	for _, name := range names {
+		rule.names[http.CanonicalHeaderKey(name)] = true
+	}
+	return rule
+}
+
+func (r headerRule) allows(name string) bool {
+	if r.mode == models.HeaderModeAllow {
+		return r.names[name]
+	}
+	return !r.names[name]
+}
+
+// Policy decides which request headers are stored and how client IPs are
+// anonymized. A header is stored only if every rule of the policy allows it.
+type Policy struct {
+	rules    []headerRule
+	ipMode   string
+	ipv4Mask net.IPMask
+	ipv6Mask net.IPMask
+	hashKey  []byte
+	view     models.PrivacyPolicyView
+}
+
+// NewPolicy builds the platform policy from the configuration
+func NewPolicy(cfg config.PrivacyConfig) (*Policy, error) {
+	if cfg.HeaderMode != models.HeaderModeAllow && cfg.HeaderMode != models.HeaderModeDeny {
+		return nil, fmt.Errorf("invalid privacy header mode %q", cfg.HeaderMode)
+	}
+	switch cfg.IPMode {
+	case models.IPModeFull, models.IPModeTruncate:
+	case models.IPModeHash:
+		if cfg.IPHashKey == "" {
+			return nil, fmt.Errorf("privacy IP hash key is required to hash IPs")
+		}
+	default:
+		return nil, fmt.Errorf("invalid privacy IP mode %q", cfg.IPMode)
+	}
+	if cfg.IPv4Prefix < 0 || cfg.IPv4Prefix > 32 || cfg.IPv6Prefix < 0 || cfg.IPv6Prefix > 128 {
+		return nil, fmt.Errorf("invalid privacy IP prefix lengths /%d and /%d", cfg.IPv4Prefix, cfg.IPv6Prefix)
+	}
+
+	headers := make([]string, 0, len(cfg.Headers))
+	for _, name := range cfg.Headers {
+		headers = append(headers, http.CanonicalHeaderKey(name))
+	}
+	sort.Strings(headers)
+
+	return &Policy{
+		rules:    []headerRule{newHeaderRule(cfg.HeaderMode, headers)},
+		ipMode:   cfg.IPMode,
+		ipv4Mask: net.CIDRMask(cfg.IPv4Prefix, 32),
+		ipv6Mask: net.CIDRMask(cfg.IPv6Prefix, 128),
+		hashKey:  []byte(cfg.IPHashKey),
+		view: models.PrivacyPolicyView{
+			HeaderMode: cfg.HeaderMode,
+			Headers:    headers,
+			IPMode:     cfg.IPMode,
+		},
+	}, nil
+}
+
+// View describes the platform policy
+func (p *Policy) View() models.PrivacyPolicyView {
+	return p.view
+}
+
+// CheckSettings reports why a client's settings cannot be applied, if they cannot
+func (p *Policy) CheckSettings(update *models.PrivacySettingsUpdate) error {
+	if update.HeaderMode != nil && update.Headers == nil {
+		return fmt.Errorf("headers are required with header_mode")
+	}
+	if update.IPMode == nil {
+		return nil
+	}
+	if *update.IPMode == models.IPModeFull && p.ipMode != models.IPModeFull {
+		return fmt.Errorf("ip_mode cannot be full while the platform anonymizes IPs")
+	}
+	if *update.IPMode == models.IPModeHash && len(p.hashKey) == 0 {
+		return fmt.Errorf("ip_mode hash is not available on this platform")
+	}
+	return nil
+}
+
+// WithClient returns the policy tightened by a client's settings. The client's
+// header list is applied on top of the platform's, and the client's IP mode
+// replaces the platform's unless it would store full IPs the platform anonymizes.
+func (p *Policy) WithClient(settings *models.PrivacySettings) *Policy {
+	if settings == nil {
+		return p
+	}
+
+	policy := *p
+	if settings.Headers != nil {
+		mode := models.HeaderModeDeny
+		if settings.HeaderMode != nil {
+			mode = *settings.HeaderMode
+		}
+		policy.rules = append(append([]headerRule(nil), p.rules...), newHeaderRule(mode, settings.Headers))
+	}
+	if settings.IPMode != nil {
+		switch mode := *settings.IPMode; {
+		case mode == models.IPModeFull && p.ipMode != models.IPModeFull:
+		case mode == models.IPModeHash && len(p.hashKey) == 0:
+			policy.ipMode = models.IPModeTruncate
+		default:
+			policy.ipMode = mode
+		}
+	}
+	return &policy
+}
+
+// Headers returns every value of the request headers the policy allows
+func (p *Policy) Headers(header http.Header) map[string][]string {
+	headers := make(map[string][]string, len(header))
+	for name, values := range header {
+		name = http.CanonicalHeaderKey(name)
+		if len(values) == 0 || !p.allowsHeader(name) {
+			continue
+		}
+		headers[name] = append(headers[name], values...)
+	}
+	return headers
+}
+
+func (p *Policy) allowsHeader(name string) bool {
+	for _, rule := range p.rules {
+		if !rule.allows(name) {
+			return false
+		}
+	}
+	return true
+}
+
+// IP anonymizes a client IP. Values that are not IPs, such as IPs hashed
+// earlier, are returned unchanged.
+func (p *Policy) IP(ip string) string {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return ip
+	}
+
+	switch p.ipMode {
+	case models.IPModeTruncate:
+		if v4 := parsed.To4(); v4 != nil {
+			return v4.Mask(p.ipv4Mask).String()
+		}
+		return parsed.Mask(p.ipv6Mask).String()
+	case models.IPModeHash:
+		mac := hmac.New(sha256.New, p.hashKey)
+		mac.Write([]byte(parsed.String()))
+		return hex.EncodeToString(mac.Sum(nil))[:hashedIPLength]
+	default:
+		return ip
+	}
+}
+
+// Apply sets the anonymized IP and the allowed headers of a request log
+func (p *Policy) Apply(request *models.Request, ip string, header http.Header) {
+	request.IPAddress = p.IP(ip)
+
+	headersJSON, err := json.Marshal(p.Headers(header))
+	if err != nil {
+		headersJSON = []byte("{}")
+	}
+	request.RequestHeaders = headersJSON
+}
+
+// Scrub applies the policy to a stored request log, reporting whether it changed
+func (p *Policy) Scrub(request *models.Request) bool {
+	ip, headers := request.IPAddress, request.RequestHeaders
+	p.Apply(request, ip, models.DecodeHeaders(headers))
+	return request.IPAddress != ip || !sameJSON(request.RequestHeaders, headers)
+}
+
+func sameJSON(a, b []byte) bool {
+	var va, vb interface{}
+	if json.Unmarshal(a, &va) != nil || json.Unmarshal(b, &vb) != nil {
+		return bytes.Equal(a, b)
+	}
+	ea, _ := json.Marshal(va)
+	eb, _ := json.Marshal(vb)
+	return bytes.Equal(ea, eb)
+}
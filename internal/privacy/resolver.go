@@ -0,0 +1,71 @@
+package privacy
+
+import (
+	"platform/internal/repository/mysql"
+	"sync"
+	"time"
+)
+
+type cachedPolicy struct {
+	policy    *Policy
+	expiresAt time.Time
+}
+
+// Resolver returns the policy in effect for a client, caching each client's
+// settings for a while so the redirect path does not query them on every click
+type Resolver struct {
+	platform *Policy
+	repo     *mysql.PrivacyRepository
+	ttl      time.Duration
+
+	mu    sync.Mutex
+	cache map[int64]cachedPolicy
+}
+
+func NewResolver(platform *Policy, repo *mysql.PrivacyRepository, ttl time.Duration) *Resolver {
+	return &Resolver{
+		platform: platform,
+		repo:     repo,
+		ttl:      ttl,
+		cache:    make(map[int64]cachedPolicy),
+	}
+}
+
+// Platform returns the platform policy
+func (r *Resolver) Platform() *Policy {
+	return r.platform
+}
+
+// For returns the policy of a client; requests that matched no mapping
+// (client ID zero) get the platform policy
+func (r *Resolver) For(clientID int64) (*Policy, error) {
+	if clientID == 0 {
+		return r.platform, nil
+	}
+
+	now := time.Now()
+	r.mu.Lock()
+	cached, ok := r.cache[clientID]
+	r.mu.Unlock()
+	if ok && now.Before(cached.expiresAt) {
+		return cached.policy, nil
+	}
+
+	settings, err := r.repo.GetSettings(clientID)
+	if err != nil {
+		return nil, err
+	}
+	policy := r.platform.WithClient(settings)
+
+	r.mu.Lock()
+	r.cache[clientID] = cachedPolicy{policy: policy, expiresAt: now.Add(r.ttl)}
+	r.mu.Unlock()
+	return policy, nil
+}
+
+// Forget drops a client's cached policy so changed settings apply immediately
+func (r *Resolver) Forget(clientID int64) {
+	r.mu.Lock()
+	delete(r.cache, clientID)
+	r.mu.Unlock()
+}
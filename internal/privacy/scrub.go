@@ -0,0 +1,59 @@
+package privacy
+
+import (
+	"context"
+	"platform/internal/models"
+	"platform/pkg/logger"
+	"time"
+)
+
+// Scrub applies the privacy policy in effect for each row's client, or the
+// platform policy for clicks on organization mappings, to the stored request
+// logs with IDs above afterID, batch by batch. Rows already compliant are left
+// untouched, so the scrub can be rerun or resumed from the reported last ID.
+func Scrub(ctx context.Context, resolver *Resolver, batchSize int, afterID int64, dryRun bool) (*models.ScrubReport, error) {
+	report := &models.ScrubReport{
+		DryRun:    dryRun,
+		LastID:    afterID,
+		StartedAt: time.Now().UTC(),
+	}
+
+	for {
+		if ctx.Err() != nil {
+			return report, ctx.Err()
+		}
+
+		requests, err := resolver.repo.GetRequestLogs(report.LastID, batchSize)
+		if err != nil {
+			return report, err
+		}
+		if len(requests) == 0 {
+			break
+		}
+
+		var changed []models.Request
+		for _, request := range requests {
+			policy, err := resolver.For(request.SettingsClientID())
+			if err != nil {
+				return report, err
+			}
+			if policy.Scrub(&request) {
+				changed = append(changed, request)
+			}
+		}
+
+		if !dryRun {
+			if err := resolver.repo.UpdateRequestLogs(changed); err != nil {
+				return report, err
+			}
+		}
+
+		report.Scanned += int64(len(requests))
+		report.Changed += int64(len(changed))
+		report.LastID = requests[len(requests)-1].ID
+		logger.Info("Scrubbed request logs", "dry_run", dryRun, "last_id", report.LastID, "scanned", report.Scanned, "changed", report.Changed)
+	}
+
+	report.FinishedAt = time.Now().UTC()
+	return report, nil
+}
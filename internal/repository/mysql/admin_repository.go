@@ -0,0 +1,425 @@
+package mysql
+
+import (
+	"database/sql"
+	"fmt"
+	"platform/internal/models"
+	"strings"
+	"time"
+)
+
+// AdminRepository serves the platform admin API. Every change it makes is
+// recorded in admin_actions in the same transaction.
+type AdminRepository struct {
+	db *sql.DB
+}
+
+func NewAdminRepository(db *sql.DB) *AdminRepository {
+	return &AdminRepository{
+		db: db,
+	}
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// SearchClients returns the clients matching a search, newest first
+func (r *AdminRepository) SearchClients(search models.ClientSearch) ([]models.Client, error) {
+	where := `1 = 1`
+	var args []interface{}
+	if search.Query != "" {
+		pattern := "%" + likeEscaper.Replace(search.Query) + "%"
+		where += ` AND (username LIKE ? OR email LIKE ?)`
+		args = append(args, pattern, pattern)
+	}
+	switch search.Status {
+	case "active":
+		where += ` AND suspended_at IS NULL`
+	case "suspended":
+		where += ` AND suspended_at IS NOT NULL`
+	}
+
+	query := `SELECT ` + clientColumns + `
+		FROM clients
+		WHERE ` + where + `
+		ORDER BY id DESC
+		LIMIT ?
+	`
+
+	rows, err := r.db.Query(query, append(args, search.Limit)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search clients: %w", err)
+	}
+	defer rows.Close()
+
+	var clients []models.Client
+	for rows.Next() {
+		client, err := scanClient(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan client: %w", err)
+		}
+		clients = append(clients, *client)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate clients: %w", err)
+	}
+
+	return clients, nil
+}
+
+// GetClientOverview returns a client with its usage since a UTC day, or nil if
+// there is no such client
+func (r *AdminRepository) GetClientOverview(clientID int64, since time.Time) (*models.ClientOverview, error) {
+	query := `SELECT ` + clientColumns + `
+		FROM clients
+		WHERE id = ?
+	`
+
+	client, err := scanClient(r.db.QueryRow(query, clientID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get client: %w", err)
+	}
+
+	overview := &models.ClientOverview{Client: *client}
+	overview.Mappings, err = r.countMappings(`WHERE client_id = ?`, clientID)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := []struct {
+		query string
+		args  []interface{}
+		dest  *int64
+	}{
+		{
+			"SELECT COALESCE(SUM(clicks), 0) FROM click_rollups_daily WHERE client_id = ? AND bucket_date >= ?",
+			[]interface{}{clientID, since.Format("2006-01-02")}, &overview.Clicks,
+		},
+		{"SELECT COUNT(*) FROM api_keys WHERE client_id = ?", []interface{}{clientID}, &overview.APIKeys},
+		{"SELECT COUNT(*) FROM organization_members WHERE client_id = ?", []interface{}{clientID}, &overview.Organizations},
+	}
+	for _, count := range counts {
+		if err := r.db.QueryRow(count.query, count.args...).Scan(count.dest); err != nil {
+			return nil, fmt.Errorf("failed to get client usage: %w", err)
+		}
+	}
+
+	return overview, nil
+}
+
+// GetClientMappings returns every mapping a client created, personal or owned
+// by an organization, newest first
+func (r *AdminRepository) GetClientMappings(clientID int64) ([]models.RedirectMapping, error) {
+	query := `SELECT ` + redirectMappingColumns + `
+		FROM redirect_mappings
+		WHERE client_id = ?
+		ORDER BY created_at DESC
+	`
+
+	rows, err := r.db.Query(query, clientID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get redirect mappings: %w", err)
+	}
+	defer rows.Close()
+
+	var mappings []models.RedirectMapping
+	for rows.Next() {
+		mapping, err := scanRedirectMapping(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan redirect mapping: %w", err)
+		}
+		mappings = append(mappings, *mapping)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate redirect mappings: %w", err)
+	}
+
+	return mappings, nil
+}
+
+// SuspendClient suspends a client. It returns false, changing nothing, if the
+// client is already suspended or does not exist.
+func (r *AdminRepository) SuspendClient(adminID, clientID int64, reason string) (bool, error) {
+	return r.change(models.AdminAction{
+		AdminID:    adminID,
+		Action:     models.AdminActionSuspend,
+		TargetType: models.AdminTargetClient,
+		TargetID:   clientID,
+		Reason:     reason,
+	}, func(tx *sql.Tx) (sql.Result, error) {
+		return tx.Exec(
+			"UPDATE clients SET suspended_at = ?, suspended_reason = ? WHERE id = ? AND suspended_at IS NULL",
+			time.Now().UTC(), reason, clientID,
+		)
+	})
+}
+
+// UnsuspendClient lifts a client's suspension. It returns false, changing
+// nothing, if the client is not suspended.
+func (r *AdminRepository) UnsuspendClient(adminID, clientID int64) (bool, error) {
+	return r.change(models.AdminAction{
+		AdminID:    adminID,
+		Action:     models.AdminActionUnsuspend,
+		TargetType: models.AdminTargetClient,
+		TargetID:   clientID,
+	}, func(tx *sql.Tx) (sql.Result, error) {
+		return tx.Exec(
+			"UPDATE clients SET suspended_at = NULL, suspended_reason = NULL WHERE id = ? AND suspended_at IS NOT NULL",
+			clientID,
+		)
+	})
+}
+
+// RequirePasswordReset makes a client reset its password before logging in or
+// using the API again. It returns false, changing nothing, if a reset is
+// already required.
+func (r *AdminRepository) RequirePasswordReset(adminID, clientID int64, reason string) (bool, error) {
+	return r.change(models.AdminAction{
+		AdminID:    adminID,
+		Action:     models.AdminActionPasswordReset,
+		TargetType: models.AdminTargetClient,
+		TargetID:   clientID,
+		Reason:     reason,
+	}, func(tx *sql.Tx) (sql.Result, error) {
+		return tx.Exec(
+			"UPDATE clients SET password_reset_required = TRUE WHERE id = ? AND password_reset_required = FALSE",
+			clientID,
+		)
+	})
+}
+
+// TakeDownMapping archives a mapping whatever its status, so that it answers
+// 410 Gone for good, and records the change in its audit trail as made by the
+// admin. It returns false if the mapping does not exist; taking down a
+// mapping again changes nothing.
+func (r *AdminRepository) TakeDownMapping(adminID, mappingID int64, reason string) (bool, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var status string
+	var inactiveResponse sql.NullString
+	err = tx.QueryRow(
+		"SELECT status, inactive_response FROM redirect_mappings WHERE id = ? FOR UPDATE", mappingID,
+	).Scan(&status, &inactiveResponse)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to get redirect mapping status: %w", err)
+	}
+	if status == models.MappingStatusArchived && inactiveResponse.String == models.InactiveResponseGone {
+		return true, nil
+	}
+
+	if _, err := tx.Exec(
+		"UPDATE redirect_mappings SET status = ?, inactive_response = ? WHERE id = ?",
+		models.MappingStatusArchived, models.InactiveResponseGone, mappingID,
+	); err != nil {
+		return false, fmt.Errorf("failed to take down redirect mapping: %w", err)
+	}
+
+	if err := insertStatusChange(tx, mappingID, adminID, status, models.MappingStatusArchived, reason); err != nil {
+		return false, err
+	}
+
+	if err := insertAdminAction(tx, models.AdminAction{
+		AdminID:    adminID,
+		Action:     models.AdminActionTakedown,
+		TargetType: models.AdminTargetMapping,
+		TargetID:   mappingID,
+		Reason:     reason,
+	}); err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return true, nil
+}
+
+// change applies an update and records the action in one transaction. It
+// returns false, recording nothing, if the update changed no row.
+func (r *AdminRepository) change(action models.AdminAction, update func(tx *sql.Tx) (sql.Result, error)) (bool, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := update(tx)
+	if err != nil {
+		return false, fmt.Errorf("failed to apply %s: %w", action.Action, err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	if affected == 0 {
+		return false, nil
+	}
+
+	if err := insertAdminAction(tx, action); err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return true, nil
+}
+
+func insertAdminAction(tx *sql.Tx, action models.AdminAction) error {
+	_, err := tx.Exec(`
+		INSERT INTO admin_actions (admin_id, action, target_type, target_id, reason)
+		VALUES (?, ?, ?, ?, NULLIF(?, ''))
+	`, action.AdminID, action.Action, action.TargetType, action.TargetID, action.Reason)
+	if err != nil {
+		return fmt.Errorf("failed to record admin action: %w", err)
+	}
+	return nil
+}
+
+// GetActions returns the audit log entries matching a filter, newest first
+func (r *AdminRepository) GetActions(filter models.AdminActionFilter) ([]models.AdminAction, error) {
+	where := `1 = 1`
+	var args []interface{}
+	if filter.AdminID != 0 {
+		where += ` AND admin_id = ?`
+		args = append(args, filter.AdminID)
+	}
+	if filter.TargetType != "" {
+		where += ` AND target_type = ?`
+		args = append(args, filter.TargetType)
+	}
+	if filter.TargetID != 0 {
+		where += ` AND target_id = ?`
+		args = append(args, filter.TargetID)
+	}
+
+	query := `
+		SELECT id, admin_id, action, target_type, target_id, reason, created_at
+		FROM admin_actions
+		WHERE ` + where + `
+		ORDER BY created_at DESC, id DESC
+		LIMIT ?
+	`
+
+	rows, err := r.db.Query(query, append(args, filter.Limit)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get admin actions: %w", err)
+	}
+	defer rows.Close()
+
+	var actions []models.AdminAction
+	for rows.Next() {
+		var action models.AdminAction
+		var reason sql.NullString
+		err := rows.Scan(&action.ID, &action.AdminID, &action.Action, &action.TargetType, &action.TargetID, &reason, &action.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan admin action: %w", err)
+		}
+		action.Reason = reason.String
+		actions = append(actions, action)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate admin actions: %w", err)
+	}
+
+	return actions, nil
+}
+
+// GetUsage summarizes the platform, with clicks counted since a UTC day and
+// the top clients by clicks
+func (r *AdminRepository) GetUsage(since time.Time, top int) (*models.PlatformUsage, error) {
+	usage := &models.PlatformUsage{}
+	counts := []struct {
+		query string
+		dest  *int64
+	}{
+		{"SELECT COUNT(*) FROM clients", &usage.Clients},
+		{"SELECT COUNT(*) FROM clients WHERE suspended_at IS NOT NULL", &usage.SuspendedClients},
+		{"SELECT COUNT(*) FROM organizations", &usage.Organizations},
+	}
+	for _, count := range counts {
+		if err := r.db.QueryRow(count.query).Scan(count.dest); err != nil {
+			return nil, fmt.Errorf("failed to get platform usage: %w", err)
+		}
+	}
+
+	var err error
+	usage.Mappings, err = r.countMappings(``)
+	if err != nil {
+		return nil, err
+	}
+
+	day := since.Format("2006-01-02")
+	if err := r.db.QueryRow(
+		"SELECT COALESCE(SUM(clicks), 0) FROM click_rollups_daily WHERE bucket_date >= ?", day,
+	).Scan(&usage.Clicks); err != nil {
+		return nil, fmt.Errorf("failed to count clicks: %w", err)
+	}
+
+	rows, err := r.db.Query(`
+		SELECT r.client_id, c.username, SUM(r.clicks) AS total
+		FROM click_rollups_daily r
+		JOIN clients c ON c.id = r.client_id
+		WHERE r.bucket_date >= ?
+		GROUP BY r.client_id, c.username
+		ORDER BY total DESC
+		LIMIT ?
+	`, day, top)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get top clients: %w", err)
+	}
+	defer rows.Close()
+
+	usage.TopClients = []models.ClientClicks{}
+	for rows.Next() {
+		var clicks models.ClientClicks
+		if err := rows.Scan(&clicks.ClientID, &clicks.Username, &clicks.Clicks); err != nil {
+			return nil, fmt.Errorf("failed to scan top client: %w", err)
+		}
+		usage.TopClients = append(usage.TopClients, clicks)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate top clients: %w", err)
+	}
+
+	return usage, nil
+}
+
+// countMappings counts mappings by status
+func (r *AdminRepository) countMappings(where string, args ...interface{}) (map[string]int64, error) {
+	rows, err := r.db.Query(`SELECT status, COUNT(*) FROM redirect_mappings `+where+` GROUP BY status`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count redirect mappings: %w", err)
+	}
+	defer rows.Close()
+
+	counts := map[string]int64{}
+	for rows.Next() {
+		var status string
+		var count int64
+		if err := rows.Scan(&status, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan redirect mapping count: %w", err)
+		}
+		counts[status] = count
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate redirect mapping counts: %w", err)
+	}
+
+	return counts, nil
+}
@@ -0,0 +1,146 @@
+package mysql
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"platform/internal/models"
+	"time"
+)
+
+type APIKeyRepository struct {
+	db *sql.DB
+}
+
+func NewAPIKeyRepository(db *sql.DB) *APIKeyRepository {
+	return &APIKeyRepository{
+		db: db,
+	}
+}
+
+const apiKeyColumns = `
+	id, client_id, name, key_prefix, key_hash, scopes, expires_at, last_used_at, created_at
+`
+
+func (r *APIKeyRepository) Create(key *models.APIKey) error {
+	scopes, err := json.Marshal(key.Scopes)
+	if err != nil {
+		return fmt.Errorf("failed to marshal scopes: %w", err)
+	}
+
+	query := `
+		INSERT INTO api_keys (client_id, name, key_prefix, key_hash, scopes, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`
+
+	var expiresAt interface{}
+	if key.ExpiresAt != nil {
+		expiresAt = key.ExpiresAt.UTC()
+	}
+	result, err := r.db.Exec(query, key.ClientID, key.Name, key.Prefix, key.KeyHash, scopes, expiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to create API key: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get last insert id: %w", err)
+	}
+
+	key.ID = id
+	return nil
+}
+
+func (r *APIKeyRepository) GetClientKeys(clientID int64) ([]models.APIKey, error) {
+	query := `SELECT ` + apiKeyColumns + `
+		FROM api_keys
+		WHERE client_id = ?
+		ORDER BY created_at DESC
+	`
+	return r.queryKeys(query, clientID)
+}
+
+// GetByHash returns the API key stored under a hash, or nil if there is none
+func (r *APIKeyRepository) GetByHash(keyHash string) (*models.APIKey, error) {
+	query := `SELECT ` + apiKeyColumns + `
+		FROM api_keys
+		WHERE key_hash = ?
+	`
+
+	keys, err := r.queryKeys(query, keyHash)
+	if err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	return &keys[0], nil
+}
+
+func (r *APIKeyRepository) Delete(clientID, id int64) (bool, error) {
+	result, err := r.db.Exec("DELETE FROM api_keys WHERE client_id = ? AND id = ?", clientID, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete API key: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	return affected > 0, nil
+}
+
+// TouchLastUsed records that a key was used at now
+func (r *APIKeyRepository) TouchLastUsed(id int64, now time.Time) error {
+	if _, err := r.db.Exec("UPDATE api_keys SET last_used_at = ? WHERE id = ?", now.UTC(), id); err != nil {
+		return fmt.Errorf("failed to update API key last use: %w", err)
+	}
+	return nil
+}
+
+func (r *APIKeyRepository) queryKeys(query string, args ...interface{}) ([]models.APIKey, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get API keys: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []models.APIKey
+	for rows.Next() {
+		var key models.APIKey
+		var scopes []byte
+		var expiresAt, lastUsedAt sql.NullTime
+		err := rows.Scan(
+			&key.ID,
+			&key.ClientID,
+			&key.Name,
+			&key.Prefix,
+			&key.KeyHash,
+			&scopes,
+			&expiresAt,
+			&lastUsedAt,
+			&key.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan API key: %w", err)
+		}
+
+		if err := json.Unmarshal(scopes, &key.Scopes); err != nil {
+			return nil, fmt.Errorf("failed to decode scopes: %w", err)
+		}
+		if expiresAt.Valid {
+			key.ExpiresAt = &expiresAt.Time
+		}
+		if lastUsedAt.Valid {
+			key.LastUsedAt = &lastUsedAt.Time
+		}
+
+		keys = append(keys, key)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate API keys: %w", err)
+	}
+
+	return keys, nil
+}
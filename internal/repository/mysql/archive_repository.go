@@ -0,0 +1,210 @@
+package mysql
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"platform/internal/models"
+	"strings"
+	"time"
+)
+
+type ArchiveRepository struct {
+	db *sql.DB
+}
+
+func NewArchiveRepository(db *sql.DB) *ArchiveRepository {
+	return &ArchiveRepository{
+		db: db,
+	}
+}
+
+// archiveTimeFormat keeps the microseconds MySQL may store in DATETIME columns
+const archiveTimeFormat = "2006-01-02 15:04:05.999999"
+
+// NextDay returns the first UTC day of a table that has not been archived yet:
+// the end of the archived ranges, or the day of the oldest unarchived row. It
+// reports false when there is nothing to archive.
+func (r *ArchiveRepository) NextDay(table string) (time.Time, bool, error) {
+	until, err := archivedUntil(r.db, table)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	if until != nil {
+		return until.UTC(), true, nil
+	}
+
+	oldest, err := oldestRow(r.db, table)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	if oldest == nil {
+		return time.Time{}, false, nil
+	}
+	return oldest.UTC().Truncate(24 * time.Hour), true, nil
+}
+
+// archivedUntil returns the end of the archived ranges that run without gaps from
+// the first archived range, provided no row of the table is older than that
+// range. Ranges archived by hand after a gap do not count until the gap is filled.
+func archivedUntil(db *sql.DB, table string) (*time.Time, error) {
+	var first sql.NullTime
+	if err := db.QueryRow("SELECT MIN(range_start) FROM archive_runs WHERE table_name = ?", table).Scan(&first); err != nil {
+		return nil, fmt.Errorf("failed to get first archive run: %w", err)
+	}
+	if !first.Valid {
+		return nil, nil
+	}
+
+	oldest, err := oldestRow(db, table)
+	if err != nil {
+		return nil, err
+	}
+	if oldest != nil && oldest.Before(first.Time) {
+		return nil, nil
+	}
+
+	query := `
+		SELECT MIN(r.range_end)
+		FROM archive_runs r
+		WHERE r.table_name = ? AND NOT EXISTS (
+			SELECT 1 FROM archive_runs n WHERE n.table_name = r.table_name AND n.range_start = r.range_end
+		)
+	`
+
+	var until sql.NullTime
+	if err := db.QueryRow(query, table).Scan(&until); err != nil {
+		return nil, fmt.Errorf("failed to get archived range of %s: %w", table, err)
+	}
+	if !until.Valid {
+		return nil, nil
+	}
+	return &until.Time, nil
+}
+
+func oldestRow(db *sql.DB, table string) (*time.Time, error) {
+	def, ok := retentionTables[table]
+	if !ok {
+		return nil, fmt.Errorf("unsupported archive table %q", table)
+	}
+
+	var oldest sql.NullTime
+	if err := db.QueryRow(`SELECT MIN(` + def.timeColumn + `) FROM ` + table).Scan(&oldest); err != nil {
+		return nil, fmt.Errorf("failed to get oldest %s: %w", table, err)
+	}
+	if !oldest.Valid {
+		return nil, nil
+	}
+	return &oldest.Time, nil
+}
+
+// RecordRun stores a completed archive run
+func (r *ArchiveRepository) RecordRun(run *models.ArchiveRun) error {
+	query := `
+		INSERT INTO archive_runs (table_name, range_start, range_end, manifest_key, row_count)
+		VALUES (?, ?, ?, ?, ?)
+	`
+
+	if _, err := r.db.Exec(query, run.Table, run.RangeStart, run.RangeEnd, run.ManifestKey, run.Rows); err != nil {
+		if isDuplicateKeyError(err) {
+			return ErrDuplicate
+		}
+		return fmt.Errorf("failed to record archive run: %w", err)
+	}
+	return nil
+}
+
+// ForEachRow streams the rows of a table within [from, to) ordered by ID. Values
+// are passed as JSON-ready types: nil, int64, float64, string, UTC timestamps
+// formatted as MySQL DATETIME strings, and JSON columns as raw JSON.
+func (r *ArchiveRepository) ForEachRow(table string, from, to time.Time, fn func(columns []string, values []interface{}) error) error {
+	def, ok := retentionTables[table]
+	if !ok {
+		return fmt.Errorf("unsupported archive table %q", table)
+	}
+
+	rows, err := r.db.Query(`SELECT * FROM `+table+` WHERE `+def.timeColumn+` >= ? AND `+def.timeColumn+` < ? ORDER BY id`, from, to)
+	if err != nil {
+		return fmt.Errorf("failed to get %s rows: %w", table, err)
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return fmt.Errorf("failed to get %s columns: %w", table, err)
+	}
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		return fmt.Errorf("failed to get %s column types: %w", table, err)
+	}
+
+	raw := make([]interface{}, len(columns))
+	dest := make([]interface{}, len(columns))
+	for i := range raw {
+		dest[i] = &raw[i]
+	}
+
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return fmt.Errorf("failed to scan %s row: %w", table, err)
+		}
+
+		values := make([]interface{}, len(columns))
+		for i, v := range raw {
+			switch v := v.(type) {
+			case []byte:
+				if types[i].DatabaseTypeName() == "JSON" {
+					values[i] = json.RawMessage(append([]byte(nil), v...))
+				} else {
+					values[i] = string(v)
+				}
+			case time.Time:
+				values[i] = v.UTC().Format(archiveTimeFormat)
+			default:
+				values[i] = v
+			}
+		}
+
+		if err := fn(columns, values); err != nil {
+			return err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to iterate %s rows: %w", table, err)
+	}
+	return nil
+}
+
+// CreateScratchTable creates an empty copy of a table's structure, without its
+// foreign keys, for restoring archives into
+func (r *ArchiveRepository) CreateScratchTable(source, name string) error {
+	if _, ok := retentionTables[source]; !ok {
+		return fmt.Errorf("unsupported archive table %q", source)
+	}
+	if _, err := r.db.Exec(fmt.Sprintf("CREATE TABLE %s LIKE %s", name, source)); err != nil {
+		return fmt.Errorf("failed to create scratch table %s: %w", name, err)
+	}
+	return nil
+}
+
+// InsertRows inserts rows into a table in a single statement
+func (r *ArchiveRepository) InsertRows(table string, columns []string, rows [][]interface{}) error {
+	if len(rows) == 0 {
+		return nil
+	}
+
+	placeholder := "(" + strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ") + ")"
+	placeholders := make([]string, len(rows))
+	args := make([]interface{}, 0, len(rows)*len(columns))
+	for i, row := range rows {
+		placeholders[i] = placeholder
+		args = append(args, row...)
+	}
+
+	query := `INSERT INTO ` + table + " (`" + strings.Join(columns, "`, `") + "`) VALUES " + strings.Join(placeholders, ", ")
+	if _, err := r.db.Exec(query, args...); err != nil {
+		return fmt.Errorf("failed to insert into %s: %w", table, err)
+	}
+	return nil
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"platform/internal/models"
 	"golang.org/x/crypto/bcrypt"
+	"time"
 )
 
 type ClientRepository struct {
@@ -17,6 +18,41 @@ func NewClientRepository(db *sql.DB) *ClientRepository {
 	}
 }
 
+const clientColumns = `
+	id, username, password_hash, email, email_verified_at, is_admin, suspended_at,
+	suspended_reason, password_reset_required, created_at, updated_at
+`
+
+func scanClient(row rowScanner) (*models.Client, error) {
+	client := &models.Client{}
+	var emailVerifiedAt, suspendedAt sql.NullTime
+	var suspendedReason sql.NullString
+	err := row.Scan(
+		&client.ID,
+		&client.Username,
+		&client.PasswordHash,
+		&client.Email,
+		&emailVerifiedAt,
+		&client.IsAdmin,
+		&suspendedAt,
+		&suspendedReason,
+		&client.PasswordResetRequired,
+		&client.CreatedAt,
+		&client.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if emailVerifiedAt.Valid {
+		client.EmailVerifiedAt = &emailVerifiedAt.Time
+	}
+	if suspendedAt.Valid {
+		client.SuspendedAt = &suspendedAt.Time
+	}
+	client.SuspendedReason = suspendedReason.String
+	return client, nil
+}
+
 func (r *ClientRepository) Create(client *models.Client) error {
 	query := `
 		INSERT INTO clients (username, password_hash, email)
@@ -44,20 +80,12 @@ func (r *ClientRepository) Create(client *models.Client) error {
 
 func (r *ClientRepository) GetByUsername(username string) (*models.Client, error) {
 	query := `
-		SELECT id, username, password_hash, email, created_at, updated_at
+		SELECT ` + clientColumns + `
 		FROM clients
 		WHERE username = ?
 	`
 
-	client := &models.Client{}
-	err := r.db.QueryRow(query, username).Scan(
-		&client.ID,
-		&client.Username,
-		&client.PasswordHash,
-		&client.Email,
-		&client.CreatedAt,
-		&client.UpdatedAt,
-	)
+	client, err := scanClient(r.db.QueryRow(query, username))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
@@ -70,20 +98,48 @@ func (r *ClientRepository) GetByUsername(username string) (*models.Client, error
 
 func (r *ClientRepository) GetByID(id int64) (*models.Client, error) {
 	query := `
-		SELECT id, username, password_hash, email, created_at, updated_at
+		SELECT ` + clientColumns + `
 		FROM clients
 		WHERE id = ?
 	`
 
-	client := &models.Client{}
-	err := r.db.QueryRow(query, id).Scan(
-		&client.ID,
-		&client.Username,
-		&client.PasswordHash,
-		&client.Email,
-		&client.CreatedAt,
-		&client.UpdatedAt,
-	)
+	client, err := scanClient(r.db.QueryRow(query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get client: %w", err)
+	}
+
+	return client, nil
+}
+
+func (r *ClientRepository) GetByEmail(email string) (*models.Client, error) {
+	query := `
+		SELECT ` + clientColumns + `
+		FROM clients
+		WHERE email = ?
+	`
+
+	client, err := scanClient(r.db.QueryRow(query, email))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get client: %w", err)
+	}
+
+	return client, nil
+}
+
+func (r *ClientRepository) GetByPostbackTokenHash(tokenHash string) (*models.Client, error) {
+	query := `
+		SELECT ` + clientColumns + `
+		FROM clients
+		WHERE postback_token_hash = ?
+	`
+
+	client, err := scanClient(r.db.QueryRow(query, tokenHash))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
@@ -94,6 +150,84 @@ func (r *ClientRepository) GetByID(id int64) (*models.Client, error) {
 	return client, nil
 }
 
+// SetPostbackTokenHash replaces the client's postback token, invalidating the previous one
+func (r *ClientRepository) SetPostbackTokenHash(clientID int64, tokenHash string) error {
+	if _, err := r.db.Exec("UPDATE clients SET postback_token_hash = ? WHERE id = ?", tokenHash, clientID); err != nil {
+		return fmt.Errorf("failed to set postback token: %w", err)
+	}
+	return nil
+}
+
+// SetPasswordHash replaces the client's password. A reset required by an admin
+// is only cleared by ResetPassword.
+func (r *ClientRepository) SetPasswordHash(clientID int64, passwordHash string) error {
+	_, err := r.db.Exec(
+		"UPDATE clients SET password_hash = ? WHERE id = ?",
+		passwordHash, clientID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to set password: %w", err)
+	}
+	return nil
+}
+
+// VerifyEmail uses an email verification token and marks the client's email
+// address verified. It returns false, changing nothing, if the token was used
+// already.
+func (r *ClientRepository) VerifyEmail(token *models.AccountToken) (bool, error) {
+	return r.useToken(token, func(tx *sql.Tx) error {
+		_, err := tx.Exec(
+			"UPDATE clients SET email_verified_at = COALESCE(email_verified_at, ?) WHERE id = ?",
+			time.Now().UTC(), token.ClientID,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to verify email: %w", err)
+		}
+		return nil
+	})
+}
+
+// ResetPassword uses a password reset token and replaces the client's
+// password, clearing a required reset. Receiving the token proves the email
+// address, so it is marked verified too. It returns false, changing nothing,
+// if the token was used already.
+func (r *ClientRepository) ResetPassword(token *models.AccountToken, passwordHash string) (bool, error) {
+	return r.useToken(token, func(tx *sql.Tx) error {
+		_, err := tx.Exec(`
+			UPDATE clients
+			SET password_hash = ?, password_reset_required = FALSE,
+				email_verified_at = COALESCE(email_verified_at, ?)
+			WHERE id = ?
+		`, passwordHash, time.Now().UTC(), token.ClientID)
+		if err != nil {
+			return fmt.Errorf("failed to reset password: %w", err)
+		}
+		return nil
+	})
+}
+
+func (r *ClientRepository) useToken(token *models.AccountToken, apply func(tx *sql.Tx) error) (bool, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	used, err := useAccountToken(tx, token.ID)
+	if err != nil || !used {
+		return false, err
+	}
+
+	if err := apply(tx); err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return true, nil
+}
+
 func (r *ClientRepository) ValidatePassword(client *models.Client, password string) bool {
 	err := bcrypt.CompareHashAndPassword([]byte(client.PasswordHash), []byte(password))
 	return err == nil
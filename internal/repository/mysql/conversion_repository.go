@@ -0,0 +1,198 @@
+package mysql
+
+import (
+	"database/sql"
+	"fmt"
+	"platform/internal/models"
+	"strings"
+	"time"
+)
+
+type ConversionRepository struct {
+	db *sql.DB
+}
+
+func NewConversionRepository(db *sql.DB) *ConversionRepository {
+	return &ConversionRepository{
+		db: db,
+	}
+}
+
+// FindClick returns the latest click with the given click_id on one of the
+// client's personal mappings, or on a mapping of an organization the client may
+// edit the mappings of
+func (r *ConversionRepository) FindClick(clientID int64, clickID string) (*models.ConvertedClick, error) {
+	roles := models.RolesAllowing(models.PermissionEditMappings)
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(roles)), ", ")
+
+	query := `
+		SELECT h.id, h.mapping_id, h.organization_id, h.variant
+		FROM redirect_history h
+		LEFT JOIN organization_members om ON om.organization_id = h.organization_id AND om.client_id = ?
+		WHERE h.click_id = ?
+			AND ((h.organization_id IS NULL AND h.client_id = ?) OR om.role IN (` + placeholders + `))
+		ORDER BY h.redirect_timestamp DESC, h.id DESC
+		LIMIT 1
+	`
+
+	args := []interface{}{clientID, clickID, clientID}
+	for _, role := range roles {
+		args = append(args, role)
+	}
+
+	click := &models.ConvertedClick{}
+	var organizationID sql.NullInt64
+	err := r.db.QueryRow(query, args...).Scan(&click.RedirectHistoryID, &click.MappingID, &organizationID, &click.Variant)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to find click: %w", err)
+	}
+	if organizationID.Valid {
+		click.OrganizationID = &organizationID.Int64
+	}
+
+	return click, nil
+}
+
+// Create stores a conversion, returning ErrDuplicate if the click already
+// converted with the same event name. Conversions of clicks on an organization's
+// mappings are deduplicated for the organization, whichever member posted them.
+func (r *ConversionRepository) Create(conversion *models.Conversion) error {
+	query := `
+		INSERT INTO conversions (
+			client_id, organization_id, mapping_id, redirect_history_id, click_id, event_name,
+			variant, payout, currency, ip_address
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, NULLIF(?, ''), ?)
+	`
+
+	result, err := r.db.Exec(
+		query,
+		conversion.ClientID,
+		conversion.OrganizationID,
+		conversion.MappingID,
+		conversion.RedirectHistoryID,
+		conversion.ClickID,
+		conversion.EventName,
+		conversion.Variant,
+		conversion.Payout,
+		conversion.Currency,
+		conversion.IPAddress,
+	)
+	if err != nil {
+		if isDuplicateKeyError(err) {
+			return ErrDuplicate
+		}
+		return fmt.Errorf("failed to create conversion: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get last insert id: %w", err)
+	}
+
+	conversion.ID = id
+	return nil
+}
+
+// GetStats returns clicks, conversions and payouts per mapping and variant
+// within the scope for clicks and conversions that happened within [from, to).
+// Clicks classified as bots are only counted when includeBots is set.
+func (r *ConversionRepository) GetStats(scope models.Scope, from, to time.Time, includeBots bool) ([]models.ConversionStats, error) {
+	type statsKey struct {
+		mappingID int64
+		variant   string
+	}
+	stats := make(map[statsKey]*models.ConversionStats)
+	var order []statsKey
+
+	get := func(key statsKey, hash string) *models.ConversionStats {
+		s, ok := stats[key]
+		if !ok {
+			s = &models.ConversionStats{
+				MappingID: key.mappingID,
+				Hash:      hash,
+				Variant:   key.variant,
+				Payouts:   map[string]float64{},
+			}
+			stats[key] = s
+			order = append(order, key)
+		}
+		return s
+	}
+
+	clicksWhere, clicksArgs := scopeConditionOn("h.", scope)
+	clicksQuery := `
+		SELECT m.id, m.hash, h.variant, COUNT(*)
+		FROM redirect_history h
+		JOIN redirect_mappings m ON m.id = h.mapping_id
+		WHERE ` + clicksWhere + ` AND h.redirect_timestamp >= ? AND h.redirect_timestamp < ?
+			AND (? OR h.is_bot = FALSE)
+		GROUP BY m.id, m.hash, h.variant
+		ORDER BY m.id, h.variant
+	`
+
+	rows, err := r.db.Query(clicksQuery, append(clicksArgs, from, to, includeBots)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get click counts: %w", err)
+	}
+	for rows.Next() {
+		var key statsKey
+		var hash string
+		var clicks int64
+		if err := rows.Scan(&key.mappingID, &hash, &key.variant, &clicks); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan click counts: %w", err)
+		}
+		get(key, hash).Clicks = clicks
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate click counts: %w", err)
+	}
+
+	conversionsWhere, conversionsArgs := scopeConditionOn("c.", scope)
+	conversionsQuery := `
+		SELECT c.mapping_id, m.hash, c.variant, COALESCE(c.currency, ''), COUNT(*), COALESCE(SUM(c.payout), 0)
+		FROM conversions c
+		JOIN redirect_mappings m ON m.id = c.mapping_id
+		WHERE ` + conversionsWhere + ` AND c.created_at >= ? AND c.created_at < ?
+		GROUP BY c.mapping_id, m.hash, c.variant, c.currency
+	`
+
+	rows, err = r.db.Query(conversionsQuery, append(conversionsArgs, from, to)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get conversion counts: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var key statsKey
+		var hash, currency string
+		var conversions int64
+		var payout float64
+		if err := rows.Scan(&key.mappingID, &hash, &key.variant, &currency, &conversions, &payout); err != nil {
+			return nil, fmt.Errorf("failed to scan conversion counts: %w", err)
+		}
+
+		s := get(key, hash)
+		s.Conversions += conversions
+		if payout != 0 {
+			s.Payouts[currency] += payout
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate conversion counts: %w", err)
+	}
+
+	result := make([]models.ConversionStats, 0, len(order))
+	for _, key := range order {
+		s := stats[key]
+		if s.Clicks > 0 {
+			s.ConversionRate = float64(s.Conversions) / float64(s.Clicks)
+		}
+		result = append(result, *s)
+	}
+
+	return result, nil
+}
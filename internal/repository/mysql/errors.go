@@ -0,0 +1,25 @@
+package mysql
+
+import (
+	"errors"
+	mysqldriver "github.com/go-sql-driver/mysql"
+)
+
+// ErrDuplicate is returned when an insert violates a unique index
+var ErrDuplicate = errors.New("duplicate entry")
+
+// mysqlErrDuplicateEntry is MySQL's ER_DUP_ENTRY error number
+const mysqlErrDuplicateEntry = 1062
+
+func isDuplicateKeyError(err error) bool {
+	var mysqlErr *mysqldriver.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlErrDuplicateEntry
+}
+
+// ErrLastOwner is returned when a change would leave an organization without
+// an owner
+var ErrLastOwner = errors.New("organization must keep an owner")
+
+// ErrOrganizationNotEmpty is returned when deleting an organization that still
+// owns mappings
+var ErrOrganizationNotEmpty = errors.New("organization still owns mappings")
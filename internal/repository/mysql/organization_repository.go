@@ -0,0 +1,391 @@
+package mysql
+
+import (
+	"database/sql"
+	"fmt"
+	"platform/internal/models"
+	"time"
+)
+
+type OrganizationRepository struct {
+	db *sql.DB
+}
+
+func NewOrganizationRepository(db *sql.DB) *OrganizationRepository {
+	return &OrganizationRepository{
+		db: db,
+	}
+}
+
+// Create stores an organization with its creator as owner
+func (r *OrganizationRepository) Create(org *models.Organization) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec("INSERT INTO organizations (name, created_by) VALUES (?, ?)", org.Name, org.CreatedBy)
+	if err != nil {
+		return fmt.Errorf("failed to create organization: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get last insert id: %w", err)
+	}
+
+	if _, err := tx.Exec(
+		"INSERT INTO organization_members (organization_id, client_id, role) VALUES (?, ?, ?)",
+		id, org.CreatedBy, models.OrgRoleOwner,
+	); err != nil {
+		return fmt.Errorf("failed to add organization owner: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	org.ID = id
+	org.Role = models.OrgRoleOwner
+	return nil
+}
+
+// GetClientOrganizations returns the organizations a client is a member of,
+// with its role in each
+func (r *OrganizationRepository) GetClientOrganizations(clientID int64) ([]models.Organization, error) {
+	query := `
+		SELECT o.id, o.name, o.created_by, o.created_at, m.role
+		FROM organizations o
+		JOIN organization_members m ON m.organization_id = o.id
+		WHERE m.client_id = ?
+		ORDER BY o.name
+	`
+
+	rows, err := r.db.Query(query, clientID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get organizations: %w", err)
+	}
+	defer rows.Close()
+
+	var orgs []models.Organization
+	for rows.Next() {
+		var org models.Organization
+		if err := rows.Scan(&org.ID, &org.Name, &org.CreatedBy, &org.CreatedAt, &org.Role); err != nil {
+			return nil, fmt.Errorf("failed to scan organization: %w", err)
+		}
+		orgs = append(orgs, org)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate organizations: %w", err)
+	}
+
+	return orgs, nil
+}
+
+// Delete deletes an organization with its members and invitations, returning
+// ErrOrganizationNotEmpty while it still owns mappings
+func (r *OrganizationRepository) Delete(id int64) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var owned int
+	if err := tx.QueryRow(
+		"SELECT COUNT(*) FROM redirect_mappings WHERE organization_id = ? FOR UPDATE", id,
+	).Scan(&owned); err != nil {
+		return fmt.Errorf("failed to count organization mappings: %w", err)
+	}
+	if owned > 0 {
+		return ErrOrganizationNotEmpty
+	}
+
+	if _, err := tx.Exec("DELETE FROM organizations WHERE id = ?", id); err != nil {
+		return fmt.Errorf("failed to delete organization: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// GetMember returns a client's membership of an organization, or nil if it is
+// not a member
+func (r *OrganizationRepository) GetMember(orgID, clientID int64) (*models.OrganizationMember, error) {
+	members, err := r.queryMembers("WHERE m.organization_id = ? AND m.client_id = ?", orgID, clientID)
+	if err != nil {
+		return nil, err
+	}
+	if len(members) == 0 {
+		return nil, nil
+	}
+	return &members[0], nil
+}
+
+func (r *OrganizationRepository) GetMembers(orgID int64) ([]models.OrganizationMember, error) {
+	return r.queryMembers("WHERE m.organization_id = ? ORDER BY m.created_at", orgID)
+}
+
+func (r *OrganizationRepository) queryMembers(where string, args ...interface{}) ([]models.OrganizationMember, error) {
+	query := `
+		SELECT m.organization_id, m.client_id, c.username, c.email, m.role, m.created_at
+		FROM organization_members m
+		JOIN clients c ON c.id = m.client_id
+	` + where
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get organization members: %w", err)
+	}
+	defer rows.Close()
+
+	var members []models.OrganizationMember
+	for rows.Next() {
+		var member models.OrganizationMember
+		err := rows.Scan(&member.OrganizationID, &member.ClientID, &member.Username, &member.Email, &member.Role, &member.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan organization member: %w", err)
+		}
+		members = append(members, member)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate organization members: %w", err)
+	}
+
+	return members, nil
+}
+
+// SetMemberRole changes a member's role. It returns false if the client is not
+// a member and ErrLastOwner if it is the last owner and would lose the role.
+func (r *OrganizationRepository) SetMemberRole(orgID, clientID int64, role string) (bool, error) {
+	return r.changeMember(orgID, clientID, role != models.OrgRoleOwner, func(tx *sql.Tx) error {
+		_, err := tx.Exec(
+			"UPDATE organization_members SET role = ? WHERE organization_id = ? AND client_id = ?",
+			role, orgID, clientID,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to update organization member: %w", err)
+		}
+		return nil
+	})
+}
+
+// RemoveMember removes a member. It returns false if the client is not a
+// member and ErrLastOwner if it is the last owner.
+func (r *OrganizationRepository) RemoveMember(orgID, clientID int64) (bool, error) {
+	return r.changeMember(orgID, clientID, true, func(tx *sql.Tx) error {
+		_, err := tx.Exec("DELETE FROM organization_members WHERE organization_id = ? AND client_id = ?", orgID, clientID)
+		if err != nil {
+			return fmt.Errorf("failed to remove organization member: %w", err)
+		}
+		return nil
+	})
+}
+
+// changeMember applies a change to a member with the organization's owners
+// locked, refusing it if the member is the last owner and loses ownership
+func (r *OrganizationRepository) changeMember(orgID, clientID int64, losesOwnership bool, change func(tx *sql.Tx) error) (bool, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(
+		"SELECT client_id, role FROM organization_members WHERE organization_id = ? FOR UPDATE", orgID,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to get organization members: %w", err)
+	}
+
+	found, isOwner, owners := false, false, 0
+	for rows.Next() {
+		var memberID int64
+		var role string
+		if err := rows.Scan(&memberID, &role); err != nil {
+			rows.Close()
+			return false, fmt.Errorf("failed to scan organization member: %w", err)
+		}
+		if role == models.OrgRoleOwner {
+			owners++
+		}
+		if memberID == clientID {
+			found, isOwner = true, role == models.OrgRoleOwner
+		}
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return false, fmt.Errorf("failed to iterate organization members: %w", err)
+	}
+	rows.Close()
+
+	if !found {
+		return false, nil
+	}
+	if isOwner && losesOwnership && owners == 1 {
+		return false, ErrLastOwner
+	}
+
+	if err := change(tx); err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return true, nil
+}
+
+const invitationColumns = `
+	id, organization_id, email, role, token_hash, invited_by, expires_at, accepted_at, created_at
+`
+
+func (r *OrganizationRepository) CreateInvitation(invitation *models.OrganizationInvitation) error {
+	query := `
+		INSERT INTO organization_invitations (organization_id, email, role, token_hash, invited_by, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`
+
+	result, err := r.db.Exec(query, invitation.OrganizationID, invitation.Email, invitation.Role,
+		invitation.TokenHash, invitation.InvitedBy, invitation.ExpiresAt.UTC())
+	if err != nil {
+		return fmt.Errorf("failed to create organization invitation: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get last insert id: %w", err)
+	}
+
+	invitation.ID = id
+	return nil
+}
+
+// GetPendingInvitations returns an organization's invitations that were
+// neither accepted nor expired
+func (r *OrganizationRepository) GetPendingInvitations(orgID int64) ([]models.OrganizationInvitation, error) {
+	query := `SELECT ` + invitationColumns + `
+		FROM organization_invitations
+		WHERE organization_id = ? AND accepted_at IS NULL AND expires_at > ?
+		ORDER BY created_at DESC
+	`
+	return r.queryInvitations(query, orgID, time.Now().UTC())
+}
+
+// GetInvitationByHash returns the invitation with a token hash, or nil if
+// there is none
+func (r *OrganizationRepository) GetInvitationByHash(tokenHash string) (*models.OrganizationInvitation, error) {
+	query := `SELECT ` + invitationColumns + `
+		FROM organization_invitations
+		WHERE token_hash = ?
+	`
+
+	invitations, err := r.queryInvitations(query, tokenHash)
+	if err != nil {
+		return nil, err
+	}
+	if len(invitations) == 0 {
+		return nil, nil
+	}
+	return &invitations[0], nil
+}
+
+func (r *OrganizationRepository) DeleteInvitation(orgID, id int64) (bool, error) {
+	result, err := r.db.Exec(
+		"DELETE FROM organization_invitations WHERE organization_id = ? AND id = ? AND accepted_at IS NULL", orgID, id,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete organization invitation: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	return affected > 0, nil
+}
+
+// AcceptInvitation marks an invitation accepted and adds the client as a
+// member with the invited role. It returns false if the invitation was
+// accepted in the meantime and ErrDuplicate if the client is already a member.
+func (r *OrganizationRepository) AcceptInvitation(invitation *models.OrganizationInvitation, clientID int64) (bool, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(
+		"UPDATE organization_invitations SET accepted_at = ? WHERE id = ? AND accepted_at IS NULL",
+		time.Now().UTC(), invitation.ID,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to accept organization invitation: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	if affected == 0 {
+		return false, nil
+	}
+
+	if _, err := tx.Exec(
+		"INSERT INTO organization_members (organization_id, client_id, role) VALUES (?, ?, ?)",
+		invitation.OrganizationID, clientID, invitation.Role,
+	); err != nil {
+		if isDuplicateKeyError(err) {
+			return false, ErrDuplicate
+		}
+		return false, fmt.Errorf("failed to add organization member: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return true, nil
+}
+
+func (r *OrganizationRepository) queryInvitations(query string, args ...interface{}) ([]models.OrganizationInvitation, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get organization invitations: %w", err)
+	}
+	defer rows.Close()
+
+	var invitations []models.OrganizationInvitation
+	for rows.Next() {
+		var invitation models.OrganizationInvitation
+		var acceptedAt sql.NullTime
+		err := rows.Scan(
+			&invitation.ID,
+			&invitation.OrganizationID,
+			&invitation.Email,
+			&invitation.Role,
+			&invitation.TokenHash,
+			&invitation.InvitedBy,
+			&invitation.ExpiresAt,
+			&acceptedAt,
+			&invitation.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan organization invitation: %w", err)
+		}
+		if acceptedAt.Valid {
+			invitation.AcceptedAt = &acceptedAt.Time
+		}
+		invitations = append(invitations, invitation)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate organization invitations: %w", err)
+	}
+
+	return invitations, nil
+}
@@ -0,0 +1,154 @@
+package mysql
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"platform/internal/models"
+)
+
+type PrivacyRepository struct {
+	db *sql.DB
+}
+
+func NewPrivacyRepository(db *sql.DB) *PrivacyRepository {
+	return &PrivacyRepository{
+		db: db,
+	}
+}
+
+// GetSettings returns a client's privacy settings, or nil if the client uses the platform policy
+func (r *PrivacyRepository) GetSettings(clientID int64) (*models.PrivacySettings, error) {
+	query := `
+		SELECT client_id, header_mode, headers, ip_mode, updated_at
+		FROM client_privacy_settings
+		WHERE client_id = ?
+	`
+
+	settings := &models.PrivacySettings{}
+	var headerMode, ipMode sql.NullString
+	var headers []byte
+	err := r.db.QueryRow(query, clientID).Scan(&settings.ClientID, &headerMode, &headers, &ipMode, &settings.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get privacy settings: %w", err)
+	}
+
+	if headerMode.Valid {
+		settings.HeaderMode = &headerMode.String
+	}
+	if ipMode.Valid {
+		settings.IPMode = &ipMode.String
+	}
+	if headers != nil {
+		if err := json.Unmarshal(headers, &settings.Headers); err != nil {
+			return nil, fmt.Errorf("failed to decode privacy headers: %w", err)
+		}
+	}
+	return settings, nil
+}
+
+// SetSettings creates or replaces a client's privacy settings
+func (r *PrivacyRepository) SetSettings(clientID int64, update *models.PrivacySettingsUpdate) error {
+	var headers interface{}
+	if update.Headers != nil {
+		encoded, err := json.Marshal(update.Headers)
+		if err != nil {
+			return fmt.Errorf("failed to encode privacy headers: %w", err)
+		}
+		headers = encoded
+	}
+
+	query := `
+		INSERT INTO client_privacy_settings (client_id, header_mode, headers, ip_mode)
+		VALUES (?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE
+			header_mode = VALUES(header_mode),
+			headers = VALUES(headers),
+			ip_mode = VALUES(ip_mode)
+	`
+
+	if _, err := r.db.Exec(query, clientID, update.HeaderMode, headers, update.IPMode); err != nil {
+		return fmt.Errorf("failed to set privacy settings: %w", err)
+	}
+	return nil
+}
+
+// DeleteSettings removes a client's privacy settings, reporting whether they existed
+func (r *PrivacyRepository) DeleteSettings(clientID int64) (bool, error) {
+	result, err := r.db.Exec("DELETE FROM client_privacy_settings WHERE client_id = ?", clientID)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete privacy settings: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	return affected > 0, nil
+}
+
+// GetRequestLogs returns up to limit request logs with IDs above afterID, in ID
+// order, carrying the fields the privacy policy applies to
+func (r *PrivacyRepository) GetRequestLogs(afterID int64, limit int) ([]models.Request, error) {
+	query := `
+		SELECT id, COALESCE(client_id, 0), COALESCE(organization_id, 0), ip_address, request_headers
+		FROM request_logs
+		WHERE id > ?
+		ORDER BY id
+		LIMIT ?
+	`
+
+	rows, err := r.db.Query(query, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get request logs: %w", err)
+	}
+	defer rows.Close()
+
+	var requests []models.Request
+	for rows.Next() {
+		var request models.Request
+		if err := rows.Scan(&request.ID, &request.ClientID, &request.OrganizationID, &request.IPAddress, &request.RequestHeaders); err != nil {
+			return nil, fmt.Errorf("failed to scan request log: %w", err)
+		}
+		requests = append(requests, request)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate request logs: %w", err)
+	}
+
+	return requests, nil
+}
+
+// UpdateRequestLogs rewrites the IP address and headers of request logs in one transaction
+func (r *PrivacyRepository) UpdateRequestLogs(requests []models.Request) error {
+	if len(requests) == 0 {
+		return nil
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare("UPDATE request_logs SET ip_address = ?, request_headers = ? WHERE id = ?")
+	if err != nil {
+		return fmt.Errorf("failed to prepare request log update: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, request := range requests {
+		if _, err := stmt.Exec(request.IPAddress, request.RequestHeaders, request.ID); err != nil {
+			return fmt.Errorf("failed to update request log %d: %w", request.ID, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
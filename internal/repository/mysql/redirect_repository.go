@@ -2,9 +2,12 @@ package mysql
 
 import (
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"math/rand"
 	"platform/internal/models"
+	"strings"
+	"time"
 )
 
 type RedirectRepository struct {
@@ -17,44 +20,166 @@ func NewRedirectRepository(db *sql.DB) *RedirectRepository {
 	}
 }
 
-func (r *RedirectRepository) GetRedirectURL(hash string) (string, error) {
-	query := `
-		SELECT redirect_url 
-		FROM redirect_mappings 
+const redirectMappingColumns = `
+	id, client_id, organization_id, hash, redirect_url, redirect_url_black,
+	utm_source, utm_medium, utm_campaign, utm_term, utm_content, utm_policy,
+	forward_policy, forward_params, forward_renames, status, inactive_response,
+	created_at, updated_at
+`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanRedirectMapping(row rowScanner) (*models.RedirectMapping, error) {
+	mapping := &models.RedirectMapping{}
+	var utmSource, utmMedium, utmCampaign, utmTerm, utmContent, utmPolicy, inactiveResponse sql.NullString
+	var forwardParams, forwardRenames []byte
+	var organizationID sql.NullInt64
+	err := row.Scan(
+		&mapping.ID,
+		&mapping.ClientID,
+		&organizationID,
+		&mapping.Hash,
+		&mapping.RedirectURL,
+		&mapping.RedirectURLBlack,
+		&utmSource,
+		&utmMedium,
+		&utmCampaign,
+		&utmTerm,
+		&utmContent,
+		&utmPolicy,
+		&mapping.Forwarding.Policy,
+		&forwardParams,
+		&forwardRenames,
+		&mapping.Status,
+		&inactiveResponse,
+		&mapping.CreatedAt,
+		&mapping.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	mapping.UTM = models.UTMParams{
+		Source:   utmSource.String,
+		Medium:   utmMedium.String,
+		Campaign: utmCampaign.String,
+		Term:     utmTerm.String,
+		Content:  utmContent.String,
+	}
+	if organizationID.Valid {
+		mapping.OrganizationID = &organizationID.Int64
+	}
+	mapping.UTMPolicy = utmPolicy.String
+	mapping.InactiveResponse = inactiveResponse.String
+	if len(forwardParams) > 0 {
+		if err := json.Unmarshal(forwardParams, &mapping.Forwarding.Params); err != nil {
+			return nil, fmt.Errorf("failed to decode forward params: %w", err)
+		}
+	}
+	if len(forwardRenames) > 0 {
+		if err := json.Unmarshal(forwardRenames, &mapping.Forwarding.Renames); err != nil {
+			return nil, fmt.Errorf("failed to decode forward renames: %w", err)
+		}
+	}
+	return mapping, nil
+}
+
+func (r *RedirectRepository) GetMappingByHash(hash string) (*models.RedirectMapping, error) {
+	query := `SELECT ` + redirectMappingColumns + `
+		FROM redirect_mappings
 		WHERE hash = ?
 	`
 
-	var redirectURL string
-	err := r.db.QueryRow(query, hash).Scan(&redirectURL)
+	mapping, err := scanRedirectMapping(r.db.QueryRow(query, hash))
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return "", nil
+			return nil, nil
 		}
-		return "", fmt.Errorf("failed to get redirect URL: %w", err)
+		return nil, fmt.Errorf("failed to get redirect mapping: %w", err)
 	}
 
-	return redirectURL, nil
+	return mapping, nil
+}
+
+// scopeCondition restricts mappings to an organization's, or to the client's
+// personal mappings when the scope has no organization
+func scopeCondition(scope models.Scope) (string, []interface{}) {
+	return scopeConditionOn("", scope)
+}
+
+// scopeConditionOn is scopeCondition for the table with the given alias
+// prefix ("h." or ""). It also restricts the tables that record the client and
+// organization of each click's mapping to the clicks within the scope.
+func scopeConditionOn(prefix string, scope models.Scope) (string, []interface{}) {
+	if scope.OrganizationID != 0 {
+		return prefix + `organization_id = ?`, []interface{}{scope.OrganizationID}
+	}
+	return prefix + `client_id = ? AND ` + prefix + `organization_id IS NULL`, []interface{}{scope.ClientID}
 }
 
-func (r *RedirectRepository) CreateRedirectMapping(clientID int64, mapping *models.RedirectMapping) error {
+// GetScopedMapping returns a mapping only if it is within the scope
+func (r *RedirectRepository) GetScopedMapping(scope models.Scope, id int64) (*models.RedirectMapping, error) {
+	where, args := scopeCondition(scope)
+	query := `SELECT ` + redirectMappingColumns + `
+		FROM redirect_mappings
+		WHERE id = ? AND ` + where
+
+	mapping, err := scanRedirectMapping(r.db.QueryRow(query, append([]interface{}{id}, args...)...))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get redirect mapping: %w", err)
+	}
+
+	return mapping, nil
+}
+
+// CreateRedirectMapping stores a new mapping created by the scope's client,
+// owned by the scope's organization if it has one, and the status it starts
+// in as the first entry of its audit trail
+func (r *RedirectRepository) CreateRedirectMapping(scope models.Scope, mapping *models.RedirectMapping) error {
 	// Generate a unique 6-character hash
 	hash := r.generateUniqueHash()
 	mapping.Hash = hash
-	mapping.ClientID = clientID
+	mapping.ClientID = scope.ClientID
+	mapping.OrganizationID = nil
+	if scope.OrganizationID != 0 {
+		organizationID := scope.OrganizationID
+		mapping.OrganizationID = &organizationID
+	}
 
 	query := `
 		INSERT INTO redirect_mappings (
-			client_id, hash, redirect_url, redirect_url_black
-		) VALUES (?, ?, ?, ?)
+			client_id, organization_id, hash, redirect_url, redirect_url_black,
+			utm_source, utm_medium, utm_campaign, utm_term, utm_content, utm_policy,
+			forward_policy, forward_params, forward_renames, status, inactive_response
+		) VALUES (?, ?, ?, ?, ?, ` + utmPlaceholders + `, ?, ?, ?, ?, NULLIF(?, ''))
 	`
 
-	result, err := r.db.Exec(
-		query,
+	forwardArgs, err := forwardingArgs(mapping.Forwarding)
+	if err != nil {
+		return err
+	}
+	args := []interface{}{
 		mapping.ClientID,
+		mapping.OrganizationID,
 		mapping.Hash,
 		mapping.RedirectURL,
 		mapping.RedirectURLBlack,
-	)
+	}
+	args = append(args, utmArgs(mapping.UTM, mapping.UTMPolicy)...)
+	args = append(args, forwardArgs...)
+	args = append(args, mapping.Status, mapping.InactiveResponse)
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(query, args...)
 	if err != nil {
 		return fmt.Errorf("failed to create redirect mapping: %w", err)
 	}
@@ -64,19 +189,192 @@ func (r *RedirectRepository) CreateRedirectMapping(clientID int64, mapping *mode
 		return fmt.Errorf("failed to get last insert id: %w", err)
 	}
 
+	if err := insertStatusChange(tx, id, scope.ClientID, "", mapping.Status, ""); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
 	mapping.ID = id
 	return nil
 }
 
-func (r *RedirectRepository) GetClientRedirectMappings(clientID int64) ([]models.RedirectMapping, error) {
+// TransitionMapping moves a mapping within the scope from one status to
+// another and records the change as made by the scope's client, replacing its
+// inactive response if one is given. It returns false, changing nothing, if
+// the mapping is no longer in the from status.
+func (r *RedirectRepository) TransitionMapping(scope models.Scope, id int64, from, to string, request *models.MappingTransitionRequest) (bool, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	where, scopeArgs := scopeCondition(scope)
+	args := append([]interface{}{to, request.InactiveResponse, id}, scopeArgs...)
+	result, err := tx.Exec(`
+		UPDATE redirect_mappings
+		SET status = ?, inactive_response = COALESCE(NULLIF(?, ''), inactive_response)
+		WHERE id = ? AND `+where+` AND status = ?
+	`, append(args, from)...)
+	if err != nil {
+		return false, fmt.Errorf("failed to update redirect mapping status: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	if affected == 0 {
+		return false, nil
+	}
+
+	if err := insertStatusChange(tx, id, scope.ClientID, from, to, request.Reason); err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return true, nil
+}
+
+func insertStatusChange(tx *sql.Tx, mappingID, clientID int64, from, to, reason string) error {
+	_, err := tx.Exec(`
+		INSERT INTO mapping_status_changes (mapping_id, client_id, from_status, to_status, reason)
+		VALUES (?, ?, NULLIF(?, ''), ?, NULLIF(?, ''))
+	`, mappingID, clientID, from, to, reason)
+	if err != nil {
+		return fmt.Errorf("failed to record mapping status change: %w", err)
+	}
+	return nil
+}
+
+// GetStatusChanges returns the audit trail of a mapping, newest first
+func (r *RedirectRepository) GetStatusChanges(mappingID int64, limit int) ([]models.MappingStatusChange, error) {
+	query := `
+		SELECT id, mapping_id, client_id, from_status, to_status, reason, created_at
+		FROM mapping_status_changes
+		WHERE mapping_id = ?
+		ORDER BY created_at DESC, id DESC
+		LIMIT ?
+	`
+
+	rows, err := r.db.Query(query, mappingID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get mapping status changes: %w", err)
+	}
+	defer rows.Close()
+
+	var changes []models.MappingStatusChange
+	for rows.Next() {
+		var change models.MappingStatusChange
+		var from, reason sql.NullString
+		err := rows.Scan(&change.ID, &change.MappingID, &change.ClientID, &from, &change.ToStatus, &reason, &change.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan mapping status change: %w", err)
+		}
+		change.FromStatus = from.String
+		change.Reason = reason.String
+		changes = append(changes, change)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate mapping status changes: %w", err)
+	}
+
+	return changes, nil
+}
+
+// UpdateMappingUTM replaces the UTM parameters and policy of a mapping within the scope
+func (r *RedirectRepository) UpdateMappingUTM(scope models.Scope, id int64, update *models.MappingUTMUpdate) error {
+	where, scopeArgs := scopeCondition(scope)
+	query := `
+		UPDATE redirect_mappings
+		SET utm_source = NULLIF(?, ''), utm_medium = NULLIF(?, ''), utm_campaign = NULLIF(?, ''),
+			utm_term = NULLIF(?, ''), utm_content = NULLIF(?, ''), utm_policy = NULLIF(?, '')
+		WHERE id = ? AND ` + where
+
+	args := append(utmArgs(update.UTM, update.UTMPolicy), id)
+	args = append(args, scopeArgs...)
+	if _, err := r.db.Exec(query, args...); err != nil {
+		return fmt.Errorf("failed to update redirect mapping utm: %w", err)
+	}
+	return nil
+}
+
+// utmPlaceholders stores empty UTM values and policies as NULL
+const utmPlaceholders = `NULLIF(?, ''), NULLIF(?, ''), NULLIF(?, ''), NULLIF(?, ''), NULLIF(?, ''), NULLIF(?, '')`
+
+func utmArgs(params models.UTMParams, policy string) []interface{} {
+	return []interface{}{params.Source, params.Medium, params.Campaign, params.Term, params.Content, policy}
+}
+
+// UpdateMappingForwarding replaces the query forwarding policy of a mapping within the scope
+func (r *RedirectRepository) UpdateMappingForwarding(scope models.Scope, id int64, forwarding *models.QueryForwarding) error {
+	where, scopeArgs := scopeCondition(scope)
 	query := `
-		SELECT id, client_id, hash, redirect_url, redirect_url_black, created_at, updated_at
+		UPDATE redirect_mappings
+		SET forward_policy = ?, forward_params = ?, forward_renames = ?
+		WHERE id = ? AND ` + where
+
+	args, err := forwardingArgs(*forwarding)
+	if err != nil {
+		return err
+	}
+	args = append(args, id)
+	if _, err := r.db.Exec(query, append(args, scopeArgs...)...); err != nil {
+		return fmt.Errorf("failed to update redirect mapping forwarding: %w", err)
+	}
+	return nil
+}
+
+// forwardingArgs stores an empty allowlist or rename map as NULL
+func forwardingArgs(forwarding models.QueryForwarding) ([]interface{}, error) {
+	var params, renames []byte
+	var err error
+	if len(forwarding.Params) > 0 {
+		if params, err = json.Marshal(forwarding.Params); err != nil {
+			return nil, fmt.Errorf("failed to marshal forward params: %w", err)
+		}
+	}
+	if len(forwarding.Renames) > 0 {
+		if renames, err = json.Marshal(forwarding.Renames); err != nil {
+			return nil, fmt.Errorf("failed to marshal forward renames: %w", err)
+		}
+	}
+	return []interface{}{forwarding.Policy, params, renames}, nil
+}
+
+// CountClientMappings counts the mappings a client created, in any status
+func (r *RedirectRepository) CountClientMappings(clientID int64) (int, error) {
+	var count int
+	if err := r.db.QueryRow("SELECT COUNT(*) FROM redirect_mappings WHERE client_id = ?", clientID).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count redirect mappings: %w", err)
+	}
+	return count, nil
+}
+
+// GetScopedRedirectMappings returns the mappings within the scope, newest
+// first. Only mappings in one of statuses are returned unless statuses is empty.
+func (r *RedirectRepository) GetScopedRedirectMappings(scope models.Scope, statuses []string) ([]models.RedirectMapping, error) {
+	where, args := scopeCondition(scope)
+	if len(statuses) > 0 {
+		where += ` AND status IN (?` + strings.Repeat(", ?", len(statuses)-1) + `)`
+		for _, status := range statuses {
+			args = append(args, status)
+		}
+	}
+
+	query := `SELECT ` + redirectMappingColumns + `
 		FROM redirect_mappings
-		WHERE client_id = ?
+		WHERE ` + where + `
 		ORDER BY created_at DESC
 	`
 
-	rows, err := r.db.Query(query, clientID)
+	rows, err := r.db.Query(query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get redirect mappings: %w", err)
 	}
@@ -84,53 +382,229 @@ func (r *RedirectRepository) GetClientRedirectMappings(clientID int64) ([]models
 
 	var mappings []models.RedirectMapping
 	for rows.Next() {
-		var mapping models.RedirectMapping
-		err := rows.Scan(
-			&mapping.ID,
-			&mapping.ClientID,
-			&mapping.Hash,
-			&mapping.RedirectURL,
-			&mapping.RedirectURLBlack,
-			&mapping.CreatedAt,
-			&mapping.UpdatedAt,
-		)
+		mapping, err := scanRedirectMapping(rows)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan redirect mapping: %w", err)
 		}
-		mappings = append(mappings, mapping)
+		mappings = append(mappings, *mapping)
 	}
 
 	return mappings, nil
 }
 
-func (r *RedirectRepository) SaveRedirect(redirect *models.Redirect) error {
+// SaveRedirect stores the redirect of a request log and reports whether it
+// was stored now. A redirect already stored for the request log is kept, and
+// its ID is returned.
+func (r *RedirectRepository) SaveRedirect(redirect *models.Redirect) (bool, error) {
+	var fraudRules []byte
+	if len(redirect.FraudRules) > 0 {
+		var err error
+		if fraudRules, err = json.Marshal(redirect.FraudRules); err != nil {
+			return false, fmt.Errorf("failed to marshal fraud rules: %w", err)
+		}
+	}
+	var forwardedParams []byte
+	if len(redirect.ForwardedParams) > 0 {
+		var err error
+		if forwardedParams, err = json.Marshal(redirect.ForwardedParams); err != nil {
+			return false, fmt.Errorf("failed to marshal forwarded params: %w", err)
+		}
+	}
+
 	query := `
 		INSERT INTO redirect_history (
-			request_log_id, original_url, redirect_url,
-			redirect_type, redirect_status, redirect_timestamp
-		) VALUES (?, ?, ?, ?, ?, ?)
+			request_log_id, mapping_id, client_id, organization_id, click_id, original_url, redirect_url, variant,
+			redirect_type, redirect_status, redirect_timestamp, is_bot, bot_reason, fraud_score, fraud_rules,
+			forwarded_params, visitor_id, is_unique
+		) VALUES (?, ?, ?, ?, NULLIF(?, ''), ?, ?, ?, ?, ?, ?, ?, NULLIF(?, ''), ?, ?, ?, NULLIF(?, ''), ?)
+		ON DUPLICATE KEY UPDATE id = LAST_INSERT_ID(id)
 	`
 
 	result, err := r.db.Exec(
 		query,
 		redirect.RequestLogID,
+		redirect.MappingID,
+		redirect.ClientID,
+		redirect.OrganizationID,
+		redirect.ClickID,
 		redirect.OriginalURL,
 		redirect.RedirectURL,
+		redirect.Variant,
 		redirect.RedirectType,
 		redirect.RedirectStatus,
 		redirect.RedirectTimestamp,
+		redirect.IsBot,
+		redirect.BotReason,
+		redirect.FraudScore,
+		fraudRules,
+		forwardedParams,
+		redirect.VisitorID,
+		redirect.IsUnique,
 	)
 	if err != nil {
-		return fmt.Errorf("failed to save redirect: %w", err)
+		return false, fmt.Errorf("failed to save redirect: %w", err)
 	}
 
 	id, err := result.LastInsertId()
 	if err != nil {
-		return fmt.Errorf("failed to get last insert id: %w", err)
+		return false, fmt.Errorf("failed to get last insert id: %w", err)
+	}
+	// 1 row is affected by an insert, none when the existing row is kept
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get affected rows: %w", err)
 	}
 
 	redirect.ID = id
-	return nil
+	return affected == 1, nil
+}
+
+const redirectHistoryColumns = `
+	id, request_log_id, mapping_id, client_id, organization_id, click_id, original_url,
+	redirect_url, variant, redirect_type, redirect_status, redirect_timestamp, is_bot,
+	bot_reason, fraud_score, fraud_rules, forwarded_params, visitor_id, is_unique, created_at
+`
+
+// GetHistoryByMapping returns a mapping's clicks within [from, to), newest first
+func (r *RedirectRepository) GetHistoryByMapping(mappingID int64, from, to time.Time, limit int) ([]models.Redirect, error) {
+	query := `SELECT ` + redirectHistoryColumns + `
+		FROM redirect_history
+		WHERE mapping_id = ? AND redirect_timestamp >= ? AND redirect_timestamp < ?
+		ORDER BY redirect_timestamp DESC, id DESC
+		LIMIT ?
+	`
+	return r.queryHistory(query, mappingID, from, to, limit)
+}
+
+// GetHistoryByClient returns clicks on all of a client's mappings within [from, to), newest first
+func (r *RedirectRepository) GetHistoryByClient(clientID int64, from, to time.Time, limit int) ([]models.Redirect, error) {
+	query := `SELECT ` + redirectHistoryColumns + `
+		FROM redirect_history
+		WHERE client_id = ? AND redirect_timestamp >= ? AND redirect_timestamp < ?
+		ORDER BY redirect_timestamp DESC, id DESC
+		LIMIT ?
+	`
+	return r.queryHistory(query, clientID, from, to, limit)
+}
+
+// GetFlaggedHistory returns the clicks within the scope and [from, to) scoring at
+// least minScore, newest first. A zero mappingID covers every mapping in the scope.
+func (r *RedirectRepository) GetFlaggedHistory(scope models.Scope, mappingID int64, minScore int, from, to time.Time, limit int) ([]models.Redirect, error) {
+	where, args := scopeCondition(scope)
+	query := `SELECT ` + redirectHistoryColumns + `
+		FROM redirect_history
+		WHERE ` + where + ` AND fraud_score >= ? AND redirect_timestamp >= ? AND redirect_timestamp < ?
+			AND (? = 0 OR mapping_id = ?)
+		ORDER BY redirect_timestamp DESC, id DESC
+		LIMIT ?
+	`
+	args = append(args, minScore, from, to, mappingID, mappingID, limit)
+	return r.queryHistory(query, args...)
+}
+
+// ClickIDSeen reports whether the client already has a click with the click_id,
+// ignoring the click of requestLogID so a redelivered event does not match itself
+func (r *RedirectRepository) ClickIDSeen(clientID int64, clickID string, requestLogID int64) (bool, error) {
+	query := `
+		SELECT EXISTS(
+			SELECT 1
+			FROM redirect_history
+			WHERE click_id = ? AND client_id = ? AND request_log_id <> ?
+		)
+	`
+
+	var seen bool
+	if err := r.db.QueryRow(query, clickID, clientID, requestLogID).Scan(&seen); err != nil {
+		return false, fmt.Errorf("failed to check click id: %w", err)
+	}
+	return seen, nil
+}
+
+// VisitorSeen reports whether the visitor has a click on the mapping within
+// [since, until], ignoring the click of requestLogID so a redelivered event does
+// not match itself
+func (r *RedirectRepository) VisitorSeen(mappingID int64, visitorID string, requestLogID int64, since, until time.Time) (bool, error) {
+	query := `
+		SELECT EXISTS(
+			SELECT 1
+			FROM redirect_history
+			WHERE mapping_id = ? AND visitor_id = ?
+				AND redirect_timestamp >= ? AND redirect_timestamp <= ?
+				AND request_log_id <> ?
+		)
+	`
+
+	var seen bool
+	if err := r.db.QueryRow(query, mappingID, visitorID, since, until, requestLogID).Scan(&seen); err != nil {
+		return false, fmt.Errorf("failed to check visitor: %w", err)
+	}
+	return seen, nil
+}
+
+func (r *RedirectRepository) queryHistory(query string, args ...interface{}) ([]models.Redirect, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get redirect history: %w", err)
+	}
+	defer rows.Close()
+
+	var history []models.Redirect
+	for rows.Next() {
+		var redirect models.Redirect
+		var mappingID, clientID, organizationID sql.NullInt64
+		var clickID, botReason, visitorID sql.NullString
+		var fraudRules, forwardedParams []byte
+		err := rows.Scan(
+			&redirect.ID,
+			&redirect.RequestLogID,
+			&mappingID,
+			&clientID,
+			&organizationID,
+			&clickID,
+			&redirect.OriginalURL,
+			&redirect.RedirectURL,
+			&redirect.Variant,
+			&redirect.RedirectType,
+			&redirect.RedirectStatus,
+			&redirect.RedirectTimestamp,
+			&redirect.IsBot,
+			&botReason,
+			&redirect.FraudScore,
+			&fraudRules,
+			&forwardedParams,
+			&visitorID,
+			&redirect.IsUnique,
+			&redirect.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan redirect history: %w", err)
+		}
+		redirect.MappingID = mappingID.Int64
+		redirect.ClientID = clientID.Int64
+		if organizationID.Valid {
+			redirect.OrganizationID = &organizationID.Int64
+		}
+		redirect.ClickID = clickID.String
+		redirect.BotReason = botReason.String
+		redirect.VisitorID = visitorID.String
+		if len(fraudRules) > 0 {
+			if err := json.Unmarshal(fraudRules, &redirect.FraudRules); err != nil {
+				return nil, fmt.Errorf("failed to decode fraud rules: %w", err)
+			}
+		}
+		if len(forwardedParams) > 0 {
+			if err := json.Unmarshal(forwardedParams, &redirect.ForwardedParams); err != nil {
+				return nil, fmt.Errorf("failed to decode forwarded params: %w", err)
+			}
+		}
+		history = append(history, redirect)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate redirect history: %w", err)
+	}
+
+	return history, nil
 }
 
 // generateUniqueHash generates a unique 6-character hash
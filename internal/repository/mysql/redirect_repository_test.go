@@ -0,0 +1,187 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+// historyRow is a click in the fake redirect_history table
+type historyRow struct {
+	requestLogID int64
+	requestID    string
+	clientID     int64
+	mappingID    int64
+	clickID      string
+	visitorID    string
+	timestamp    time.Time
+}
+
+// fakeHistory is a database/sql driver answering the ClickIDSeen and
+// VisitorSeen queries from a fixed set of clicks. It records every argument it
+// receives so tests can check which values the queries depend on.
+type fakeHistory struct {
+	rows []historyRow
+	args []driver.Value
+}
+
+func (f *fakeHistory) Connect(context.Context) (driver.Conn, error) { return f, nil }
+func (f *fakeHistory) Driver() driver.Driver                        { return f }
+func (f *fakeHistory) Open(string) (driver.Conn, error)             { return f, nil }
+func (f *fakeHistory) Begin() (driver.Tx, error)                    { return nil, fmt.Errorf("not supported") }
+func (f *fakeHistory) Close() error                                 { return nil }
+
+func (f *fakeHistory) Prepare(query string) (driver.Stmt, error) {
+	return &fakeStmt{history: f, query: query}, nil
+}
+
+type fakeStmt struct {
+	history *fakeHistory
+	query   string
+}
+
+func (s *fakeStmt) Close() error  { return nil }
+func (s *fakeStmt) NumInput() int { return -1 }
+
+func (s *fakeStmt) Exec([]driver.Value) (driver.Result, error) {
+	return nil, fmt.Errorf("not supported")
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.history.args = append(s.history.args, args...)
+
+	var match func(row historyRow) bool
+	switch {
+	case strings.Contains(s.query, "visitor_id = ?") && len(args) == 5:
+		since, until := args[2].(time.Time), args[3].(time.Time)
+		match = func(row historyRow) bool {
+			return row.mappingID == args[0].(int64) && row.visitorID == args[1].(string) &&
+				!row.timestamp.Before(since) && !row.timestamp.After(until) &&
+				row.requestLogID != args[4].(int64)
+		}
+	case strings.Contains(s.query, "click_id = ?") && len(args) == 3:
+		match = func(row historyRow) bool {
+			return row.clickID == args[0].(string) && row.clientID == args[1].(int64) &&
+				row.requestLogID != args[2].(int64)
+		}
+	default:
+		return nil, fmt.Errorf("unexpected query %q with %d arguments", s.query, len(args))
+	}
+
+	seen := false
+	for _, row := range s.history.rows {
+		seen = seen || match(row)
+	}
+	return &existsRows{seen: seen}, nil
+}
+
+// existsRows returns the single row of a SELECT EXISTS(...)
+type existsRows struct {
+	seen bool
+	done bool
+}
+
+func (r *existsRows) Columns() []string { return []string{"seen"} }
+func (r *existsRows) Close() error      { return nil }
+
+func (r *existsRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	dest[0], r.done = r.seen, true
+	return nil
+}
+
+// sharedRequestID is sent by every click below, as a client replaying a fixed
+// X-Request-ID header would
+const sharedRequestID = "fixed-request-id"
+
+var clickedAt = time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func newFakeHistoryRepository(rows ...historyRow) (*RedirectRepository, *fakeHistory) {
+	history := &fakeHistory{rows: rows}
+	return NewRedirectRepository(sql.OpenDB(history)), history
+}
+
+func TestVisitorSeen(t *testing.T) {
+	stored := historyRow{
+		requestLogID: 1, requestID: sharedRequestID, clientID: 3, mappingID: 7,
+		visitorID: "visitor-a", timestamp: clickedAt,
+	}
+
+	tests := []struct {
+		name         string
+		requestLogID int64
+		visitorID    string
+		at           time.Time
+		want         bool
+	}{
+		{name: "other event with the same request id", requestLogID: 2, visitorID: "visitor-a", at: clickedAt.Add(time.Minute), want: true},
+		{name: "redelivery of the stored event", requestLogID: 1, visitorID: "visitor-a", at: clickedAt, want: false},
+		{name: "other visitor", requestLogID: 2, visitorID: "visitor-b", at: clickedAt.Add(time.Minute), want: false},
+		{name: "outside the window", requestLogID: 2, visitorID: "visitor-a", at: clickedAt.Add(48 * time.Hour), want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, history := newFakeHistoryRepository(stored)
+
+			seen, err := repo.VisitorSeen(stored.mappingID, tt.visitorID, tt.requestLogID, tt.at.Add(-24*time.Hour), tt.at)
+			if err != nil {
+				t.Fatalf("VisitorSeen() error = %v", err)
+			}
+			if seen != tt.want {
+				t.Errorf("VisitorSeen() = %v, want %v", seen, tt.want)
+			}
+			for _, arg := range history.args {
+				if arg == sharedRequestID {
+					t.Errorf("VisitorSeen() depends on the client-supplied request ID")
+				}
+			}
+		})
+	}
+}
+
+func TestClickIDSeen(t *testing.T) {
+	stored := historyRow{
+		requestLogID: 1, requestID: sharedRequestID, clientID: 3, mappingID: 7,
+		clickID: "click-a", timestamp: clickedAt,
+	}
+
+	tests := []struct {
+		name         string
+		requestLogID int64
+		clientID     int64
+		clickID      string
+		want         bool
+	}{
+		{name: "other event with the same request id", requestLogID: 2, clientID: 3, clickID: "click-a", want: true},
+		{name: "redelivery of the stored event", requestLogID: 1, clientID: 3, clickID: "click-a", want: false},
+		{name: "other click id", requestLogID: 2, clientID: 3, clickID: "click-b", want: false},
+		{name: "other client", requestLogID: 2, clientID: 4, clickID: "click-a", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, history := newFakeHistoryRepository(stored)
+
+			seen, err := repo.ClickIDSeen(tt.clientID, tt.clickID, tt.requestLogID)
+			if err != nil {
+				t.Fatalf("ClickIDSeen() error = %v", err)
+			}
+			if seen != tt.want {
+				t.Errorf("ClickIDSeen() = %v, want %v", seen, tt.want)
+			}
+			for _, arg := range history.args {
+				if arg == sharedRequestID {
+					t.Errorf("ClickIDSeen() depends on the client-supplied request ID")
+				}
+			}
+		})
+	}
+}
@@ -16,22 +16,40 @@ func NewRequestRepository(db *sql.DB) *RequestRepository {
 	}
 }
 
+// SaveRequest stores a request log. A request already stored for the same
+// click event is kept, and its ID is returned.
 func (r *RequestRepository) SaveRequest(request *models.Request) error {
 	query := `
 		INSERT INTO request_logs (
-			timestamp, ip_address, request_url, request_method,
-			request_headers, processing_status
-		) VALUES (?, ?, ?, ?, ?, 'processed')
+			request_id, event_id, client_id, organization_id, timestamp, ip_address, request_url,
+			request_method, request_headers, browser_family, browser_version, os_family, os_version,
+			device_class, is_bot, country_code, region, city, asn, as_org,
+			referrer_url, referrer_host, source_category, utm_source, utm_medium,
+			utm_campaign, utm_term, utm_content, processing_status
+		) VALUES (
+			NULLIF(?, ''), NULLIF(?, ''), NULLIF(?, 0), NULLIF(?, 0), ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?,
+			NULLIF(?, ''), NULLIF(?, ''), NULLIF(?, ''), NULLIF(?, 0), NULLIF(?, ''),
+			`+attributionPlaceholders+`, 'processed'
+		)
+		ON DUPLICATE KEY UPDATE id = LAST_INSERT_ID(id)
 	`
 
-	result, err := r.db.Exec(
-		query,
+	args := []interface{}{
+		request.RequestID,
+		request.EventID,
+		request.ClientID,
+		request.OrganizationID,
 		request.Timestamp,
 		request.IPAddress,
 		request.RequestURL,
 		request.RequestMethod,
 		request.RequestHeaders,
-	)
+	}
+	args = append(args, userAgentArgs(request.UserAgent)...)
+	args = append(args, geoArgs(request.Geo)...)
+	args = append(args, attributionArgs(request.Attribution)...)
+
+	result, err := r.db.Exec(query, args...)
 	if err != nil {
 		return fmt.Errorf("failed to save request: %w", err)
 	}
@@ -43,4 +61,206 @@ func (r *RequestRepository) SaveRequest(request *models.Request) error {
 
 	request.ID = id
 	return nil
-} 
\ No newline at end of file
+} 
+
+// userAgentArgs returns the values of the parsed User-Agent columns, all NULL
+// when the request has not been parsed
+func userAgentArgs(ua *models.UserAgent) []interface{} {
+	if ua == nil {
+		return []interface{}{nil, nil, nil, nil, nil, nil}
+	}
+	return []interface{}{ua.BrowserFamily, ua.BrowserVersion, ua.OSFamily, ua.OSVersion, ua.DeviceClass, ua.IsBot}
+}
+
+// geoArgs returns the values of the geo columns; empty values are stored as NULL
+func geoArgs(geo *models.Geo) []interface{} {
+	if geo == nil {
+		geo = &models.Geo{}
+	}
+	return []interface{}{geo.CountryCode, geo.Region, geo.City, geo.ASN, geo.ASOrg}
+}
+
+// attributionPlaceholders stores empty attribution values as NULL, and
+// source_category as NULL only when the request has not been parsed
+const attributionPlaceholders = `NULLIF(?, ''), NULLIF(?, ''), ?, NULLIF(?, ''), NULLIF(?, ''), NULLIF(?, ''), NULLIF(?, ''), NULLIF(?, '')`
+
+// attributionArgs returns the values of the attribution columns
+func attributionArgs(a *models.Attribution) []interface{} {
+	if a == nil {
+		return []interface{}{"", "", nil, "", "", "", "", ""}
+	}
+	return []interface{}{a.ReferrerURL, a.ReferrerHost, a.SourceCategory, a.UTMSource, a.UTMMedium, a.UTMCampaign, a.UTMTerm, a.UTMContent}
+}
+
+// userAgentColumns scans the parsed User-Agent columns of request_logs
+type userAgentColumns struct {
+	browserFamily, browserVersion, osFamily, osVersion, deviceClass sql.NullString
+	isBot                                                           sql.NullBool
+}
+
+func (c *userAgentColumns) dest() []interface{} {
+	return []interface{}{&c.browserFamily, &c.browserVersion, &c.osFamily, &c.osVersion, &c.deviceClass, &c.isBot}
+}
+
+// value returns the scanned User-Agent, or nil when the row has not been parsed
+func (c *userAgentColumns) value() *models.UserAgent {
+	if !c.deviceClass.Valid {
+		return nil
+	}
+	return &models.UserAgent{
+		BrowserFamily:  c.browserFamily.String,
+		BrowserVersion: c.browserVersion.String,
+		OSFamily:       c.osFamily.String,
+		OSVersion:      c.osVersion.String,
+		DeviceClass:    c.deviceClass.String,
+		IsBot:          c.isBot.Bool,
+	}
+}
+
+// GetUserAgentBatch returns up to limit request logs with IDs above afterID, in ID
+// order, with their headers. Unless all is set, only rows whose User-Agent has not
+// been parsed yet are returned.
+func (r *RequestRepository) GetUserAgentBatch(afterID int64, limit int, all bool) ([]models.Request, error) {
+	where := `id > ?`
+	if !all {
+		where += ` AND device_class IS NULL`
+	}
+	query := `
+		SELECT id, request_headers
+		FROM request_logs
+		WHERE ` + where + `
+		ORDER BY id
+		LIMIT ?
+	`
+
+	rows, err := r.db.Query(query, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get request logs: %w", err)
+	}
+	defer rows.Close()
+
+	var requests []models.Request
+	for rows.Next() {
+		var request models.Request
+		if err := rows.Scan(&request.ID, &request.RequestHeaders); err != nil {
+			return nil, fmt.Errorf("failed to scan request log: %w", err)
+		}
+		requests = append(requests, request)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate request logs: %w", err)
+	}
+
+	return requests, nil
+}
+
+// SetUserAgents stores the parsed User-Agent fields of request logs in one transaction
+func (r *RequestRepository) SetUserAgents(requests []models.Request) error {
+	if len(requests) == 0 {
+		return nil
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`
+		UPDATE request_logs
+		SET browser_family = ?, browser_version = ?, os_family = ?, os_version = ?, device_class = ?, is_bot = ?
+		WHERE id = ?
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare user agent update: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, request := range requests {
+		args := append(userAgentArgs(request.UserAgent), request.ID)
+		if _, err := stmt.Exec(args...); err != nil {
+			return fmt.Errorf("failed to update user agent of request log %d: %w", request.ID, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// GetAttributionBatch returns up to limit request logs with IDs above afterID, in
+// ID order, with their URL and headers. Unless all is set, only rows whose
+// attribution has not been parsed yet are returned.
+func (r *RequestRepository) GetAttributionBatch(afterID int64, limit int, all bool) ([]models.Request, error) {
+	where := `id > ?`
+	if !all {
+		where += ` AND source_category IS NULL`
+	}
+	query := `
+		SELECT id, request_url, request_headers
+		FROM request_logs
+		WHERE ` + where + `
+		ORDER BY id
+		LIMIT ?
+	`
+
+	rows, err := r.db.Query(query, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get request logs: %w", err)
+	}
+	defer rows.Close()
+
+	var requests []models.Request
+	for rows.Next() {
+		var request models.Request
+		if err := rows.Scan(&request.ID, &request.RequestURL, &request.RequestHeaders); err != nil {
+			return nil, fmt.Errorf("failed to scan request log: %w", err)
+		}
+		requests = append(requests, request)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate request logs: %w", err)
+	}
+
+	return requests, nil
+}
+
+// SetAttributions stores the parsed attribution fields of request logs in one transaction
+func (r *RequestRepository) SetAttributions(requests []models.Request) error {
+	if len(requests) == 0 {
+		return nil
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`
+		UPDATE request_logs
+		SET referrer_url = NULLIF(?, ''), referrer_host = NULLIF(?, ''), source_category = ?,
+			utm_source = NULLIF(?, ''), utm_medium = NULLIF(?, ''), utm_campaign = NULLIF(?, ''),
+			utm_term = NULLIF(?, ''), utm_content = NULLIF(?, '')
+		WHERE id = ?
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare attribution update: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, request := range requests {
+		args := append(attributionArgs(request.Attribution), request.ID)
+		if _, err := stmt.Exec(args...); err != nil {
+			return fmt.Errorf("failed to update attribution of request log %d: %w", request.ID, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,240 @@
+package mysql
+
+import (
+	"database/sql"
+	"fmt"
+	"platform/internal/models"
+	"strings"
+	"time"
+)
+
+type RetentionRepository struct {
+	db *sql.DB
+}
+
+func NewRetentionRepository(db *sql.DB) *RetentionRepository {
+	return &RetentionRepository{
+		db: db,
+	}
+}
+
+// retentionTable describes how expired rows of a raw click table are found
+type retentionTable struct {
+	timeColumn string
+	// guard keeps rows that other retained rows still reference
+	guard string
+}
+
+var retentionTables = map[string]retentionTable{
+	models.RetentionRedirectHistory: {
+		timeColumn: "redirect_timestamp",
+	},
+	models.RetentionRequestLogs: {
+		timeColumn: "timestamp",
+		guard:      `NOT EXISTS (SELECT 1 FROM redirect_history h WHERE h.request_log_id = request_logs.id)`,
+	},
+}
+
+// GetPolicies returns every per-client retention override
+func (r *RetentionRepository) GetPolicies() ([]models.RetentionPolicy, error) {
+	return r.queryPolicies(`
+		SELECT client_id, table_name, retention_days, updated_at
+		FROM client_retention_policies
+		ORDER BY table_name, client_id
+	`)
+}
+
+// GetClientPolicies returns the retention overrides of one client
+func (r *RetentionRepository) GetClientPolicies(clientID int64) ([]models.RetentionPolicy, error) {
+	return r.queryPolicies(`
+		SELECT client_id, table_name, retention_days, updated_at
+		FROM client_retention_policies
+		WHERE client_id = ?
+		ORDER BY table_name
+	`, clientID)
+}
+
+func (r *RetentionRepository) queryPolicies(query string, args ...interface{}) ([]models.RetentionPolicy, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get retention policies: %w", err)
+	}
+	defer rows.Close()
+
+	var policies []models.RetentionPolicy
+	for rows.Next() {
+		var policy models.RetentionPolicy
+		if err := rows.Scan(&policy.ClientID, &policy.Table, &policy.Days, &policy.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan retention policy: %w", err)
+		}
+		policies = append(policies, policy)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate retention policies: %w", err)
+	}
+
+	return policies, nil
+}
+
+// SetPolicy creates or replaces a client's retention override for a table
+func (r *RetentionRepository) SetPolicy(clientID int64, table string, days int) error {
+	query := `
+		INSERT INTO client_retention_policies (client_id, table_name, retention_days)
+		VALUES (?, ?, ?)
+		ON DUPLICATE KEY UPDATE retention_days = VALUES(retention_days)
+	`
+
+	if _, err := r.db.Exec(query, clientID, table, days); err != nil {
+		return fmt.Errorf("failed to set retention policy: %w", err)
+	}
+	return nil
+}
+
+// DeletePolicy removes a client's retention override, reporting whether one existed
+func (r *RetentionRepository) DeletePolicy(clientID int64, table string) (bool, error) {
+	result, err := r.db.Exec("DELETE FROM client_retention_policies WHERE client_id = ? AND table_name = ?", clientID, table)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete retention policy: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	return affected > 0, nil
+}
+
+func retentionWhere(table string, scope models.RetentionScope) (string, []interface{}, error) {
+	def, ok := retentionTables[table]
+	if !ok {
+		return "", nil, fmt.Errorf("unsupported retention table %q", table)
+	}
+
+	where := def.timeColumn + ` < ?`
+	args := []interface{}{scope.Cutoff}
+	if scope.ClientID != 0 {
+		where += ` AND client_id = ? AND organization_id IS NULL`
+		args = append(args, scope.ClientID)
+	} else if len(scope.ExcludeClientIDs) > 0 {
+		placeholders := make([]string, len(scope.ExcludeClientIDs))
+		for i, id := range scope.ExcludeClientIDs {
+			placeholders[i] = "?"
+			args = append(args, id)
+		}
+		where += ` AND (client_id IS NULL OR organization_id IS NOT NULL OR client_id NOT IN (` + strings.Join(placeholders, ", ") + `))`
+	}
+	if def.guard != "" {
+		where += ` AND ` + def.guard
+	}
+	return where, args, nil
+}
+
+// CountExpired returns how many rows of a table a purge of the scope would delete
+func (r *RetentionRepository) CountExpired(table string, scope models.RetentionScope) (int64, error) {
+	where, args, err := retentionWhere(table, scope)
+	if err != nil {
+		return 0, err
+	}
+
+	var count int64
+	if err := r.db.QueryRow(`SELECT COUNT(*) FROM `+table+` WHERE `+where, args...).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count expired %s: %w", table, err)
+	}
+	return count, nil
+}
+
+// DeleteExpired deletes up to limit expired rows of a table, oldest IDs first
+func (r *RetentionRepository) DeleteExpired(table string, scope models.RetentionScope, limit int) (int64, error) {
+	where, args, err := retentionWhere(table, scope)
+	if err != nil {
+		return 0, err
+	}
+	args = append(args, limit)
+
+	result, err := r.db.Exec(`DELETE FROM `+table+` WHERE `+where+` ORDER BY id LIMIT ?`, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired %s: %w", table, err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	return affected, nil
+}
+
+// GetPartitions returns the range partitions of a table in order, or none if
+// the table is not partitioned. Row counts are the server's estimates.
+func (r *RetentionRepository) GetPartitions(table string) ([]models.Partition, error) {
+	query := `
+		SELECT PARTITION_NAME, PARTITION_DESCRIPTION, COALESCE(TABLE_ROWS, 0)
+		FROM information_schema.PARTITIONS
+		WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND PARTITION_NAME IS NOT NULL
+		ORDER BY PARTITION_ORDINAL_POSITION
+	`
+
+	rows, err := r.db.Query(query, table)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get partitions: %w", err)
+	}
+	defer rows.Close()
+
+	var partitions []models.Partition
+	for rows.Next() {
+		var partition models.Partition
+		var description string
+		if err := rows.Scan(&partition.Name, &description, &partition.Rows); err != nil {
+			return nil, fmt.Errorf("failed to scan partition: %w", err)
+		}
+		if description != "MAXVALUE" {
+			lessThan, err := time.ParseInLocation("2006-01-02 15:04:05", strings.Trim(description, "'"), time.UTC)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse bound of partition %s: %w", partition.Name, err)
+			}
+			partition.LessThan = &lessThan
+		}
+		partitions = append(partitions, partition)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate partitions: %w", err)
+	}
+
+	return partitions, nil
+}
+
+// OldestTimestamp returns the time of a table's oldest row, or nil if it is empty
+func (r *RetentionRepository) OldestTimestamp(table string) (*time.Time, error) {
+	return oldestRow(r.db, table)
+}
+
+// SplitPartition reorganizes the catch-all partition into the given partitions
+// followed by a new catch-all
+func (r *RetentionRepository) SplitPartition(table, catchAll string, partitions []models.Partition) error {
+	defs := make([]string, 0, len(partitions)+1)
+	for _, p := range partitions {
+		defs = append(defs, fmt.Sprintf("PARTITION %s VALUES LESS THAN ('%s')", p.Name, p.LessThan.UTC().Format("2006-01-02 15:04:05")))
+	}
+	defs = append(defs, fmt.Sprintf("PARTITION %s VALUES LESS THAN (MAXVALUE)", catchAll))
+
+	query := fmt.Sprintf("ALTER TABLE %s REORGANIZE PARTITION %s INTO (%s)", table, catchAll, strings.Join(defs, ", "))
+	if _, err := r.db.Exec(query); err != nil {
+		return fmt.Errorf("failed to split partition %s of %s: %w", catchAll, table, err)
+	}
+	return nil
+}
+
+// DropPartition drops a partition together with its rows
+func (r *RetentionRepository) DropPartition(table, name string) error {
+	if _, err := r.db.Exec(fmt.Sprintf("ALTER TABLE %s DROP PARTITION %s", table, name)); err != nil {
+		return fmt.Errorf("failed to drop partition %s of %s: %w", name, table, err)
+	}
+	return nil
+}
+
+// ArchivedUntil returns the time before which every row of a table has been
+// archived, or nil if that holds for no row yet
+func (r *RetentionRepository) ArchivedUntil(table string) (*time.Time, error) {
+	return archivedUntil(r.db, table)
+}
@@ -0,0 +1,436 @@
+package mysql
+
+import (
+	"database/sql"
+	"fmt"
+	"platform/internal/hll"
+	"platform/internal/models"
+	"sort"
+	"strings"
+	"time"
+)
+
+type RollupRepository struct {
+	db *sql.DB
+}
+
+func NewRollupRepository(db *sql.DB) *RollupRepository {
+	return &RollupRepository{
+		db: db,
+	}
+}
+
+// rollupBatchSize bounds the number of rows written by one INSERT statement
+const rollupBatchSize = 500
+
+// rollupDimensions maps the breakdowns served from the rollups to their columns
+var rollupDimensions = map[string]string{
+	"variant": "variant",
+	"country": "country",
+	"device":  "device_class",
+}
+
+// IsRollupDimension reports whether a breakdown can be served from the rollups
+func IsRollupDimension(dimension string) bool {
+	_, ok := rollupDimensions[dimension]
+	return ok
+}
+
+// Increment adds the batch to both the hourly and the daily rollups in one transaction
+func (r *RollupRepository) Increment(batch *models.RollupBatch) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := upsertRollups(tx, batch.Counts); err != nil {
+		return err
+	}
+	if err := mergeVisitorSketches(tx, batch.Visitors); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// ReplaceDay overwrites the hourly and daily rollups of one UTC day with the given batch
+func (r *RollupRepository) ReplaceDay(day time.Time, batch *models.RollupBatch) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM click_rollups_hourly WHERE bucket_start >= ? AND bucket_start < ?", day, day.AddDate(0, 0, 1)); err != nil {
+		return fmt.Errorf("failed to clear hourly rollups: %w", err)
+	}
+	if _, err := tx.Exec("DELETE FROM click_rollups_daily WHERE bucket_date = ?", day.Format("2006-01-02")); err != nil {
+		return fmt.Errorf("failed to clear daily rollups: %w", err)
+	}
+	if _, err := tx.Exec("DELETE FROM visitor_rollups_hourly WHERE bucket_start >= ? AND bucket_start < ?", day, day.AddDate(0, 0, 1)); err != nil {
+		return fmt.Errorf("failed to clear hourly visitor rollups: %w", err)
+	}
+	if _, err := tx.Exec("DELETE FROM visitor_rollups_daily WHERE bucket_date = ?", day.Format("2006-01-02")); err != nil {
+		return fmt.Errorf("failed to clear daily visitor rollups: %w", err)
+	}
+
+	if err := upsertRollups(tx, batch.Counts); err != nil {
+		return err
+	}
+	if err := mergeVisitorSketches(tx, batch.Visitors); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// upsertRollups adds the hourly counts to the hourly table and their per-day sums
+// to the daily table. Like the visitor sketches, rows are upserted in a fixed
+// order so concurrent flushes do not deadlock.
+func upsertRollups(tx *sql.Tx, counts map[models.RollupKey]models.RollupCounts) error {
+	keys := make([]models.RollupKey, 0, len(counts))
+	dailyCounts := make(map[models.RollupKey]models.RollupCounts)
+	for key, hourly := range counts {
+		keys = append(keys, key)
+		dayKey := key
+		dayKey.Hour = key.Day()
+		daily := dailyCounts[dayKey]
+		daily.Clicks += hourly.Clicks
+		daily.UniqueClicks += hourly.UniqueClicks
+		dailyCounts[dayKey] = daily
+	}
+
+	sortRollupKeys(keys)
+	if err := upsertRollupRows(tx, "click_rollups_hourly", "bucket_start", keys, counts, func(k models.RollupKey) interface{} {
+		return k.Hour.UTC()
+	}); err != nil {
+		return err
+	}
+
+	dayKeys := make([]models.RollupKey, 0, len(dailyCounts))
+	for key := range dailyCounts {
+		dayKeys = append(dayKeys, key)
+	}
+	sortRollupKeys(dayKeys)
+	return upsertRollupRows(tx, "click_rollups_daily", "bucket_date", dayKeys, dailyCounts, func(k models.RollupKey) interface{} {
+		return k.Hour.Format("2006-01-02")
+	})
+}
+
+// sortRollupKeys orders keys by mapping, then bucket, then the rest of the
+// rollup's unique key
+func sortRollupKeys(keys []models.RollupKey) {
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := keys[i], keys[j]
+		switch {
+		case a.MappingID != b.MappingID:
+			return a.MappingID < b.MappingID
+		case !a.Hour.Equal(b.Hour):
+			return a.Hour.Before(b.Hour)
+		case a.Variant != b.Variant:
+			return a.Variant < b.Variant
+		case a.Country != b.Country:
+			return a.Country < b.Country
+		case a.DeviceClass != b.DeviceClass:
+			return a.DeviceClass < b.DeviceClass
+		default:
+			return !a.IsBot && b.IsBot
+		}
+	})
+}
+
+func upsertRollupRows(tx *sql.Tx, table, bucketColumn string, keys []models.RollupKey, counts map[models.RollupKey]models.RollupCounts, bucket func(models.RollupKey) interface{}) error {
+	for start := 0; start < len(keys); start += rollupBatchSize {
+		end := start + rollupBatchSize
+		if end > len(keys) {
+			end = len(keys)
+		}
+
+		placeholders := make([]string, 0, end-start)
+		args := make([]interface{}, 0, (end-start)*10)
+		for _, key := range keys[start:end] {
+			placeholders = append(placeholders, "(?, ?, ?, NULLIF(?, 0), ?, ?, ?, ?, ?, ?)")
+			args = append(args, bucket(key), key.MappingID, key.ClientID, key.OrganizationID, key.Variant, key.Country, key.DeviceClass, key.IsBot, counts[key].Clicks, counts[key].UniqueClicks)
+		}
+
+		query := `INSERT INTO ` + table + ` (` + bucketColumn + `, mapping_id, client_id, organization_id, variant, country, device_class, is_bot, clicks, unique_clicks)
+			VALUES ` + strings.Join(placeholders, ", ") + `
+			ON DUPLICATE KEY UPDATE clicks = clicks + VALUES(clicks), unique_clicks = unique_clicks + VALUES(unique_clicks)`
+
+		if _, err := tx.Exec(query, args...); err != nil {
+			return fmt.Errorf("failed to upsert %s: %w", table, err)
+		}
+	}
+	return nil
+}
+
+// mergeVisitorSketches merges the hourly sketches into the hourly visitor
+// rollups and their per-day unions into the daily ones. Sketches cannot be
+// merged by SQL, so each stored row is locked, merged and written back; keys
+// are visited in a fixed order so concurrent flushes do not deadlock.
+func mergeVisitorSketches(tx *sql.Tx, sketches map[models.VisitorKey]*hll.Sketch) error {
+	daily := make(map[models.VisitorKey]*hll.Sketch)
+	for key, sketch := range sketches {
+		dayKey := key
+		dayKey.Hour = key.Day()
+		if daily[dayKey] == nil {
+			daily[dayKey] = hll.New()
+		}
+		daily[dayKey].Merge(sketch)
+	}
+
+	if err := mergeVisitorRows(tx, "visitor_rollups_hourly", "bucket_start", sketches, func(k models.VisitorKey) interface{} {
+		return k.Hour.UTC()
+	}); err != nil {
+		return err
+	}
+	return mergeVisitorRows(tx, "visitor_rollups_daily", "bucket_date", daily, func(k models.VisitorKey) interface{} {
+		return k.Hour.Format("2006-01-02")
+	})
+}
+
+func mergeVisitorRows(tx *sql.Tx, table, bucketColumn string, sketches map[models.VisitorKey]*hll.Sketch, bucket func(models.VisitorKey) interface{}) error {
+	keys := make([]models.VisitorKey, 0, len(sketches))
+	for key := range sketches {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].MappingID != keys[j].MappingID {
+			return keys[i].MappingID < keys[j].MappingID
+		}
+		if !keys[i].Hour.Equal(keys[j].Hour) {
+			return keys[i].Hour.Before(keys[j].Hour)
+		}
+		return !keys[i].IsBot && keys[j].IsBot
+	})
+
+	selectQuery := `SELECT sketch FROM ` + table + ` WHERE mapping_id = ? AND ` + bucketColumn + ` = ? AND is_bot = ? FOR UPDATE`
+	upsertQuery := `INSERT INTO ` + table + ` (` + bucketColumn + `, mapping_id, client_id, organization_id, is_bot, sketch)
+		VALUES (?, ?, ?, NULLIF(?, 0), ?, ?)
+		ON DUPLICATE KEY UPDATE sketch = VALUES(sketch)`
+
+	for _, key := range keys {
+		merged := hll.New()
+		merged.Merge(sketches[key])
+
+		var stored []byte
+		err := tx.QueryRow(selectQuery, key.MappingID, bucket(key), key.IsBot).Scan(&stored)
+		if err != nil && err != sql.ErrNoRows {
+			return fmt.Errorf("failed to get %s: %w", table, err)
+		}
+		if err == nil {
+			existing := hll.New()
+			if err := existing.UnmarshalBinary(stored); err != nil {
+				return fmt.Errorf("failed to decode %s sketch: %w", table, err)
+			}
+			merged.Merge(existing)
+		}
+
+		data, err := merged.MarshalBinary()
+		if err != nil {
+			return fmt.Errorf("failed to encode %s sketch: %w", table, err)
+		}
+		if _, err := tx.Exec(upsertQuery, bucket(key), key.MappingID, key.ClientID, key.OrganizationID, key.IsBot, data); err != nil {
+			return fmt.Errorf("failed to upsert %s: %w", table, err)
+		}
+	}
+	return nil
+}
+
+// ForEachClick streams the stored clicks within [from, to) as click events carrying
+// the fields the rollups are keyed on. Requests whose User-Agent has not been
+// parsed yet carry no UserAgent, and requests without a GeoIP country no Geo.
+// The bot classification, visitor and uniqueness stored with each click are
+// kept as is.
+func (r *RollupRepository) ForEachClick(from, to time.Time, fn func(*models.ClickEvent) error) error {
+	query := `
+		SELECT h.redirect_timestamp, h.mapping_id, h.client_id, COALESCE(h.organization_id, 0), h.variant,
+			h.is_bot, COALESCE(h.bot_reason, ''),
+			COALESCE(h.visitor_id, ''), h.is_unique, l.request_headers,
+			l.browser_family, l.browser_version, l.os_family, l.os_version, l.device_class, l.is_bot,
+			COALESCE(l.country_code, '')
+		FROM redirect_history h
+		JOIN request_logs l ON l.id = h.request_log_id
+		WHERE h.redirect_timestamp >= ? AND h.redirect_timestamp < ? AND h.mapping_id IS NOT NULL
+	`
+
+	rows, err := r.db.Query(query, from, to)
+	if err != nil {
+		return fmt.Errorf("failed to get clicks: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		event := &models.ClickEvent{}
+		var ua userAgentColumns
+		var countryCode string
+		dest := []interface{}{
+			&event.Timestamp,
+			&event.MappingID,
+			&event.ClientID,
+			&event.OrganizationID,
+			&event.Variant,
+			&event.IsBot,
+			&event.BotReason,
+			&event.VisitorID,
+			&event.Unique,
+			&event.Request.RequestHeaders,
+		}
+		if err := rows.Scan(append(append(dest, ua.dest()...), &countryCode)...); err != nil {
+			return fmt.Errorf("failed to scan click: %w", err)
+		}
+		event.Request.UserAgent = ua.value()
+		if countryCode != "" {
+			event.Request.Geo = &models.Geo{CountryCode: countryCode}
+		}
+		if err := fn(event); err != nil {
+			return err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to iterate clicks: %w", err)
+	}
+	return nil
+}
+
+// rollupSource picks the daily table of a rollup family (click_rollups or
+// visitor_rollups) when the range covers whole UTC days, else the hourly one
+func rollupSource(family string, filter models.StatsFilter, daily bool) (table, bucket string, from, to interface{}) {
+	if daily {
+		return family + "_daily", "bucket_date", filter.From.UTC().Format("2006-01-02"), filter.To.UTC().Format("2006-01-02")
+	}
+	return family + "_hourly", "bucket_start", filter.From.UTC().Truncate(time.Hour), filter.To
+}
+
+func rollupWhere(filter models.StatsFilter, bucket string, from, to interface{}) (string, []interface{}) {
+	where, args := scopeCondition(filter.Scope)
+	where += ` AND ` + bucket + ` >= ? AND ` + bucket + ` < ?`
+	args = append(args, from, to)
+	if filter.MappingID != 0 {
+		where += ` AND mapping_id = ?`
+		args = append(args, filter.MappingID)
+	}
+	if !filter.IncludeBots {
+		where += ` AND is_bot = FALSE`
+	}
+	return where, args
+}
+
+// BucketClicks returns click and unique click counts per rollup bucket (UTC
+// hour, or UTC day when daily is set)
+func (r *RollupRepository) BucketClicks(filter models.StatsFilter, daily bool) ([]models.HourlyCount, error) {
+	table, bucket, from, to := rollupSource("click_rollups", filter, daily)
+	where, args := rollupWhere(filter, bucket, from, to)
+	query := `
+		SELECT ` + bucket + `, SUM(clicks), SUM(unique_clicks)
+		FROM ` + table + `
+		WHERE ` + where + `
+		GROUP BY ` + bucket + `
+		ORDER BY ` + bucket
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rollup clicks: %w", err)
+	}
+	defer rows.Close()
+
+	var counts []models.HourlyCount
+	for rows.Next() {
+		var count models.HourlyCount
+		if err := rows.Scan(&count.Hour, &count.Clicks, &count.UniqueClicks); err != nil {
+			return nil, fmt.Errorf("failed to scan rollup clicks: %w", err)
+		}
+		counts = append(counts, count)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate rollup clicks: %w", err)
+	}
+
+	return counts, nil
+}
+
+// Visitors estimates the distinct visitors within the filter by merging the
+// visitor sketches of every rollup bucket it covers
+func (r *RollupRepository) Visitors(filter models.StatsFilter, daily bool) (int64, error) {
+	table, bucket, from, to := rollupSource("visitor_rollups", filter, daily)
+	where, args := rollupWhere(filter, bucket, from, to)
+	query := `
+		SELECT sketch
+		FROM ` + table + `
+		WHERE ` + where
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get visitor sketches: %w", err)
+	}
+	defer rows.Close()
+
+	merged := hll.New()
+	for rows.Next() {
+		var data []byte
+		if err := rows.Scan(&data); err != nil {
+			return 0, fmt.Errorf("failed to scan visitor sketch: %w", err)
+		}
+		sketch := hll.New()
+		if err := sketch.UnmarshalBinary(data); err != nil {
+			return 0, fmt.Errorf("failed to decode visitor sketch: %w", err)
+		}
+		merged.Merge(sketch)
+	}
+
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to iterate visitor sketches: %w", err)
+	}
+
+	return int64(merged.Estimate()), nil
+}
+
+// Breakdown returns the top values of a rollup dimension by clicks
+func (r *RollupRepository) Breakdown(filter models.StatsFilter, dimension string, limit int, daily bool) ([]models.BreakdownItem, error) {
+	column, ok := rollupDimensions[dimension]
+	if !ok {
+		return nil, fmt.Errorf("unsupported rollup dimension %q", dimension)
+	}
+
+	table, bucket, from, to := rollupSource("click_rollups", filter, daily)
+	where, args := rollupWhere(filter, bucket, from, to)
+	query := `
+		SELECT ` + column + ` AS value, SUM(clicks) AS total
+		FROM ` + table + `
+		WHERE ` + where + `
+		GROUP BY value
+		ORDER BY total DESC, value
+		LIMIT ?
+	`
+	args = append(args, limit)
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s breakdown: %w", dimension, err)
+	}
+	defer rows.Close()
+
+	var items []models.BreakdownItem
+	for rows.Next() {
+		var item models.BreakdownItem
+		if err := rows.Scan(&item.Value, &item.Clicks); err != nil {
+			return nil, fmt.Errorf("failed to scan %s breakdown: %w", dimension, err)
+		}
+		items = append(items, item)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate %s breakdown: %w", dimension, err)
+	}
+
+	return items, nil
+}
@@ -0,0 +1,117 @@
+package mysql
+
+import (
+	"database/sql"
+	"fmt"
+	"platform/internal/models"
+)
+
+type StatsRepository struct {
+	db *sql.DB
+}
+
+func NewStatsRepository(db *sql.DB) *StatsRepository {
+	return &StatsRepository{
+		db: db,
+	}
+}
+
+// The referrer of rows not parsed by the worker yet is read from the JSON headers
+// blob of request_logs. Headers hold arrays of values; [0] also reads the single
+// strings of older rows.
+const refererExpr = `COALESCE(JSON_UNQUOTE(JSON_EXTRACT(l.request_headers, '$.Referer[0]')), '')`
+
+// statsDimensions maps the breakdowns served from the raw logs to the SQL expression
+// they group by. Expressions may use redirect_history (h) and request_logs (l).
+// Variant, country and device are served from the rollups instead.
+var statsDimensions = map[string]string{
+	"referrer":   `COALESCE(l.referrer_host, SUBSTRING_INDEX(SUBSTRING_INDEX(SUBSTRING_INDEX(` + refererExpr + `, '://', -1), '/', 1), ':', 1))`,
+	"source":     `COALESCE(l.source_category, '')`,
+	"campaign":   `COALESCE(l.utm_campaign, '')`,
+	"utm_source": `COALESCE(l.utm_source, '')`,
+	"utm_medium": `COALESCE(l.utm_medium, '')`,
+	"browser":    `COALESCE(l.browser_family, '` + models.UnknownFamily + `')`,
+	"os":         `COALESCE(l.os_family, '` + models.UnknownFamily + `')`,
+	"region":     `COALESCE(l.region, '')`,
+	"city":       `COALESCE(l.city, '')`,
+}
+
+// IsStatsDimension reports whether a breakdown dimension is supported
+func IsStatsDimension(dimension string) bool {
+	_, ok := statsDimensions[dimension]
+	return ok || IsRollupDimension(dimension)
+}
+
+// statsWhere builds the WHERE clause shared by all stats queries
+func statsWhere(filter models.StatsFilter) (string, []interface{}) {
+	where, args := scopeConditionOn("h.", filter.Scope)
+	where += ` AND h.redirect_timestamp >= ? AND h.redirect_timestamp < ?`
+	args = append(args, filter.From, filter.To)
+	if filter.MappingID != 0 {
+		where += ` AND h.mapping_id = ?`
+		args = append(args, filter.MappingID)
+	}
+	if !filter.IncludeBots {
+		where += ` AND h.is_bot = FALSE`
+	}
+	return where, args
+}
+
+// Breakdown returns the top values of a dimension by clicks
+func (r *StatsRepository) Breakdown(filter models.StatsFilter, dimension string, limit int) ([]models.BreakdownItem, error) {
+	expr, ok := statsDimensions[dimension]
+	if !ok {
+		return nil, fmt.Errorf("unsupported stats dimension %q", dimension)
+	}
+
+	where, args := statsWhere(filter)
+	query := `
+		SELECT ` + expr + ` AS value, COUNT(*) AS clicks
+		FROM redirect_history h
+		JOIN request_logs l ON l.id = h.request_log_id
+		WHERE ` + where + `
+		GROUP BY value
+		ORDER BY clicks DESC, value
+		LIMIT ?
+	`
+	args = append(args, limit)
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s breakdown: %w", dimension, err)
+	}
+	defer rows.Close()
+
+	var items []models.BreakdownItem
+	for rows.Next() {
+		var item models.BreakdownItem
+		var value sql.NullString
+		if err := rows.Scan(&value, &item.Clicks); err != nil {
+			return nil, fmt.Errorf("failed to scan %s breakdown: %w", dimension, err)
+		}
+		item.Value = value.String
+		items = append(items, item)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate %s breakdown: %w", dimension, err)
+	}
+
+	return items, nil
+}
+
+// UniqueVisitors counts the distinct visitors within the filter exactly, from the
+// raw history. Clicks stored before visitors were identified are not counted.
+func (r *StatsRepository) UniqueVisitors(filter models.StatsFilter) (int64, error) {
+	where, args := statsWhere(filter)
+	query := `
+		SELECT COUNT(DISTINCT h.visitor_id)
+		FROM redirect_history h
+		WHERE ` + where
+
+	var visitors int64
+	if err := r.db.QueryRow(query, args...).Scan(&visitors); err != nil {
+		return 0, fmt.Errorf("failed to count unique visitors: %w", err)
+	}
+	return visitors, nil
+}
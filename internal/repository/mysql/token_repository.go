@@ -0,0 +1,332 @@
+package mysql
+
+import (
+	"database/sql"
+	"fmt"
+	"platform/internal/models"
+	"time"
+)
+
+type TokenRepository struct {
+	db *sql.DB
+}
+
+func NewTokenRepository(db *sql.DB) *TokenRepository {
+	return &TokenRepository{
+		db: db,
+	}
+}
+
+// CreateRefreshToken stores the refresh token that starts a new family
+func (r *TokenRepository) CreateRefreshToken(token *models.RefreshToken) error {
+	return insertRefreshToken(r.db, token)
+}
+
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+func insertRefreshToken(db execer, token *models.RefreshToken) error {
+	query := `
+		INSERT INTO refresh_tokens (client_id, family_id, token_hash, access_jti, access_expires_at, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`
+
+	result, err := db.Exec(query, token.ClientID, token.FamilyID, token.TokenHash,
+		token.AccessJTI, token.AccessExpiresAt.UTC(), token.ExpiresAt.UTC())
+	if err != nil {
+		return fmt.Errorf("failed to create refresh token: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get last insert ID: %w", err)
+	}
+	token.ID = id
+	return nil
+}
+
+// GetRefreshToken returns the refresh token stored under a hash, or nil if
+// there is none
+func (r *TokenRepository) GetRefreshToken(tokenHash string) (*models.RefreshToken, error) {
+	query := `
+		SELECT id, client_id, family_id, token_hash, access_jti, access_expires_at,
+			expires_at, used_at, revoked_at, created_at
+		FROM refresh_tokens
+		WHERE token_hash = ?
+	`
+
+	var token models.RefreshToken
+	var usedAt, revokedAt sql.NullTime
+	err := r.db.QueryRow(query, tokenHash).Scan(
+		&token.ID,
+		&token.ClientID,
+		&token.FamilyID,
+		&token.TokenHash,
+		&token.AccessJTI,
+		&token.AccessExpiresAt,
+		&token.ExpiresAt,
+		&usedAt,
+		&revokedAt,
+		&token.CreatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get refresh token: %w", err)
+	}
+
+	if usedAt.Valid {
+		token.UsedAt = &usedAt.Time
+	}
+	if revokedAt.Valid {
+		token.RevokedAt = &revokedAt.Time
+	}
+	return &token, nil
+}
+
+// RotateRefreshToken marks a refresh token used and stores its replacement.
+// It returns false without storing anything if the token was used or revoked
+// in the meantime.
+func (r *TokenRepository) RotateRefreshToken(usedID int64, next *models.RefreshToken) (bool, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(
+		"UPDATE refresh_tokens SET used_at = ? WHERE id = ? AND used_at IS NULL AND revoked_at IS NULL",
+		time.Now().UTC(), usedID,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to mark refresh token used: %w", err)
+	}
+
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	if updated == 0 {
+		return false, nil
+	}
+
+	if err := insertRefreshToken(tx, next); err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return true, nil
+}
+
+// RevokeFamily revokes every refresh token of a client's family and denies the
+// access tokens issued with them that have not expired yet, which it returns
+func (r *TokenRepository) RevokeFamily(clientID int64, familyID string) ([]models.RevokedToken, error) {
+	return r.revoke(clientID, "family_id = ?", familyID)
+}
+
+// RevokeClient does what RevokeFamily does for every family of a client but
+// exceptFamilyID, which may be empty
+func (r *TokenRepository) RevokeClient(clientID int64, exceptFamilyID string) ([]models.RevokedToken, error) {
+	return r.revoke(clientID, "family_id <> ?", exceptFamilyID)
+}
+
+// revoke revokes the refresh tokens of a client matching a condition on their
+// family, and the access tokens issued with them
+func (r *TokenRepository) revoke(clientID int64, familyCondition, familyArg string) ([]models.RevokedToken, error) {
+	now := time.Now().UTC()
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(
+		"SELECT access_jti, access_expires_at FROM refresh_tokens WHERE client_id = ? AND "+familyCondition+" AND access_expires_at > ? FOR UPDATE",
+		clientID, familyArg, now,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get family access tokens: %w", err)
+	}
+
+	var revoked []models.RevokedToken
+	for rows.Next() {
+		var token models.RevokedToken
+		if err := rows.Scan(&token.JTI, &token.ExpiresAt); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan family access token: %w", err)
+		}
+		revoked = append(revoked, token)
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return nil, fmt.Errorf("error iterating family access tokens: %w", err)
+	}
+	rows.Close()
+
+	for _, token := range revoked {
+		if _, err := tx.Exec(
+			"INSERT IGNORE INTO revoked_tokens (jti, client_id, expires_at) VALUES (?, ?, ?)",
+			token.JTI, clientID, token.ExpiresAt.UTC(),
+		); err != nil {
+			return nil, fmt.Errorf("failed to revoke access token: %w", err)
+		}
+	}
+
+	if _, err := tx.Exec(
+		"UPDATE refresh_tokens SET revoked_at = ? WHERE client_id = ? AND "+familyCondition+" AND revoked_at IS NULL",
+		now, clientID, familyArg,
+	); err != nil {
+		return nil, fmt.Errorf("failed to revoke refresh tokens: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return revoked, nil
+}
+
+// IsRevoked reports whether an access token was revoked
+func (r *TokenRepository) IsRevoked(jti string) (bool, error) {
+	var exists int
+	err := r.db.QueryRow("SELECT 1 FROM revoked_tokens WHERE jti = ?", jti).Scan(&exists)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to check revoked token: %w", err)
+	}
+	return true, nil
+}
+
+// CreateAccountToken stores an account token, replacing the client's unused
+// tokens for the same purpose
+func (r *TokenRepository) CreateAccountToken(token *models.AccountToken) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(
+		"DELETE FROM account_tokens WHERE client_id = ? AND purpose = ? AND used_at IS NULL",
+		token.ClientID, token.Purpose,
+	); err != nil {
+		return fmt.Errorf("failed to delete previous account tokens: %w", err)
+	}
+
+	result, err := tx.Exec(`
+		INSERT INTO account_tokens (client_id, purpose, email, token_hash, expires_at)
+		VALUES (?, ?, ?, ?, ?)
+	`, token.ClientID, token.Purpose, token.Email, token.TokenHash, token.ExpiresAt.UTC())
+	if err != nil {
+		return fmt.Errorf("failed to create account token: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get last insert ID: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	token.ID = id
+	return nil
+}
+
+// GetAccountToken returns the account token stored under a hash for a
+// purpose, or nil if there is none
+func (r *TokenRepository) GetAccountToken(tokenHash, purpose string) (*models.AccountToken, error) {
+	query := `
+		SELECT id, client_id, purpose, email, token_hash, expires_at, used_at, created_at
+		FROM account_tokens
+		WHERE token_hash = ? AND purpose = ?
+	`
+
+	var token models.AccountToken
+	var usedAt sql.NullTime
+	err := r.db.QueryRow(query, tokenHash, purpose).Scan(
+		&token.ID,
+		&token.ClientID,
+		&token.Purpose,
+		&token.Email,
+		&token.TokenHash,
+		&token.ExpiresAt,
+		&usedAt,
+		&token.CreatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get account token: %w", err)
+	}
+
+	if usedAt.Valid {
+		token.UsedAt = &usedAt.Time
+	}
+	return &token, nil
+}
+
+// LastAccountTokenAt returns when the client was last issued a token for a
+// purpose, or nil if it never was
+func (r *TokenRepository) LastAccountTokenAt(clientID int64, purpose string) (*time.Time, error) {
+	var last sql.NullTime
+	err := r.db.QueryRow(
+		"SELECT MAX(created_at) FROM account_tokens WHERE client_id = ? AND purpose = ?",
+		clientID, purpose,
+	).Scan(&last)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get last account token: %w", err)
+	}
+	if !last.Valid {
+		return nil, nil
+	}
+	return &last.Time, nil
+}
+
+// useAccountToken marks an account token used. It returns false if it was
+// used already.
+func useAccountToken(tx *sql.Tx, id int64) (bool, error) {
+	result, err := tx.Exec(
+		"UPDATE account_tokens SET used_at = ? WHERE id = ? AND used_at IS NULL",
+		time.Now().UTC(), id,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to mark account token used: %w", err)
+	}
+
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	return updated > 0, nil
+}
+
+// DeleteExpired deletes refresh tokens, revoked access tokens and account
+// tokens that expired before now. Expired tokens are rejected anyway.
+func (r *TokenRepository) DeleteExpired(now time.Time) (int64, error) {
+	var deleted int64
+	for _, query := range []string{
+		"DELETE FROM refresh_tokens WHERE expires_at < ?",
+		"DELETE FROM revoked_tokens WHERE expires_at < ?",
+		"DELETE FROM account_tokens WHERE expires_at < ?",
+	} {
+		result, err := r.db.Exec(query, now.UTC())
+		if err != nil {
+			return deleted, fmt.Errorf("failed to delete expired tokens: %w", err)
+		}
+
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return deleted, fmt.Errorf("failed to get affected rows: %w", err)
+		}
+		deleted += affected
+	}
+	return deleted, nil
+}
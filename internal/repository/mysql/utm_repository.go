@@ -0,0 +1,90 @@
+package mysql
+
+import (
+	"database/sql"
+	"fmt"
+	"platform/internal/models"
+)
+
+type UTMRepository struct {
+	db *sql.DB
+}
+
+func NewUTMRepository(db *sql.DB) *UTMRepository {
+	return &UTMRepository{
+		db: db,
+	}
+}
+
+// GetSettings returns a client's default UTM settings, or nil if the client has none
+func (r *UTMRepository) GetSettings(clientID int64) (*models.UTMSettings, error) {
+	query := `
+		SELECT client_id, utm_source, utm_medium, utm_campaign, utm_term, utm_content, policy, updated_at
+		FROM client_utm_settings
+		WHERE client_id = ?
+	`
+
+	settings := &models.UTMSettings{}
+	var source, medium, campaign, term, content, policy sql.NullString
+	err := r.db.QueryRow(query, clientID).Scan(
+		&settings.ClientID,
+		&source,
+		&medium,
+		&campaign,
+		&term,
+		&content,
+		&policy,
+		&settings.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get utm settings: %w", err)
+	}
+
+	settings.UTMParams = models.UTMParams{
+		Source:   source.String,
+		Medium:   medium.String,
+		Campaign: campaign.String,
+		Term:     term.String,
+		Content:  content.String,
+	}
+	settings.Policy = policy.String
+	return settings, nil
+}
+
+// SetSettings creates or replaces a client's default UTM settings
+func (r *UTMRepository) SetSettings(clientID int64, update *models.UTMSettingsUpdate) error {
+	query := `
+		INSERT INTO client_utm_settings (client_id, utm_source, utm_medium, utm_campaign, utm_term, utm_content, policy)
+		VALUES (?, ` + utmPlaceholders + `)
+		ON DUPLICATE KEY UPDATE
+			utm_source = VALUES(utm_source),
+			utm_medium = VALUES(utm_medium),
+			utm_campaign = VALUES(utm_campaign),
+			utm_term = VALUES(utm_term),
+			utm_content = VALUES(utm_content),
+			policy = VALUES(policy)
+	`
+
+	args := append([]interface{}{clientID}, utmArgs(update.UTMParams, update.Policy)...)
+	if _, err := r.db.Exec(query, args...); err != nil {
+		return fmt.Errorf("failed to set utm settings: %w", err)
+	}
+	return nil
+}
+
+// DeleteSettings removes a client's default UTM settings, reporting whether they existed
+func (r *UTMRepository) DeleteSettings(clientID int64) (bool, error) {
+	result, err := r.db.Exec("DELETE FROM client_utm_settings WHERE client_id = ?", clientID)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete utm settings: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	return affected > 0, nil
+}
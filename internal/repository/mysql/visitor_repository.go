@@ -0,0 +1,54 @@
+package mysql
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// visitorSaltSize is the size in bytes of a daily visitor hash salt
+const visitorSaltSize = 32
+
+type VisitorRepository struct {
+	db *sql.DB
+}
+
+func NewVisitorRepository(db *sql.DB) *VisitorRepository {
+	return &VisitorRepository{
+		db: db,
+	}
+}
+
+// GetSalt returns the visitor hash salt of a UTC day, creating a random one if
+// the day has none yet. Concurrent gateways agree on the first salt stored.
+func (r *VisitorRepository) GetSalt(day time.Time) ([]byte, error) {
+	date := day.UTC().Format("2006-01-02")
+
+	salt := make([]byte, visitorSaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("failed to generate visitor salt: %w", err)
+	}
+	if _, err := r.db.Exec("INSERT IGNORE INTO visitor_salts (salt_date, salt) VALUES (?, ?)", date, salt); err != nil {
+		return nil, fmt.Errorf("failed to store visitor salt: %w", err)
+	}
+
+	if err := r.db.QueryRow("SELECT salt FROM visitor_salts WHERE salt_date = ?", date).Scan(&salt); err != nil {
+		return nil, fmt.Errorf("failed to get visitor salt: %w", err)
+	}
+	return salt, nil
+}
+
+// DeleteSaltsBefore deletes the salts of the UTC days before day
+func (r *VisitorRepository) DeleteSaltsBefore(day time.Time) (int64, error) {
+	result, err := r.db.Exec("DELETE FROM visitor_salts WHERE salt_date < ?", day.UTC().Format("2006-01-02"))
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete visitor salts: %w", err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	return deleted, nil
+}
@@ -0,0 +1,479 @@
+package mysql
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"platform/internal/models"
+	"strings"
+	"time"
+)
+
+type WebhookRepository struct {
+	db *sql.DB
+}
+
+func NewWebhookRepository(db *sql.DB) *WebhookRepository {
+	return &WebhookRepository{
+		db: db,
+	}
+}
+
+const webhookEndpointColumns = `
+	id, client_id, organization_id, url, secret, event_types, mapping_ids, min_fraud_score, max_fraud_score, is_active,
+	consecutive_failures, disabled_at, disabled_reason, created_at, updated_at
+`
+
+func (r *WebhookRepository) CreateEndpoint(endpoint *models.WebhookEndpoint) error {
+	eventTypes, err := json.Marshal(endpoint.EventTypes)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event types: %w", err)
+	}
+	var mappingIDs []byte
+	if len(endpoint.MappingIDs) > 0 {
+		mappingIDs, err = json.Marshal(endpoint.MappingIDs)
+		if err != nil {
+			return fmt.Errorf("failed to marshal mapping ids: %w", err)
+		}
+	}
+
+	query := `
+		INSERT INTO webhook_endpoints (client_id, organization_id, url, secret, event_types, mapping_ids, min_fraud_score, max_fraud_score)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	result, err := r.db.Exec(query, endpoint.ClientID, endpoint.OrganizationID, endpoint.URL, endpoint.Secret, eventTypes, mappingIDs, endpoint.MinFraudScore, endpoint.MaxFraudScore)
+	if err != nil {
+		return fmt.Errorf("failed to create webhook endpoint: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get last insert id: %w", err)
+	}
+
+	endpoint.ID = id
+	endpoint.IsActive = true
+	return nil
+}
+
+// GetScopedEndpoints returns the endpoints registered for an organization, or the
+// client's personal endpoints when the scope has no organization
+func (r *WebhookRepository) GetScopedEndpoints(scope models.Scope) ([]models.WebhookEndpoint, error) {
+	where, args := scopeCondition(scope)
+	query := `SELECT ` + webhookEndpointColumns + `
+		FROM webhook_endpoints
+		WHERE ` + where + `
+		ORDER BY created_at DESC
+	`
+	return r.queryEndpoints(query, args...)
+}
+
+// GetActiveEndpoints returns the enabled endpoints the events of the scope's
+// mappings may be sent to
+func (r *WebhookRepository) GetActiveEndpoints(scope models.Scope) ([]models.WebhookEndpoint, error) {
+	where, args := scopeCondition(scope)
+	query := `SELECT ` + webhookEndpointColumns + `
+		FROM webhook_endpoints
+		WHERE ` + where + ` AND is_active = TRUE
+	`
+	return r.queryEndpoints(query, args...)
+}
+
+func (r *WebhookRepository) GetEndpoint(scope models.Scope, id int64) (*models.WebhookEndpoint, error) {
+	where, args := scopeCondition(scope)
+	query := `SELECT ` + webhookEndpointColumns + `
+		FROM webhook_endpoints
+		WHERE ` + where + ` AND id = ?
+	`
+
+	endpoints, err := r.queryEndpoints(query, append(args, id)...)
+	if err != nil {
+		return nil, err
+	}
+	if len(endpoints) == 0 {
+		return nil, nil
+	}
+	return &endpoints[0], nil
+}
+
+func (r *WebhookRepository) DeleteEndpoint(scope models.Scope, id int64) (bool, error) {
+	where, args := scopeCondition(scope)
+	result, err := r.db.Exec("DELETE FROM webhook_endpoints WHERE "+where+" AND id = ?", append(args, id)...)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete webhook endpoint: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	return affected > 0, nil
+}
+
+// EnableEndpoint re-activates an endpoint and clears its failure counter
+func (r *WebhookRepository) EnableEndpoint(scope models.Scope, id int64) (bool, error) {
+	where, args := scopeCondition(scope)
+	query := `
+		UPDATE webhook_endpoints
+		SET is_active = TRUE, consecutive_failures = 0, disabled_at = NULL, disabled_reason = NULL
+		WHERE ` + where + ` AND id = ?
+	`
+
+	result, err := r.db.Exec(query, append(args, id)...)
+	if err != nil {
+		return false, fmt.Errorf("failed to enable webhook endpoint: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	return affected > 0, nil
+}
+
+// RecordEndpointFailure increments the consecutive failure counter and returns its new value
+func (r *WebhookRepository) RecordEndpointFailure(endpointID int64) (int, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("UPDATE webhook_endpoints SET consecutive_failures = consecutive_failures + 1 WHERE id = ?", endpointID); err != nil {
+		return 0, fmt.Errorf("failed to record endpoint failure: %w", err)
+	}
+
+	var failures int
+	if err := tx.QueryRow("SELECT consecutive_failures FROM webhook_endpoints WHERE id = ?", endpointID).Scan(&failures); err != nil {
+		return 0, fmt.Errorf("failed to read endpoint failures: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return failures, nil
+}
+
+func (r *WebhookRepository) ResetEndpointFailures(endpointID int64) error {
+	if _, err := r.db.Exec("UPDATE webhook_endpoints SET consecutive_failures = 0 WHERE id = ? AND consecutive_failures > 0", endpointID); err != nil {
+		return fmt.Errorf("failed to reset endpoint failures: %w", err)
+	}
+	return nil
+}
+
+func (r *WebhookRepository) DisableEndpoint(endpointID int64, reason string) error {
+	query := `
+		UPDATE webhook_endpoints
+		SET is_active = FALSE, disabled_at = ?, disabled_reason = ?
+		WHERE id = ?
+	`
+
+	if _, err := r.db.Exec(query, time.Now(), reason, endpointID); err != nil {
+		return fmt.Errorf("failed to disable webhook endpoint: %w", err)
+	}
+	return nil
+}
+
+func (r *WebhookRepository) queryEndpoints(query string, args ...interface{}) ([]models.WebhookEndpoint, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook endpoints: %w", err)
+	}
+	defer rows.Close()
+
+	var endpoints []models.WebhookEndpoint
+	for rows.Next() {
+		var endpoint models.WebhookEndpoint
+		var eventTypes, mappingIDs []byte
+		var organizationID, minFraudScore, maxFraudScore sql.NullInt64
+		var disabledAt sql.NullTime
+		var disabledReason sql.NullString
+		err := rows.Scan(
+			&endpoint.ID,
+			&endpoint.ClientID,
+			&organizationID,
+			&endpoint.URL,
+			&endpoint.Secret,
+			&eventTypes,
+			&mappingIDs,
+			&minFraudScore,
+			&maxFraudScore,
+			&endpoint.IsActive,
+			&endpoint.ConsecutiveFailures,
+			&disabledAt,
+			&disabledReason,
+			&endpoint.CreatedAt,
+			&endpoint.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook endpoint: %w", err)
+		}
+
+		if err := json.Unmarshal(eventTypes, &endpoint.EventTypes); err != nil {
+			return nil, fmt.Errorf("failed to decode event types: %w", err)
+		}
+		if len(mappingIDs) > 0 {
+			if err := json.Unmarshal(mappingIDs, &endpoint.MappingIDs); err != nil {
+				return nil, fmt.Errorf("failed to decode mapping ids: %w", err)
+			}
+		}
+		if organizationID.Valid {
+			endpoint.OrganizationID = &organizationID.Int64
+		}
+		if minFraudScore.Valid {
+			score := int(minFraudScore.Int64)
+			endpoint.MinFraudScore = &score
+		}
+		if maxFraudScore.Valid {
+			score := int(maxFraudScore.Int64)
+			endpoint.MaxFraudScore = &score
+		}
+		if disabledAt.Valid {
+			endpoint.DisabledAt = &disabledAt.Time
+		}
+		endpoint.DisabledReason = disabledReason.String
+
+		endpoints = append(endpoints, endpoint)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate webhook endpoints: %w", err)
+	}
+
+	return endpoints, nil
+}
+
+// CreateDelivery queues a delivery. An event already queued for the endpoint
+// is not queued again unless the delivery is a replay.
+func (r *WebhookRepository) CreateDelivery(delivery *models.WebhookDelivery) error {
+	query := `
+		INSERT INTO webhook_deliveries (
+			endpoint_id, event_id, event_type, replay_of, payload, status, next_attempt_at
+		) VALUES (?, ?, ?, ?, ?, 'pending', ?)
+		ON DUPLICATE KEY UPDATE id = LAST_INSERT_ID(id)
+	`
+
+	result, err := r.db.Exec(
+		query,
+		delivery.EndpointID,
+		delivery.EventID,
+		delivery.EventType,
+		delivery.ReplayOf,
+		delivery.Payload,
+		delivery.NextAttemptAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create webhook delivery: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get last insert id: %w", err)
+	}
+
+	delivery.ID = id
+	delivery.Status = models.WebhookStatusPending
+	return nil
+}
+
+// ClaimDueDeliveries locks pending deliveries whose retry time has come and pushes
+// their next attempt past the lease, so concurrent workers don't send them twice
+func (r *WebhookRepository) ClaimDueDeliveries(limit int, lease time.Duration) ([]models.WebhookDelivery, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		SELECT d.id, d.endpoint_id, d.event_id, d.event_type, d.payload, d.attempts, e.url, e.secret
+		FROM webhook_deliveries d
+		JOIN webhook_endpoints e ON e.id = d.endpoint_id
+		WHERE d.status = 'pending' AND d.next_attempt_at <= ? AND e.is_active = TRUE
+		ORDER BY d.next_attempt_at
+		LIMIT ?
+		FOR UPDATE OF d SKIP LOCKED
+	`
+
+	now := time.Now()
+	rows, err := tx.Query(query, now, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get due deliveries: %w", err)
+	}
+
+	var deliveries []models.WebhookDelivery
+	for rows.Next() {
+		var delivery models.WebhookDelivery
+		err := rows.Scan(
+			&delivery.ID,
+			&delivery.EndpointID,
+			&delivery.EventID,
+			&delivery.EventType,
+			&delivery.Payload,
+			&delivery.Attempts,
+			&delivery.URL,
+			&delivery.Secret,
+		)
+		if err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan webhook delivery: %w", err)
+		}
+		delivery.Status = models.WebhookStatusPending
+		deliveries = append(deliveries, delivery)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate webhook deliveries: %w", err)
+	}
+
+	if len(deliveries) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]string, len(deliveries))
+	args := []interface{}{now.Add(lease)}
+	for i, delivery := range deliveries {
+		ids[i] = "?"
+		args = append(args, delivery.ID)
+	}
+	update := "UPDATE webhook_deliveries SET next_attempt_at = ? WHERE id IN (" + strings.Join(ids, ", ") + ")"
+	if _, err := tx.Exec(update, args...); err != nil {
+		return nil, fmt.Errorf("failed to lease webhook deliveries: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return deliveries, nil
+}
+
+func (r *WebhookRepository) MarkDeliverySucceeded(id int64, attempts, responseStatus int) error {
+	query := `
+		UPDATE webhook_deliveries
+		SET status = 'succeeded', attempts = ?, response_status = ?, last_error = NULL,
+			last_attempt_at = ?, delivered_at = ?
+		WHERE id = ?
+	`
+
+	now := time.Now()
+	if _, err := r.db.Exec(query, attempts, responseStatus, now, now, id); err != nil {
+		return fmt.Errorf("failed to mark delivery succeeded: %w", err)
+	}
+	return nil
+}
+
+// MarkDeliveryFailed records a failed attempt. A nil nextAttempt means the delivery
+// has exhausted its retries and is marked as failed for good.
+func (r *WebhookRepository) MarkDeliveryFailed(id int64, attempts int, responseStatus *int, lastError string, nextAttempt *time.Time) error {
+	now := time.Now()
+	status := models.WebhookStatusPending
+	next := now
+	if nextAttempt != nil {
+		next = *nextAttempt
+	} else {
+		status = models.WebhookStatusFailed
+	}
+
+	query := `
+		UPDATE webhook_deliveries
+		SET status = ?, attempts = ?, response_status = ?, last_error = ?,
+			last_attempt_at = ?, next_attempt_at = ?
+		WHERE id = ?
+	`
+
+	if _, err := r.db.Exec(query, status, attempts, responseStatus, lastError, now, next, id); err != nil {
+		return fmt.Errorf("failed to mark delivery failed: %w", err)
+	}
+	return nil
+}
+
+const webhookDeliveryColumns = `
+	id, endpoint_id, event_id, event_type, replay_of, payload, status, attempts,
+	next_attempt_at, last_attempt_at, response_status, last_error, delivered_at, created_at
+`
+
+func (r *WebhookRepository) GetEndpointDeliveries(endpointID int64, status string, limit int) ([]models.WebhookDelivery, error) {
+	query := `SELECT ` + webhookDeliveryColumns + `
+		FROM webhook_deliveries
+		WHERE endpoint_id = ? AND (? = '' OR status = ?)
+		ORDER BY created_at DESC, id DESC
+		LIMIT ?
+	`
+	return r.queryDeliveries(query, endpointID, status, status, limit)
+}
+
+func (r *WebhookRepository) GetDelivery(endpointID, id int64) (*models.WebhookDelivery, error) {
+	query := `SELECT ` + webhookDeliveryColumns + `
+		FROM webhook_deliveries
+		WHERE endpoint_id = ? AND id = ?
+	`
+
+	deliveries, err := r.queryDeliveries(query, endpointID, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(deliveries) == 0 {
+		return nil, nil
+	}
+	return &deliveries[0], nil
+}
+
+func (r *WebhookRepository) queryDeliveries(query string, args ...interface{}) ([]models.WebhookDelivery, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	var deliveries []models.WebhookDelivery
+	for rows.Next() {
+		var delivery models.WebhookDelivery
+		var lastAttemptAt, deliveredAt sql.NullTime
+		var replayOf, responseStatus sql.NullInt64
+		var lastError sql.NullString
+		err := rows.Scan(
+			&delivery.ID,
+			&delivery.EndpointID,
+			&delivery.EventID,
+			&delivery.EventType,
+			&replayOf,
+			&delivery.Payload,
+			&delivery.Status,
+			&delivery.Attempts,
+			&delivery.NextAttemptAt,
+			&lastAttemptAt,
+			&responseStatus,
+			&lastError,
+			&deliveredAt,
+			&delivery.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook delivery: %w", err)
+		}
+
+		if replayOf.Valid {
+			delivery.ReplayOf = &replayOf.Int64
+		}
+		if lastAttemptAt.Valid {
+			delivery.LastAttemptAt = &lastAttemptAt.Time
+		}
+		if deliveredAt.Valid {
+			delivery.DeliveredAt = &deliveredAt.Time
+		}
+		if responseStatus.Valid {
+			status := int(responseStatus.Int64)
+			delivery.ResponseStatus = &status
+		}
+		delivery.LastError = lastError.String
+
+		deliveries = append(deliveries, delivery)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate webhook deliveries: %w", err)
+	}
+
+	return deliveries, nil
+}
@@ -9,10 +9,15 @@ import (
 	"platform/pkg/logger"
 )
 
+// consumerTag identifies the consumer on its channel so it can be cancelled
+const consumerTag = "click-events"
+
 type Consumer struct {
 	conn    *amqp091.Connection
 	channel *amqp091.Channel
 	queue   amqp091.Queue
+	// done is closed once the handler has returned for the last delivery
+	done chan struct{}
 }
 
 func NewConsumer(cfg *config.Config) (*Consumer, error) {
@@ -37,19 +42,12 @@ func NewConsumer(cfg *config.Config) (*Consumer, error) {
 		return nil, fmt.Errorf("failed to open channel: %w", err)
 	}
 
-	// Declare queue
-	q, err := ch.QueueDeclare(
-		cfg.RabbitMQ.Queue, // name
-		true,              // durable
-		false,             // delete when unused
-		false,             // exclusive
-		false,             // no-wait
-		nil,               // arguments
-	)
+	// Declare exchange and queue
+	q, err := declareClickTopology(ch, cfg)
 	if err != nil {
 		ch.Close()
 		conn.Close()
-		return nil, fmt.Errorf("failed to declare queue: %w", err)
+		return nil, err
 	}
 
 	return &Consumer{
@@ -59,7 +57,15 @@ func NewConsumer(cfg *config.Config) (*Consumer, error) {
 	}, nil
 }
 
+// Close stops consuming and waits for the delivery being handled, if any, to be
+// acknowledged before closing the connection
 func (c *Consumer) Close() {
+	if c.done != nil {
+		if err := c.channel.Cancel(consumerTag, false); err != nil {
+			logger.Error("Failed to cancel consumer", "error", err.Error())
+		}
+		<-c.done
+	}
 	if c.channel != nil {
 		c.channel.Close()
 	}
@@ -68,10 +74,10 @@ func (c *Consumer) Close() {
 	}
 }
 
-func (c *Consumer) Consume(handler func(*models.Request) error) error {
+func (c *Consumer) Consume(handler func(*models.ClickEvent) error) error {
 	msgs, err := c.channel.Consume(
 		c.queue.Name, // queue
-		"",           // consumer
+		consumerTag,  // consumer
 		false,        // auto-ack
 		false,        // exclusive
 		false,        // no-local
@@ -82,18 +88,20 @@ func (c *Consumer) Consume(handler func(*models.Request) error) error {
 		return fmt.Errorf("failed to register a consumer: %w", err)
 	}
 
+	c.done = make(chan struct{})
 	go func() {
+		defer close(c.done)
 		for d := range msgs {
-			var request models.Request
-			if err := json.Unmarshal(d.Body, &request); err != nil {
-				logger.Error("Failed to unmarshal request", "error", err.Error())
+			var event models.ClickEvent
+			if err := json.Unmarshal(d.Body, &event); err != nil {
+				logger.Error("Failed to unmarshal click event", "error", err.Error())
 				// Reject the message and requeue it
 				d.Reject(true)
 				continue
 			}
 
-			if err := handler(&request); err != nil {
-				logger.Error("Failed to process request", "error", err.Error())
+			if err := handler(&event); err != nil {
+				logger.Error("Failed to process click event", "event_id", event.EventID, "error", err.Error())
 				// Reject the message and requeue it
 				d.Reject(true)
 				continue
@@ -101,7 +109,7 @@ func (c *Consumer) Consume(handler func(*models.Request) error) error {
 
 			// Acknowledge the message after successful processing
 			d.Ack(false)
-			logger.Info("Successfully processed request", "request_id", request.ID)
+			logger.Info("Successfully processed click event", "event_id", event.EventID, "request_log_id", event.Request.ID)
 		}
 	}()
 
@@ -10,9 +10,10 @@ import (
 )
 
 type Publisher struct {
-	conn    *amqp091.Connection
-	channel *amqp091.Channel
-	queue   amqp091.Queue
+	conn     *amqp091.Connection
+	channel  *amqp091.Channel
+	queue    amqp091.Queue
+	exchange string
 }
 
 func NewPublisher(cfg *config.Config) (*Publisher, error) {
@@ -37,25 +38,19 @@ func NewPublisher(cfg *config.Config) (*Publisher, error) {
 		return nil, fmt.Errorf("failed to open channel: %w", err)
 	}
 
-	// Declare queue
-	q, err := ch.QueueDeclare(
-		cfg.RabbitMQ.Queue, // name
-		true,              // durable
-		false,             // delete when unused
-		false,             // exclusive
-		false,             // no-wait
-		nil,               // arguments
-	)
+	// Declare exchange and queue
+	q, err := declareClickTopology(ch, cfg)
 	if err != nil {
 		ch.Close()
 		conn.Close()
-		return nil, fmt.Errorf("failed to declare queue: %w", err)
+		return nil, err
 	}
 
 	return &Publisher{
-		conn:    conn,
-		channel: ch,
-		queue:   q,
+		conn:     conn,
+		channel:  ch,
+		queue:    q,
+		exchange: cfg.RabbitMQ.Exchange,
 	}, nil
 }
 
@@ -68,29 +63,32 @@ func (p *Publisher) Close() {
 	}
 }
 
-func (p *Publisher) PublishRequest(request *models.Request) error {
-	// Convert request to JSON
-	body, err := json.Marshal(request)
+func (p *Publisher) PublishClickEvent(event *models.ClickEvent) error {
+	// Convert event to JSON
+	body, err := json.Marshal(event)
 	if err != nil {
-		return fmt.Errorf("failed to marshal request: %w", err)
+		return fmt.Errorf("failed to marshal click event: %w", err)
 	}
 
 	// Publish message
 	err = p.channel.PublishWithContext(
 		nil,           // context
-		"",            // exchange
-		p.queue.Name,  // routing key
+		p.exchange,    // exchange
+		"",            // routing key
 		false,         // mandatory
 		false,         // immediate
 		amqp091.Publishing{
-			ContentType: "application/json",
-			Body:        body,
+			ContentType:  "application/json",
+			DeliveryMode: amqp091.Persistent,
+			MessageId:    event.EventID,
+			Timestamp:    event.Timestamp,
+			Body:         body,
 		},
 	)
 	if err != nil {
 		return fmt.Errorf("failed to publish message: %w", err)
 	}
 
-	logger.Info("Published request to queue", "request_id", request.ID)
+	logger.Info("Published click event to exchange", "event_id", event.EventID, "request_id", event.RequestID)
 	return nil
 } 
\ No newline at end of file
@@ -0,0 +1,253 @@
+package rabbitmq
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/rabbitmq/amqp091-go"
+	"platform/internal/config"
+	"platform/internal/models"
+	"platform/pkg/logger"
+	"platform/pkg/metrics"
+	"sync"
+	"time"
+)
+
+// Delays between attempts to resubscribe after the connection or channel drops
+const (
+	resubscribeInitialBackoff = time.Second
+	resubscribeMaxBackoff     = 30 * time.Second
+)
+
+var (
+	subscriberConnected  = metrics.Int("stream_subscriber_connected")
+	subscriberReconnects = metrics.Int("stream_subscriber_reconnects")
+)
+
+// Subscriber receives a copy of every click event through its own exclusive queue
+// bound to the click events exchange. Unlike Consumer, deliveries are not
+// acknowledged individually and are lost when the subscriber disconnects, which
+// suits live views that only care about what happens while they are connected.
+// When the connection or channel drops, the subscriber reconnects with backoff
+// and declares a new queue; events published in between are missed.
+type Subscriber struct {
+	cfg *config.Config
+	url string
+
+	mu      sync.Mutex
+	conn    *amqp091.Connection
+	channel *amqp091.Channel
+	queue   amqp091.Queue
+	closed  bool
+	// closes reports why the channel, or the connection under it, was closed
+	closes chan *amqp091.Error
+}
+
+func NewSubscriber(cfg *config.Config) (*Subscriber, error) {
+	// Create RabbitMQ connection URL
+	url := fmt.Sprintf("amqp://%s:%s@%s:%s/",
+		cfg.RabbitMQ.User,
+		cfg.RabbitMQ.Password,
+		cfg.RabbitMQ.Host,
+		cfg.RabbitMQ.Port,
+	)
+
+	s := &Subscriber{
+		cfg: cfg,
+		url: url,
+	}
+	if err := s.connect(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// connect dials RabbitMQ and declares the subscriber's queue
+func (s *Subscriber) connect() error {
+	// Connect to RabbitMQ
+	conn, err := amqp091.Dial(s.url)
+	if err != nil {
+		return fmt.Errorf("failed to connect to RabbitMQ: %w", err)
+	}
+
+	// Create channel
+	ch, err := conn.Channel()
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to open channel: %w", err)
+	}
+
+	// Declare exchange and the worker queue so events published before the
+	// worker starts are not lost
+	if _, err := declareClickTopology(ch, s.cfg); err != nil {
+		ch.Close()
+		conn.Close()
+		return err
+	}
+
+	// Declare a server-named queue that is removed when this subscriber goes away.
+	// Its length is capped so a stalled subscriber only drops its oldest events.
+	q, err := ch.QueueDeclare(
+		"",    // name
+		false, // durable
+		true,  // delete when unused
+		true,  // exclusive
+		false, // no-wait
+		amqp091.Table{
+			"x-max-length": s.cfg.Stream.QueueMaxLength,
+			"x-overflow":   "drop-head",
+		}, // arguments
+	)
+	if err != nil {
+		ch.Close()
+		conn.Close()
+		return fmt.Errorf("failed to declare queue: %w", err)
+	}
+
+	// Bind queue to exchange
+	if err := ch.QueueBind(q.Name, "", s.cfg.RabbitMQ.Exchange, false, nil); err != nil {
+		ch.Close()
+		conn.Close()
+		return fmt.Errorf("failed to bind queue: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		ch.Close()
+		conn.Close()
+		return fmt.Errorf("subscriber closed")
+	}
+	s.conn = conn
+	s.channel = ch
+	s.queue = q
+	s.closes = ch.NotifyClose(make(chan *amqp091.Error, 1))
+	return nil
+}
+
+// Close stops the subscription; it is not reconnected afterwards
+func (s *Subscriber) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closed = true
+	if s.channel != nil {
+		s.channel.Close()
+	}
+	if s.conn != nil {
+		s.conn.Close()
+	}
+}
+
+// Subscribe passes every received click event to handler, which must not block.
+// The subscription survives lost connections until the subscriber is closed.
+func (s *Subscriber) Subscribe(handler func(*models.ClickEvent)) error {
+	msgs, err := s.consume()
+	if err != nil {
+		return err
+	}
+	subscriberConnected.Set(1)
+
+	go func() {
+		for {
+			reason := s.receive(msgs, handler)
+			subscriberConnected.Set(0)
+
+			msgs = s.resubscribe(reason)
+			if msgs == nil {
+				return
+			}
+			subscriberConnected.Set(1)
+			subscriberReconnects.Add(1)
+		}
+	}()
+
+	return nil
+}
+
+func (s *Subscriber) consume() (<-chan amqp091.Delivery, error) {
+	s.mu.Lock()
+	ch, queue := s.channel, s.queue.Name
+	s.mu.Unlock()
+
+	msgs, err := ch.Consume(
+		queue, // queue
+		"",    // consumer
+		true,  // auto-ack
+		true,  // exclusive
+		false, // no-local
+		false, // no-wait
+		nil,   // args
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to register a subscriber: %w", err)
+	}
+	return msgs, nil
+}
+
+// receive hands deliveries to handler until the channel or connection closes
+// and returns why it closed, or nil if it was closed on purpose
+func (s *Subscriber) receive(msgs <-chan amqp091.Delivery, handler func(*models.ClickEvent)) *amqp091.Error {
+	s.mu.Lock()
+	closes := s.closes
+	s.mu.Unlock()
+
+	for {
+		select {
+		case reason := <-closes:
+			return reason
+		case d, ok := <-msgs:
+			if !ok {
+				return <-closes
+			}
+			var event models.ClickEvent
+			if err := json.Unmarshal(d.Body, &event); err != nil {
+				logger.Error("Failed to unmarshal click event", "error", err.Error())
+				continue
+			}
+			handler(&event)
+		}
+	}
+}
+
+// resubscribe reconnects and consumes from a new queue, doubling the delay
+// between attempts up to resubscribeMaxBackoff. It returns nil once the
+// subscriber is closed.
+func (s *Subscriber) resubscribe(reason *amqp091.Error) <-chan amqp091.Delivery {
+	backoff := resubscribeInitialBackoff
+	for {
+		s.mu.Lock()
+		closed, queue := s.closed, s.queue.Name
+		if !closed {
+			// Release whatever is left of the broken connection
+			s.channel.Close()
+			s.conn.Close()
+		}
+		s.mu.Unlock()
+		if closed {
+			return nil
+		}
+
+		if reason != nil {
+			logger.Error("Click event subscription closed", "queue", queue, "error", reason.Error())
+			reason = nil
+		}
+		logger.Info("Reconnecting click event subscription", "retry_in", backoff.String())
+		time.Sleep(backoff)
+		if backoff *= 2; backoff > resubscribeMaxBackoff {
+			backoff = resubscribeMaxBackoff
+		}
+
+		if err := s.connect(); err != nil {
+			logger.Error("Failed to reconnect click event subscription", "error", err.Error())
+			continue
+		}
+		msgs, err := s.consume()
+		if err != nil {
+			logger.Error("Failed to reconnect click event subscription", "error", err.Error())
+			continue
+		}
+
+		logger.Info("Click event subscription reconnected")
+		return msgs
+	}
+}
@@ -0,0 +1,46 @@
+package rabbitmq
+
+import (
+	"fmt"
+	"github.com/rabbitmq/amqp091-go"
+	"platform/internal/config"
+)
+
+// declareClickTopology declares the fanout exchange click events are published to
+// and the durable worker queue bound to it. Every other subscriber, such as the
+// live stream of each gateway replica, binds its own queue to the same exchange.
+func declareClickTopology(ch *amqp091.Channel, cfg *config.Config) (amqp091.Queue, error) {
+	// Declare exchange
+	err := ch.ExchangeDeclare(
+		cfg.RabbitMQ.Exchange, // name
+		"fanout",              // type
+		true,                  // durable
+		false,                 // auto-deleted
+		false,                 // internal
+		false,                 // no-wait
+		nil,                   // arguments
+	)
+	if err != nil {
+		return amqp091.Queue{}, fmt.Errorf("failed to declare exchange: %w", err)
+	}
+
+	// Declare queue
+	q, err := ch.QueueDeclare(
+		cfg.RabbitMQ.Queue, // name
+		true,               // durable
+		false,              // delete when unused
+		false,              // exclusive
+		false,              // no-wait
+		nil,                // arguments
+	)
+	if err != nil {
+		return amqp091.Queue{}, fmt.Errorf("failed to declare queue: %w", err)
+	}
+
+	// Bind queue to exchange
+	if err := ch.QueueBind(q.Name, "", cfg.RabbitMQ.Exchange, false, nil); err != nil {
+		return amqp091.Queue{}, fmt.Errorf("failed to bind queue: %w", err)
+	}
+
+	return q, nil
+}
@@ -0,0 +1,410 @@
+package retention
+
+import (
+	"context"
+	"fmt"
+	"platform/internal/config"
+	"platform/internal/models"
+	"platform/internal/repository/mysql"
+	"platform/pkg/logger"
+	"platform/pkg/metrics"
+	"time"
+)
+
+// catchAllPartition is the MAXVALUE partition new monthly partitions are split from
+const catchAllPartition = "pmax"
+
+// Actions recorded for partitions in a retention report
+const (
+	ActionCreate = "create"
+	ActionDrop   = "drop"
+)
+
+var (
+	rowsDeleted       = metrics.Map("retention_rows_deleted")
+	rowsExpired       = metrics.Map("retention_rows_expired")
+	partitionsDropped = metrics.Map("retention_partitions_dropped")
+	partitionsCreated = metrics.Map("retention_partitions_created")
+	runs              = metrics.Int("retention_runs")
+	failures          = metrics.Int("retention_failures")
+	lastRun           = metrics.Int("retention_last_run_unix")
+	lastDuration      = metrics.Int("retention_last_run_duration_ms")
+)
+
+// Purger deletes raw click data older than the configured retention. Rollups are
+// kept: they are separate tables without references to the raw rows.
+type Purger struct {
+	repo *mysql.RetentionRepository
+	cfg  config.RetentionConfig
+}
+
+func NewPurger(repo *mysql.RetentionRepository, cfg config.RetentionConfig) *Purger {
+	return &Purger{
+		repo: repo,
+		cfg:  cfg,
+	}
+}
+
+// DefaultDays returns the platform retention of a table; zero keeps data forever
+func DefaultDays(cfg config.RetentionConfig, table string) int {
+	switch table {
+	case models.RetentionRequestLogs:
+		return cfg.RequestLogsDays
+	case models.RetentionRedirectHistory:
+		return cfg.RedirectHistoryDays
+	default:
+		return 0
+	}
+}
+
+// Run purges once per interval until the context is cancelled
+func (p *Purger) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		report, err := p.Purge(ctx, p.cfg.DryRun)
+		if err != nil {
+			logger.Error("Retention purge failed", "error", err)
+		} else {
+			logReport(report)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func logReport(report *models.RetentionReport) {
+	for _, partition := range report.Partitions {
+		logger.Info("Retention partition",
+			"dry_run", report.DryRun,
+			"table", partition.Table,
+			"partition", partition.Partition,
+			"action", partition.Action,
+			"rows", partition.Rows,
+		)
+	}
+	for _, scope := range report.Scopes {
+		if scope.Rows == 0 {
+			continue
+		}
+		logger.Info("Retention purge",
+			"dry_run", report.DryRun,
+			"table", scope.Table,
+			"client_id", scope.ClientID,
+			"cutoff", scope.Cutoff,
+			"rows", scope.Rows,
+		)
+	}
+	logger.Info("Retention run finished", "dry_run", report.DryRun, "duration", report.FinishedAt.Sub(report.StartedAt))
+}
+
+// Purge applies the retention policies once. In a dry run nothing is changed
+// and the report lists what would be deleted, created and dropped.
+func (p *Purger) Purge(ctx context.Context, dryRun bool) (*models.RetentionReport, error) {
+	report := &models.RetentionReport{
+		StartedAt: time.Now().UTC(),
+		DryRun:    dryRun,
+		Scopes:    []models.RetentionScopeReport{},
+	}
+
+	runs.Add(1)
+	err := p.purge(ctx, report)
+	report.FinishedAt = time.Now().UTC()
+	if err != nil {
+		failures.Add(1)
+		return nil, err
+	}
+
+	lastRun.Set(report.FinishedAt.Unix())
+	lastDuration.Set(report.FinishedAt.Sub(report.StartedAt).Milliseconds())
+	return report, nil
+}
+
+func (p *Purger) purge(ctx context.Context, report *models.RetentionReport) error {
+	overrides, err := p.overrides()
+	if err != nil {
+		return err
+	}
+
+	if p.cfg.Partitioned {
+		if err := p.managePartitions(models.RetentionRequestLogs, overrides, report); err != nil {
+			return err
+		}
+	}
+
+	for _, table := range models.RetentionTables {
+		limit, ok, err := p.archiveLimit(table)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+
+		clientIDs := make([]int64, 0, len(overrides[table]))
+		for clientID, days := range overrides[table] {
+			clientIDs = append(clientIDs, clientID)
+			scope := models.RetentionScope{ClientID: clientID, Cutoff: limitCutoff(cutoff(report.StartedAt, days), limit)}
+			if err := p.purgeScope(ctx, table, days, scope, report); err != nil {
+				return err
+			}
+		}
+
+		days := DefaultDays(p.cfg, table)
+		if days == 0 {
+			continue
+		}
+		scope := models.RetentionScope{ExcludeClientIDs: clientIDs, Cutoff: limitCutoff(cutoff(report.StartedAt, days), limit)}
+		if err := p.purgeScope(ctx, table, days, scope, report); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// archiveLimit returns the time before which rows of a table may be purged when
+// RequireArchive is set: the end of the archived ranges. It reports false when
+// nothing may be purged yet, and a nil limit when archiving is not required.
+func (p *Purger) archiveLimit(table string) (*time.Time, bool, error) {
+	if !p.cfg.RequireArchive {
+		return nil, true, nil
+	}
+
+	until, err := p.repo.ArchivedUntil(table)
+	if err != nil {
+		return nil, false, err
+	}
+	return until, until != nil, nil
+}
+
+func limitCutoff(cutoff time.Time, limit *time.Time) time.Time {
+	if limit != nil && limit.Before(cutoff) {
+		return *limit
+	}
+	return cutoff
+}
+
+// overrides returns the per-client retention days keyed by table and client
+func (p *Purger) overrides() (map[string]map[int64]int, error) {
+	policies, err := p.repo.GetPolicies()
+	if err != nil {
+		return nil, err
+	}
+
+	overrides := make(map[string]map[int64]int)
+	for _, policy := range policies {
+		if overrides[policy.Table] == nil {
+			overrides[policy.Table] = make(map[int64]int)
+		}
+		overrides[policy.Table][policy.ClientID] = policy.Days
+	}
+	return overrides, nil
+}
+
+// configuredDays returns every retention in effect for the raw tables: the
+// defaults and all client overrides
+func (p *Purger) configuredDays(overrides map[string]map[int64]int) []int {
+	var days []int
+	for _, table := range models.RetentionTables {
+		days = append(days, DefaultDays(p.cfg, table))
+		for _, d := range overrides[table] {
+			days = append(days, d)
+		}
+	}
+	return days
+}
+
+func cutoff(now time.Time, days int) time.Time {
+	return now.AddDate(0, 0, -days)
+}
+
+// purgeScope deletes the scope's expired rows in batches, pausing between them so
+// the purge does not starve the worker's writes
+func (p *Purger) purgeScope(ctx context.Context, table string, days int, scope models.RetentionScope, report *models.RetentionReport) error {
+	result := models.RetentionScopeReport{
+		Table:    table,
+		ClientID: scope.ClientID,
+		Days:     days,
+		Cutoff:   scope.Cutoff,
+	}
+
+	if report.DryRun {
+		count, err := p.repo.CountExpired(table, scope)
+		if err != nil {
+			return err
+		}
+		result.Rows = count
+		rowsExpired.Add(table, count)
+		report.Scopes = append(report.Scopes, result)
+		return nil
+	}
+
+	for {
+		deleted, err := p.repo.DeleteExpired(table, scope, p.cfg.BatchSize)
+		if err != nil {
+			return err
+		}
+		result.Rows += deleted
+		rowsDeleted.Add(table, deleted)
+
+		if deleted < int64(p.cfg.BatchSize) {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			report.Scopes = append(report.Scopes, result)
+			return ctx.Err()
+		case <-time.After(p.cfg.BatchPause):
+		}
+	}
+
+	report.Scopes = append(report.Scopes, result)
+	return nil
+}
+
+// EarliestIntactDay returns the first UTC day for which no client's raw clicks
+// may have been purged yet, or false when no retention applies. Rebuilding
+// rollups before that day would replace them with partial counts.
+func (p *Purger) EarliestIntactDay(now time.Time) (time.Time, bool, error) {
+	overrides, err := p.overrides()
+	if err != nil {
+		return time.Time{}, false, err
+	}
+
+	shortest := 0
+	for _, d := range p.configuredDays(overrides) {
+		if d > 0 && (shortest == 0 || d < shortest) {
+			shortest = d
+		}
+	}
+	if shortest == 0 {
+		return time.Time{}, false, nil
+	}
+
+	// The day containing the cutoff may already be partially purged
+	day := cutoff(now.UTC(), shortest).Truncate(24 * time.Hour)
+	return day.AddDate(0, 0, 1), true, nil
+}
+
+// managePartitions creates monthly partitions ahead of time and drops those that
+// only hold expired rows. A partition is dropped only when it is older than the
+// longest retention of both raw tables, so neither a client's override nor
+// retained redirect history referencing a request log loses rows early; the
+// row-level purge handles shorter retentions.
+func (p *Purger) managePartitions(table string, overrides map[string]map[int64]int, report *models.RetentionReport) error {
+	partitions, err := p.repo.GetPartitions(table)
+	if err != nil {
+		return err
+	}
+	if len(partitions) == 0 || partitions[len(partitions)-1].Name != catchAllPartition || partitions[len(partitions)-1].LessThan != nil {
+		return fmt.Errorf("%s is not partitioned by month with a %s partition; apply scripts/migrations/optional/partition_%s.sql", table, catchAllPartition, table)
+	}
+
+	if err := p.createPartitions(table, partitions, report); err != nil {
+		return err
+	}
+
+	longest := 0
+	for _, d := range p.configuredDays(overrides) {
+		if d == 0 {
+			// Some data is kept forever, so no partition only holds expired rows
+			return nil
+		}
+		if d > longest {
+			longest = d
+		}
+	}
+
+	limit, ok, err := p.archiveLimit(table)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return nil
+	}
+	dropBefore := limitCutoff(cutoff(report.StartedAt, longest), limit)
+	for _, partition := range partitions {
+		if partition.LessThan == nil || partition.LessThan.After(dropBefore) {
+			continue
+		}
+
+		if !report.DryRun {
+			if err := p.repo.DropPartition(table, partition.Name); err != nil {
+				return err
+			}
+			partitionsDropped.Add(table, 1)
+			rowsDeleted.Add(table, partition.Rows)
+		}
+		report.Partitions = append(report.Partitions, models.RetentionPartitionReport{
+			Table:     table,
+			Partition: partition.Name,
+			LessThan:  *partition.LessThan,
+			Action:    ActionDrop,
+			Rows:      partition.Rows,
+		})
+	}
+
+	return nil
+}
+
+// createPartitions splits monthly partitions off the catch-all up to
+// PartitionsAhead months after the current one
+func (p *Purger) createPartitions(table string, partitions []models.Partition, report *models.RetentionReport) error {
+	var next time.Time
+	if len(partitions) > 1 {
+		// Continue after the last monthly partition
+		next = *partitions[len(partitions)-2].LessThan
+	} else {
+		// First run after partitioning: start at the month of the oldest row
+		oldest, err := p.repo.OldestTimestamp(table)
+		if err != nil {
+			return err
+		}
+		next = monthStart(report.StartedAt)
+		if oldest != nil {
+			next = monthStart(*oldest)
+		}
+	}
+
+	last := monthStart(report.StartedAt).AddDate(0, p.cfg.PartitionsAhead+1, 0)
+	var created []models.Partition
+	for month := next; month.Before(last); month = month.AddDate(0, 1, 0) {
+		lessThan := month.AddDate(0, 1, 0)
+		created = append(created, models.Partition{
+			Name:     "p" + month.Format("200601"),
+			LessThan: &lessThan,
+		})
+	}
+	if len(created) == 0 {
+		return nil
+	}
+
+	if !report.DryRun {
+		if err := p.repo.SplitPartition(table, catchAllPartition, created); err != nil {
+			return err
+		}
+		partitionsCreated.Add(table, int64(len(created)))
+	}
+	for _, partition := range created {
+		report.Partitions = append(report.Partitions, models.RetentionPartitionReport{
+			Table:     table,
+			Partition: partition.Name,
+			LessThan:  *partition.LessThan,
+			Action:    ActionCreate,
+		})
+	}
+
+	return nil
+}
+
+func monthStart(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
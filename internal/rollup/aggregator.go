@@ -0,0 +1,81 @@
+package rollup
+
+import (
+	"context"
+	"platform/internal/models"
+	"platform/internal/repository/mysql"
+	"platform/pkg/logger"
+	"sync"
+	"time"
+)
+
+// Aggregator accumulates click counts and visitor sketches in memory and writes
+// them to the rollup tables once per flush interval, as upsert-increments and
+// sketch merges. Batches that fail to flush are kept for the next attempt;
+// batches lost in a crash can be recovered with the rebuild-rollups command.
+type Aggregator struct {
+	repo     *mysql.RollupRepository
+	interval time.Duration
+
+	mu      sync.Mutex
+	pending *models.RollupBatch
+}
+
+func NewAggregator(repo *mysql.RollupRepository, interval time.Duration) *Aggregator {
+	return &Aggregator{
+		repo:     repo,
+		interval: interval,
+		pending:  models.NewRollupBatch(),
+	}
+}
+
+// Add counts a persisted click event
+func (a *Aggregator) Add(event *models.ClickEvent) {
+	if !event.Matched() {
+		return
+	}
+
+	a.mu.Lock()
+	add(a.pending, event)
+	a.mu.Unlock()
+}
+
+// Run flushes pending counts every interval until the context is cancelled.
+// Callers flush once more after they stop adding events.
+func (a *Aggregator) Run(ctx context.Context) {
+	ticker := time.NewTicker(a.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := a.Flush(); err != nil {
+				logger.Error("Failed to flush click rollups", "error", err)
+			}
+		}
+	}
+}
+
+// Flush writes the pending batch to the hourly and daily rollups
+func (a *Aggregator) Flush() error {
+	a.mu.Lock()
+	batch := a.pending
+	a.pending = models.NewRollupBatch()
+	a.mu.Unlock()
+
+	if batch.Empty() {
+		return nil
+	}
+
+	if err := a.repo.Increment(batch); err != nil {
+		// Merge the batch back so it is retried with the next flush
+		a.mu.Lock()
+		a.pending.Merge(batch)
+		a.mu.Unlock()
+		return err
+	}
+
+	return nil
+}
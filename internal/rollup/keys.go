@@ -0,0 +1,77 @@
+package rollup
+
+import (
+	"platform/internal/hll"
+	"platform/internal/models"
+	"strings"
+	"time"
+)
+
+// KeyFor returns the rollup counter a persisted click event increments
+func KeyFor(event *models.ClickEvent) models.RollupKey {
+	return models.RollupKey{
+		Hour:           event.Timestamp.UTC().Truncate(time.Hour),
+		MappingID:      event.MappingID,
+		ClientID:       event.ClientID,
+		OrganizationID: event.OrganizationID,
+		Variant:        event.Variant,
+		Country:        country(&event.Request),
+		DeviceClass:    deviceClass(&event.Request),
+		IsBot:          event.IsBot,
+	}
+}
+
+// VisitorKeyFor returns the visitor sketch a persisted click event is added to
+func VisitorKeyFor(event *models.ClickEvent) models.VisitorKey {
+	return models.VisitorKey{
+		Hour:           event.Timestamp.UTC().Truncate(time.Hour),
+		MappingID:      event.MappingID,
+		ClientID:       event.ClientID,
+		OrganizationID: event.OrganizationID,
+		IsBot:          event.IsBot,
+	}
+}
+
+// add counts a persisted click event in the batch and adds its visitor to the
+// visitor sketch
+func add(batch *models.RollupBatch, event *models.ClickEvent) {
+	key := KeyFor(event)
+	counts := batch.Counts[key]
+	counts.Clicks++
+	if event.Unique {
+		counts.UniqueClicks++
+	}
+	batch.Counts[key] = counts
+
+	if event.VisitorID == "" {
+		return
+	}
+	visitorKey := VisitorKeyFor(event)
+	sketch, ok := batch.Visitors[visitorKey]
+	if !ok {
+		sketch = hll.New()
+		batch.Visitors[visitorKey] = sketch
+	}
+	sketch.Add(event.VisitorID)
+}
+
+// country returns the ISO country code found by GeoIP, or else the one set by a
+// CDN in front of the gateway
+func country(request *models.Request) string {
+	if request.Geo != nil && request.Geo.CountryCode != "" {
+		return request.Geo.CountryCode
+	}
+	code := strings.ToUpper(request.Header("Cf-Ipcountry"))
+	if len(code) != 2 {
+		return ""
+	}
+	return code
+}
+
+// deviceClass returns the class parsed from the request's User-Agent
+func deviceClass(request *models.Request) string {
+	if request.UserAgent == nil {
+		return models.DeviceUnknown
+	}
+	return request.UserAgent.DeviceClass
+}
@@ -0,0 +1,35 @@
+package rollup
+
+import (
+	"fmt"
+	"platform/internal/models"
+	"platform/internal/repository/mysql"
+	"platform/internal/useragent"
+	"platform/pkg/logger"
+	"time"
+)
+
+// Rebuild recomputes the hourly and daily rollups of every UTC day in [from, to)
+// from the raw click history, replacing whatever the rollups held for those days.
+// User-Agents not parsed when the clicks were stored are parsed with the parser.
+func Rebuild(repo *mysql.RollupRepository, parser *useragent.Parser, from, to time.Time) error {
+	for day := from.UTC(); day.Before(to); day = day.AddDate(0, 0, 1) {
+		batch := models.NewRollupBatch()
+		err := repo.ForEachClick(day, day.AddDate(0, 0, 1), func(event *models.ClickEvent) error {
+			if event.Request.UserAgent == nil {
+				event.Request.UserAgent = parser.Parse(event.Request.Header("User-Agent"))
+			}
+			add(batch, event)
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to read clicks of %s: %w", day.Format("2006-01-02"), err)
+		}
+
+		if err := repo.ReplaceDay(day, batch); err != nil {
+			return fmt.Errorf("failed to replace rollups of %s: %w", day.Format("2006-01-02"), err)
+		}
+		logger.Info("Rebuilt click rollups", "day", day.Format("2006-01-02"), "counters", len(batch.Counts), "visitor_sketches", len(batch.Visitors))
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package stream
+
+import (
+	"platform/internal/models"
+	"sync"
+	"sync/atomic"
+)
+
+// Hub fans click events out to the live stream clients connected to this
+// gateway replica. Publishing never blocks: each subscription has its own
+// buffer, and events for a subscription whose buffer is full are dropped and
+// counted instead of holding up the other subscriptions.
+type Hub struct {
+	bufferSize int
+
+	mu            sync.RWMutex
+	subscriptions map[*Subscription]struct{}
+}
+
+func NewHub(bufferSize int) *Hub {
+	return &Hub{
+		bufferSize:    bufferSize,
+		subscriptions: make(map[*Subscription]struct{}),
+	}
+}
+
+// Subscription receives the matched click events of one scope, optionally
+// restricted to a set of mappings
+type Subscription struct {
+	scope      models.Scope
+	mappingIDs map[int64]bool
+	events     chan *models.ClickEvent
+	dropped    atomic.Int64
+}
+
+// Events returns the channel the subscription's click events are delivered on
+func (s *Subscription) Events() <-chan *models.ClickEvent {
+	return s.events
+}
+
+// TakeDropped returns the number of events dropped since the last call and resets it
+func (s *Subscription) TakeDropped() int64 {
+	return s.dropped.Swap(0)
+}
+
+func (s *Subscription) accepts(event *models.ClickEvent) bool {
+	if len(s.mappingIDs) > 0 {
+		return s.mappingIDs[event.MappingID]
+	}
+	if s.scope.OrganizationID != 0 {
+		return event.OrganizationID == s.scope.OrganizationID
+	}
+	return event.ClientID == s.scope.ClientID && event.OrganizationID == 0
+}
+
+// Subscribe registers a subscription for the clicks on mappingIDs, which the
+// caller must have checked the scope may see. An empty mappingIDs subscribes
+// to every mapping within the scope: the organization's mappings, or the
+// client's personal ones.
+func (h *Hub) Subscribe(scope models.Scope, mappingIDs []int64) *Subscription {
+	sub := &Subscription{
+		scope:      scope,
+		mappingIDs: make(map[int64]bool, len(mappingIDs)),
+		events:     make(chan *models.ClickEvent, h.bufferSize),
+	}
+	for _, id := range mappingIDs {
+		sub.mappingIDs[id] = true
+	}
+
+	h.mu.Lock()
+	h.subscriptions[sub] = struct{}{}
+	h.mu.Unlock()
+
+	return sub
+}
+
+// Unsubscribe removes a subscription; no events are delivered to it afterwards
+func (h *Hub) Unsubscribe(sub *Subscription) {
+	h.mu.Lock()
+	delete(h.subscriptions, sub)
+	h.mu.Unlock()
+}
+
+// Publish delivers a click event to every matching subscription
+func (h *Hub) Publish(event *models.ClickEvent) {
+	if !event.Matched() {
+		return
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for sub := range h.subscriptions {
+		if !sub.accepts(event) {
+			continue
+		}
+		select {
+		case sub.events <- event:
+		default:
+			sub.dropped.Add(1)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package useragent
+
+import (
+	"context"
+	"platform/internal/models"
+	"platform/internal/repository/mysql"
+	"platform/pkg/logger"
+	"time"
+)
+
+// Backfill parses the User-Agents of stored request logs with IDs above afterID,
+// batch by batch. Unless all is set, only rows not parsed yet are visited; set it
+// to reparse everything after updating the rules.
+func Backfill(ctx context.Context, repo *mysql.RequestRepository, parser *Parser, batchSize int, afterID int64, all bool) (*models.UserAgentBackfillReport, error) {
+	report := &models.UserAgentBackfillReport{
+		LastID:    afterID,
+		StartedAt: time.Now().UTC(),
+	}
+
+	for {
+		if ctx.Err() != nil {
+			return report, ctx.Err()
+		}
+
+		requests, err := repo.GetUserAgentBatch(report.LastID, batchSize, all)
+		if err != nil {
+			return report, err
+		}
+		if len(requests) == 0 {
+			break
+		}
+
+		for i := range requests {
+			requests[i].UserAgent = parser.Parse(requests[i].Header("User-Agent"))
+		}
+		if err := repo.SetUserAgents(requests); err != nil {
+			return report, err
+		}
+
+		report.Parsed += int64(len(requests))
+		report.LastID = requests[len(requests)-1].ID
+		logger.Info("Parsed stored user agents", "last_id", report.LastID, "parsed", report.Parsed)
+	}
+
+	report.FinishedAt = time.Now().UTC()
+	return report, nil
+}
@@ -0,0 +1,198 @@
+package useragent
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"platform/internal/config"
+	"platform/internal/models"
+	"regexp"
+	"strings"
+)
+
+// maxVersionLength matches the width of the version columns
+const maxVersionLength = 32
+
+// defaultRules are the rules built into the binary. A rules file with the same
+// format can replace them without a rebuild.
+//
+//go:embed rules.json
+var defaultRules []byte
+
+// Rules is the JSON rule set of the parser. Rules of each list are tried in
+// order and the first match wins, so specific patterns go before generic ones.
+type Rules struct {
+	Bots     []FamilyRule `json:"bots"`
+	Browsers []FamilyRule `json:"browsers"`
+	OS       []FamilyRule `json:"os"`
+	Devices  []DeviceRule `json:"devices"`
+}
+
+// FamilyRule names the family of a matching User-Agent. The version is the first
+// non-empty capture group, with Separator replaced by dots and then mapped
+// through Versions when listed there.
+type FamilyRule struct {
+	Family    string            `json:"family"`
+	Pattern   string            `json:"pattern"`
+	Separator string            `json:"separator,omitempty"`
+	Versions  map[string]string `json:"versions,omitempty"`
+}
+
+// DeviceRule assigns a device class to User-Agents that match Pattern but not Exclude
+type DeviceRule struct {
+	Class   string `json:"class"`
+	Pattern string `json:"pattern"`
+	Exclude string `json:"exclude,omitempty"`
+}
+
+type familyMatcher struct {
+	FamilyRule
+	re *regexp.Regexp
+}
+
+type deviceMatcher struct {
+	class   string
+	re      *regexp.Regexp
+	exclude *regexp.Regexp
+}
+
+// Parser extracts browser, OS and device fields from User-Agent strings. It is
+// safe for concurrent use.
+type Parser struct {
+	bots     []familyMatcher
+	browsers []familyMatcher
+	os       []familyMatcher
+	devices  []deviceMatcher
+}
+
+// NewParser loads the rules file set in the configuration, or the built-in rules
+// when none is set
+func NewParser(cfg config.UserAgentConfig) (*Parser, error) {
+	data := defaultRules
+	if cfg.RulesFile != "" {
+		var err error
+		if data, err = os.ReadFile(cfg.RulesFile); err != nil {
+			return nil, fmt.Errorf("failed to read user agent rules: %w", err)
+		}
+	}
+
+	var rules Rules
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("failed to decode user agent rules: %w", err)
+	}
+	return Compile(rules)
+}
+
+// Compile builds a parser from a rule set
+func Compile(rules Rules) (*Parser, error) {
+	p := &Parser{}
+	var err error
+	if p.bots, err = compileFamilies("bot", rules.Bots); err != nil {
+		return nil, err
+	}
+	if p.browsers, err = compileFamilies("browser", rules.Browsers); err != nil {
+		return nil, err
+	}
+	if p.os, err = compileFamilies("os", rules.OS); err != nil {
+		return nil, err
+	}
+
+	for _, rule := range rules.Devices {
+		switch rule.Class {
+		case models.DeviceDesktop, models.DeviceMobile, models.DeviceTablet:
+		default:
+			return nil, fmt.Errorf("invalid device class %q in user agent rules", rule.Class)
+		}
+		re, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s device pattern: %w", rule.Class, err)
+		}
+		matcher := deviceMatcher{class: rule.Class, re: re}
+		if rule.Exclude != "" {
+			if matcher.exclude, err = regexp.Compile(rule.Exclude); err != nil {
+				return nil, fmt.Errorf("invalid %s device exclude pattern: %w", rule.Class, err)
+			}
+		}
+		p.devices = append(p.devices, matcher)
+	}
+
+	return p, nil
+}
+
+func compileFamilies(kind string, rules []FamilyRule) ([]familyMatcher, error) {
+	matchers := make([]familyMatcher, 0, len(rules))
+	for _, rule := range rules {
+		if rule.Family == "" {
+			return nil, fmt.Errorf("%s rule without family in user agent rules", kind)
+		}
+		re, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s pattern of %s: %w", kind, rule.Family, err)
+		}
+		matchers = append(matchers, familyMatcher{FamilyRule: rule, re: re})
+	}
+	return matchers, nil
+}
+
+// Parse classifies a User-Agent. Bots keep their name as the browser family.
+func (p *Parser) Parse(userAgent string) *models.UserAgent {
+	ua := &models.UserAgent{
+		BrowserFamily: models.UnknownFamily,
+		OSFamily:      models.UnknownFamily,
+		DeviceClass:   models.DeviceUnknown,
+	}
+	if userAgent == "" {
+		return ua
+	}
+
+	ua.OSFamily, ua.OSVersion = match(p.os, userAgent)
+
+	if family, version := match(p.bots, userAgent); family != models.UnknownFamily {
+		ua.BrowserFamily, ua.BrowserVersion = family, version
+		ua.DeviceClass = models.DeviceBot
+		ua.IsBot = true
+		return ua
+	}
+
+	ua.BrowserFamily, ua.BrowserVersion = match(p.browsers, userAgent)
+	ua.DeviceClass = p.deviceClass(userAgent)
+	return ua
+}
+
+func match(matchers []familyMatcher, userAgent string) (string, string) {
+	for _, m := range matchers {
+		groups := m.re.FindStringSubmatch(userAgent)
+		if groups == nil {
+			continue
+		}
+
+		var version string
+		for _, group := range groups[1:] {
+			if group != "" {
+				version = group
+				break
+			}
+		}
+		if m.Separator != "" {
+			version = strings.ReplaceAll(version, m.Separator, ".")
+		}
+		if mapped, ok := m.Versions[version]; ok {
+			version = mapped
+		}
+		if len(version) > maxVersionLength {
+			version = version[:maxVersionLength]
+		}
+		return m.Family, version
+	}
+	return models.UnknownFamily, ""
+}
+
+func (p *Parser) deviceClass(userAgent string) string {
+	for _, m := range p.devices {
+		if m.re.MatchString(userAgent) && (m.exclude == nil || !m.exclude.MatchString(userAgent)) {
+			return m.class
+		}
+	}
+	return models.DeviceDesktop
+}
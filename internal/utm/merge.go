@@ -0,0 +1,45 @@
+package utm
+
+import (
+	"net/url"
+	"platform/internal/models"
+)
+
+// Effective returns the UTM parameters and policy a mapping redirects with:
+// its own values, falling back to the client's defaults, and keep when neither
+// sets a policy
+func Effective(mapping *models.RedirectMapping, defaults *models.UTMSettings) (models.UTMParams, string) {
+	params := mapping.UTM
+	policy := mapping.UTMPolicy
+	if defaults != nil {
+		params = params.Inherit(defaults.UTMParams)
+		if policy == "" {
+			policy = defaults.Policy
+		}
+	}
+	if policy == "" {
+		policy = models.UTMPolicyKeep
+	}
+	return params, policy
+}
+
+// Merge sets the non-empty UTM parameters on a destination query. Under the
+// keep policy a parameter the destination already carries is left as it is;
+// under override it is replaced.
+func Merge(query url.Values, params models.UTMParams, policy string) {
+	for _, param := range []struct{ key, value string }{
+		{"utm_source", params.Source},
+		{"utm_medium", params.Medium},
+		{"utm_campaign", params.Campaign},
+		{"utm_term", params.Term},
+		{"utm_content", params.Content},
+	} {
+		if param.value == "" {
+			continue
+		}
+		if _, ok := query[param.key]; ok && policy != models.UTMPolicyOverride {
+			continue
+		}
+		query.Set(param.key, param.value)
+	}
+}
@@ -0,0 +1,59 @@
+package utm
+
+import (
+	"platform/internal/models"
+	"platform/internal/repository/mysql"
+	"sync"
+	"time"
+)
+
+type cachedSettings struct {
+	settings  *models.UTMSettings
+	expiresAt time.Time
+}
+
+// Resolver returns a client's default UTM settings, caching them for a while
+// so the redirect path does not query them on every click
+type Resolver struct {
+	repo *mysql.UTMRepository
+	ttl  time.Duration
+
+	mu    sync.Mutex
+	cache map[int64]cachedSettings
+}
+
+func NewResolver(repo *mysql.UTMRepository, ttl time.Duration) *Resolver {
+	return &Resolver{
+		repo:  repo,
+		ttl:   ttl,
+		cache: make(map[int64]cachedSettings),
+	}
+}
+
+// For returns the default UTM settings of a client, or nil if it has none
+func (r *Resolver) For(clientID int64) (*models.UTMSettings, error) {
+	now := time.Now()
+	r.mu.Lock()
+	cached, ok := r.cache[clientID]
+	r.mu.Unlock()
+	if ok && now.Before(cached.expiresAt) {
+		return cached.settings, nil
+	}
+
+	settings, err := r.repo.GetSettings(clientID)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	r.cache[clientID] = cachedSettings{settings: settings, expiresAt: now.Add(r.ttl)}
+	r.mu.Unlock()
+	return settings, nil
+}
+
+// Forget drops a client's cached settings so changes apply immediately
+func (r *Resolver) Forget(clientID int64) {
+	r.mu.Lock()
+	delete(r.cache, clientID)
+	r.mu.Unlock()
+}
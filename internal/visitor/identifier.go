@@ -0,0 +1,110 @@
+package visitor
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"platform/internal/config"
+	"platform/internal/repository/mysql"
+	"platform/pkg/logger"
+	"sync"
+	"time"
+)
+
+// idLength is the number of hex characters of a visitor ID
+const idLength = 32
+
+// Identifier tells visitors apart by a first-party cookie, falling back to a
+// hash of IP and User-Agent keyed with a random salt of the current UTC day.
+// Salts of past days are deleted, so hashes cannot be recomputed from an IP
+// later on and the same visitor hashes differently each day.
+type Identifier struct {
+	cfg  config.VisitorConfig
+	repo *mysql.VisitorRepository
+
+	mu   sync.Mutex
+	day  time.Time
+	salt []byte
+}
+
+func NewIdentifier(cfg config.VisitorConfig, repo *mysql.VisitorRepository) *Identifier {
+	return &Identifier{
+		cfg:  cfg,
+		repo: repo,
+	}
+}
+
+// Identify returns the visitor ID of a request and whether it was read from
+// the cookie
+func (i *Identifier) Identify(r *http.Request, ip string) (string, bool, error) {
+	if i.cfg.SetCookie {
+		if cookie, err := r.Cookie(i.cfg.CookieName); err == nil && validID(cookie.Value) {
+			return cookie.Value, true, nil
+		}
+	}
+
+	salt, err := i.saltFor(time.Now())
+	if err != nil {
+		return "", false, err
+	}
+	mac := hmac.New(sha256.New, salt)
+	mac.Write([]byte(ip))
+	mac.Write([]byte{0})
+	mac.Write([]byte(r.UserAgent()))
+	return hex.EncodeToString(mac.Sum(nil))[:idLength], false, nil
+}
+
+// Cookie returns the cookie that keeps a visitor ID, or nil if cookies are off
+func (i *Identifier) Cookie(id string) *http.Cookie {
+	if !i.cfg.SetCookie {
+		return nil
+	}
+	return &http.Cookie{
+		Name:     i.cfg.CookieName,
+		Value:    id,
+		Path:     "/",
+		Domain:   i.cfg.CookieDomain,
+		MaxAge:   int(i.cfg.CookieMaxAge.Seconds()),
+		Secure:   i.cfg.CookieSecure,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	}
+}
+
+// saltFor returns the salt of now's UTC day, loading it when the day changes
+func (i *Identifier) saltFor(now time.Time) ([]byte, error) {
+	day := now.UTC().Truncate(24 * time.Hour)
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if i.salt != nil && i.day.Equal(day) {
+		return i.salt, nil
+	}
+
+	salt, err := i.repo.GetSalt(day)
+	if err != nil {
+		return nil, err
+	}
+	if deleted, err := i.repo.DeleteSaltsBefore(day); err != nil {
+		logger.Error("Failed to delete past visitor salts", "error", err)
+	} else if deleted > 0 {
+		logger.Info("Deleted past visitor salts", "count", deleted)
+	}
+
+	i.day, i.salt = day, salt
+	return salt, nil
+}
+
+// validID reports whether a cookie value is a visitor ID the gateway issued
+func validID(value string) bool {
+	if len(value) != idLength {
+		return false
+	}
+	for _, c := range value {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
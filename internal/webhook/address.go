@@ -0,0 +1,87 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"syscall"
+)
+
+// ErrBlockedAddress is returned for endpoints on the loopback, private or
+// link-local networks the platform itself runs on
+var ErrBlockedAddress = errors.New("webhook URL must not point to a loopback, private or link-local address")
+
+// blockedNetworks are special-purpose ranges the net.IP predicates do not cover
+var blockedNetworks = []*net.IPNet{
+	mustParseCIDR("0.0.0.0/8"),     // "this" network
+	mustParseCIDR("100.64.0.0/10"), // carrier-grade NAT
+	mustParseCIDR("192.0.0.0/24"),  // IETF protocol assignments
+	mustParseCIDR("198.18.0.0/15"), // benchmarking
+}
+
+func mustParseCIDR(cidr string) *net.IPNet {
+	_, network, err := net.ParseCIDR(cidr)
+	if err != nil {
+		panic(err)
+	}
+	return network
+}
+
+// AllowedIP reports whether webhooks may be sent to ip
+func AllowedIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return false
+	}
+	for _, network := range blockedNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// CheckURL validates an endpoint URL when it is registered: it must be http or
+// https and its host must only resolve to allowed addresses. The addresses are
+// checked again when connecting, since DNS answers can change in between.
+func CheckURL(ctx context.Context, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return fmt.Errorf("webhook URL must be an http or https URL")
+	}
+
+	if ip := net.ParseIP(u.Hostname()); ip != nil {
+		if !AllowedIP(ip) {
+			return ErrBlockedAddress
+		}
+		return nil
+	}
+
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, u.Hostname())
+	if err != nil {
+		return fmt.Errorf("webhook URL host %s could not be resolved", u.Hostname())
+	}
+	for _, addr := range addrs {
+		if !AllowedIP(addr.IP) {
+			return ErrBlockedAddress
+		}
+	}
+	return nil
+}
+
+// dialControl refuses connections to blocked addresses. It runs after DNS
+// resolution, so a host that resolves differently than at registration is
+// still checked.
+func dialControl(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return fmt.Errorf("failed to parse dial address %s: %w", address, err)
+	}
+	if ip := net.ParseIP(host); ip == nil || !AllowedIP(ip) {
+		return fmt.Errorf("refusing to connect to %s: %w", host, ErrBlockedAddress)
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+)
+
+func TestAllowedIP(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{ip: "93.184.216.34", want: true},
+		{ip: "2606:2800:220:1::1", want: true},
+		{ip: "127.0.0.1", want: false},
+		{ip: "::1", want: false},
+		{ip: "10.1.2.3", want: false},
+		{ip: "172.16.0.10", want: false},
+		{ip: "192.168.1.1", want: false},
+		{ip: "169.254.169.254", want: false},
+		{ip: "fe80::1", want: false},
+		{ip: "fd00::1", want: false},
+		{ip: "0.0.0.0", want: false},
+		{ip: "100.64.0.1", want: false},
+		{ip: "::ffff:127.0.0.1", want: false},
+		{ip: "224.0.0.1", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ip, func(t *testing.T) {
+			if got := AllowedIP(net.ParseIP(tt.ip)); got != tt.want {
+				t.Errorf("AllowedIP(%s) = %v, want %v", tt.ip, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckURL(t *testing.T) {
+	tests := []struct {
+		url     string
+		wantErr bool
+		blocked bool
+	}{
+		{url: "https://93.184.216.34/hooks", wantErr: false},
+		{url: "http://[2606:2800:220:1::1]:8080/hooks", wantErr: false},
+		{url: "http://127.0.0.1:9090/metrics", wantErr: true, blocked: true},
+		{url: "http://169.254.169.254/", wantErr: true, blocked: true},
+		{url: "http://10.0.0.5:3306", wantErr: true, blocked: true},
+		{url: "http://[::1]/", wantErr: true, blocked: true},
+		{url: "http://localhost:9090/metrics", wantErr: true},
+		{url: "ftp://93.184.216.34/", wantErr: true},
+		{url: "mysql://93.184.216.34:3306", wantErr: true},
+		{url: "https:///hooks", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			err := CheckURL(context.Background(), tt.url)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CheckURL(%s) error = %v, want error %v", tt.url, err, tt.wantErr)
+			}
+			if tt.blocked && !errors.Is(err, ErrBlockedAddress) {
+				t.Errorf("CheckURL(%s) error = %v, want %v", tt.url, err, ErrBlockedAddress)
+			}
+		})
+	}
+}
+
+func TestDialControl(t *testing.T) {
+	tests := []struct {
+		address string
+		allowed bool
+	}{
+		{address: "93.184.216.34:443", allowed: true},
+		{address: "127.0.0.1:9090", allowed: false},
+		{address: "[::1]:3306", allowed: false},
+		{address: "169.254.169.254:80", allowed: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.address, func(t *testing.T) {
+			err := dialControl("tcp", tt.address, nil)
+			if (err == nil) != tt.allowed {
+				t.Errorf("dialControl(%s) error = %v, want allowed %v", tt.address, err, tt.allowed)
+			}
+		})
+	}
+}
@@ -0,0 +1,238 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"platform/internal/config"
+	"platform/internal/models"
+	"platform/internal/repository/mysql"
+	"platform/pkg/logger"
+	"strconv"
+	"time"
+)
+
+// Dispatcher fans events out to matching endpoints and delivers them with retries.
+// Deliveries are persisted first, so the delivery log doubles as the retry queue.
+type Dispatcher struct {
+	repo   *mysql.WebhookRepository
+	cfg    config.WebhookConfig
+	client *http.Client
+}
+
+func NewDispatcher(repo *mysql.WebhookRepository, cfg config.WebhookConfig) *Dispatcher {
+	return &Dispatcher{
+		repo: repo,
+		cfg:  cfg,
+		client: &http.Client{
+			Timeout: cfg.Timeout,
+			// Connect directly, never through a proxy, so every address is checked
+			Transport: &http.Transport{
+				DialContext: (&net.Dialer{
+					Timeout:   30 * time.Second,
+					KeepAlive: 30 * time.Second,
+					Control:   dialControl,
+				}).DialContext,
+				MaxIdleConns:          100,
+				IdleConnTimeout:       90 * time.Second,
+				TLSHandshakeTimeout:   10 * time.Second,
+				ExpectContinueTimeout: time.Second,
+			},
+			// Receivers must answer directly; following redirects would leak signed payloads
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+// Enqueue records a pending delivery for every active endpoint of the scope
+// owning the event's mapping whose filters accept the event; events without a
+// fraud score pass 0. The event ID is sent unchanged on every attempt so
+// receivers can deduplicate, and enqueueing an event again does not queue it
+// twice.
+func (d *Dispatcher) Enqueue(owner models.Scope, mappingID int64, fraudScore int, eventID, eventType string, data interface{}) error {
+	endpoints, err := d.repo.GetActiveEndpoints(owner)
+	if err != nil {
+		return fmt.Errorf("failed to get webhook endpoints: %w", err)
+	}
+
+	var payload []byte
+	for _, endpoint := range endpoints {
+		if !endpoint.Accepts(eventType, mappingID, fraudScore) {
+			continue
+		}
+
+		if payload == nil {
+			payload, err = json.Marshal(models.WebhookEvent{
+				ID:        eventID,
+				Type:      eventType,
+				CreatedAt: time.Now().UTC(),
+				Data:      data,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to marshal webhook event: %w", err)
+			}
+		}
+
+		delivery := &models.WebhookDelivery{
+			EndpointID:    endpoint.ID,
+			EventID:       eventID,
+			EventType:     eventType,
+			Payload:       payload,
+			NextAttemptAt: time.Now(),
+		}
+		if err := d.repo.CreateDelivery(delivery); err != nil {
+			return fmt.Errorf("failed to enqueue webhook delivery: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Run polls for due deliveries until the context is cancelled
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		d.dispatchDue(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (d *Dispatcher) dispatchDue(ctx context.Context) {
+	// Claimed deliveries are sent one after another, so lease them for longer
+	// than sending the whole batch can take
+	lease := time.Duration(d.cfg.BatchSize)*d.cfg.Timeout + time.Minute
+
+	for {
+		claimedAt := time.Now()
+		deliveries, err := d.repo.ClaimDueDeliveries(d.cfg.BatchSize, lease)
+		if err != nil {
+			logger.Error("Failed to claim webhook deliveries", "error", err)
+			return
+		}
+
+		for i := range deliveries {
+			if ctx.Err() != nil {
+				return
+			}
+			// Leave the rest of the batch once a send could outlast the lease;
+			// another worker may claim them as soon as it expires
+			if time.Since(claimedAt)+d.cfg.Timeout > lease {
+				logger.Info("Webhook delivery lease running out, leaving the rest of the batch",
+					"remaining", len(deliveries)-i)
+				return
+			}
+			d.deliver(ctx, &deliveries[i])
+		}
+
+		if len(deliveries) < d.cfg.BatchSize {
+			return
+		}
+	}
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, delivery *models.WebhookDelivery) {
+	attempts := delivery.Attempts + 1
+	status, err := d.send(ctx, delivery)
+	if err == nil {
+		if err := d.repo.MarkDeliverySucceeded(delivery.ID, attempts, status); err != nil {
+			logger.Error("Failed to mark webhook delivery succeeded", "delivery_id", delivery.ID, "error", err)
+		}
+		if err := d.repo.ResetEndpointFailures(delivery.EndpointID); err != nil {
+			logger.Error("Failed to reset webhook endpoint failures", "endpoint_id", delivery.EndpointID, "error", err)
+		}
+		return
+	}
+
+	logger.Error("Webhook delivery failed",
+		"delivery_id", delivery.ID,
+		"endpoint_id", delivery.EndpointID,
+		"attempt", attempts,
+		"error", err,
+	)
+
+	var responseStatus *int
+	if status != 0 {
+		responseStatus = &status
+	}
+
+	var nextAttempt *time.Time
+	if attempts < d.cfg.MaxAttempts {
+		next := time.Now().Add(d.backoff(attempts))
+		nextAttempt = &next
+	}
+	if err := d.repo.MarkDeliveryFailed(delivery.ID, attempts, responseStatus, err.Error(), nextAttempt); err != nil {
+		logger.Error("Failed to mark webhook delivery failed", "delivery_id", delivery.ID, "error", err)
+	}
+
+	failures, err := d.repo.RecordEndpointFailure(delivery.EndpointID)
+	if err != nil {
+		logger.Error("Failed to record webhook endpoint failure", "endpoint_id", delivery.EndpointID, "error", err)
+		return
+	}
+	if failures >= d.cfg.FailureThreshold {
+		reason := fmt.Sprintf("disabled after %d consecutive failed deliveries", failures)
+		if err := d.repo.DisableEndpoint(delivery.EndpointID, reason); err != nil {
+			logger.Error("Failed to disable webhook endpoint", "endpoint_id", delivery.EndpointID, "error", err)
+			return
+		}
+		logger.Info("Disabled failing webhook endpoint", "endpoint_id", delivery.EndpointID, "failures", failures)
+	}
+}
+
+// send POSTs the signed payload and returns the response status code
+func (d *Dispatcher) send(ctx context.Context, delivery *models.WebhookDelivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, fmt.Errorf("failed to build request: %w", err)
+	}
+
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "platform-webhooks/1.0")
+	req.Header.Set(EventIDHeader, delivery.EventID)
+	req.Header.Set(EventTypeHeader, delivery.EventType)
+	req.Header.Set(DeliveryHeader, strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(SignatureHeader, "v1="+Sign(delivery.Secret, timestamp, delivery.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("unexpected response status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// backoff doubles the delay on each attempt, capped at MaxBackoff, with up to 10% jitter
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	delay := d.cfg.InitialBackoff
+	for i := 1; i < attempts && delay < d.cfg.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > d.cfg.MaxBackoff {
+		delay = d.cfg.MaxBackoff
+	}
+	if jitter := int64(delay / 10); jitter > 0 {
+		delay += time.Duration(rand.Int63n(jitter))
+	}
+	return delay
+}
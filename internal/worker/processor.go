@@ -0,0 +1,142 @@
+package worker
+
+import (
+	"fmt"
+	"net/url"
+	"platform/internal/attribution"
+	"platform/internal/bot"
+	"platform/internal/fraud"
+	"platform/internal/geoip"
+	"platform/internal/models"
+	"platform/internal/repository/mysql"
+	"platform/internal/rollup"
+	"platform/internal/useragent"
+	"platform/internal/webhook"
+	"strings"
+	"time"
+)
+
+// Processor persists click events consumed from RabbitMQ
+type Processor struct {
+	requestRepo  *mysql.RequestRepository
+	redirectRepo *mysql.RedirectRepository
+	dispatcher   *webhook.Dispatcher
+	aggregator   *rollup.Aggregator
+	parser       *useragent.Parser
+	locator      *geoip.Locator
+	classifier   *bot.Classifier
+	scorer       *fraud.Scorer
+	uniqueWindow time.Duration
+}
+
+func NewProcessor(requestRepo *mysql.RequestRepository, redirectRepo *mysql.RedirectRepository, dispatcher *webhook.Dispatcher, aggregator *rollup.Aggregator, parser *useragent.Parser, locator *geoip.Locator, classifier *bot.Classifier, scorer *fraud.Scorer, uniqueWindow time.Duration) *Processor {
+	return &Processor{
+		requestRepo:  requestRepo,
+		redirectRepo: redirectRepo,
+		dispatcher:   dispatcher,
+		aggregator:   aggregator,
+		parser:       parser,
+		locator:      locator,
+		classifier:   classifier,
+		scorer:       scorer,
+		uniqueWindow: uniqueWindow,
+	}
+}
+
+// Process stores the request and, for matched hashes, notifies the webhook
+// endpoints of the mapping's owner, stores the redirect exactly as the gateway
+// served it along with its bot classification, fraud score and whether it is
+// unique, then counts it in the rollups.
+//
+// A failed event is redelivered and processed again from the start. Every write
+// is keyed by the event, so a redelivered event is stored and queued only once,
+// and the redirect is stored last so the click is counted only once, by the
+// delivery that stored it.
+func (p *Processor) Process(event *models.ClickEvent) error {
+	// Save request to database
+	request := &event.Request
+	request.RequestID = event.RequestID
+	request.EventID = event.EventID
+	request.ClientID = event.ClientID
+	request.OrganizationID = event.OrganizationID
+	request.UserAgent = p.parser.Parse(request.Header("User-Agent"))
+	request.Attribution = attribution.Parse(request)
+	if request.Geo == nil {
+		// The gateway locates clicks itself when it has the databases; here the
+		// IP may already be truncated or hashed by the privacy policy
+		request.Geo = p.locator.Lookup(request.IPAddress)
+	}
+	if err := p.requestRepo.SaveRequest(request); err != nil {
+		return fmt.Errorf("failed to save request: %w", err)
+	}
+
+	if !event.Matched() {
+		return nil
+	}
+
+	event.IsBot, event.BotReason = p.classifier.Classify(event)
+	score, rules, err := p.scorer.Score(event)
+	if err != nil {
+		return fmt.Errorf("failed to score click: %w", err)
+	}
+	event.FraudScore, event.FraudRules = score, rules
+	if event.VisitorID != "" {
+		seen, err := p.redirectRepo.VisitorSeen(event.MappingID, event.VisitorID, request.ID, event.Timestamp.Add(-p.uniqueWindow), event.Timestamp)
+		if err != nil {
+			return fmt.Errorf("failed to check unique click: %w", err)
+		}
+		event.Unique = !seen
+	}
+
+	// Notify the webhook endpoints of the client or organization owning the mapping
+	data := models.NewWebhookClickData(event)
+	if err := p.dispatcher.Enqueue(event.Owner(), event.MappingID, event.FraudScore, event.EventID, models.WebhookEventClick, data); err != nil {
+		return fmt.Errorf("failed to enqueue webhooks: %w", err)
+	}
+
+	// Save redirect record
+	redirect := &models.Redirect{
+		RequestLogID:      request.ID,
+		MappingID:         event.MappingID,
+		ClientID:          event.ClientID,
+		ClickID:           event.ClickID,
+		OriginalURL:       request.RequestURL,
+		RedirectURL:       event.Destination,
+		Variant:           event.Variant,
+		RedirectType:      RedirectType(event.Destination),
+		RedirectStatus:    event.StatusCode,
+		RedirectTimestamp: event.Timestamp,
+		IsBot:             event.IsBot,
+		BotReason:         event.BotReason,
+		FraudScore:        event.FraudScore,
+		FraudRules:        event.FraudRules,
+		ForwardedParams:   event.ForwardedParams,
+		VisitorID:         event.VisitorID,
+		IsUnique:          event.Unique,
+	}
+	if event.OrganizationID != 0 {
+		redirect.OrganizationID = &event.OrganizationID
+	}
+	saved, err := p.redirectRepo.SaveRedirect(redirect)
+	if err != nil {
+		return fmt.Errorf("failed to save redirect: %w", err)
+	}
+
+	// Count the click towards the rollups, unless an earlier delivery of the
+	// event already did
+	if saved {
+		p.aggregator.Add(event)
+	}
+
+	return nil
+}
+
+// RedirectType labels a destination by the first label of its host.
+// Example: http://site1.com/special-offer -> site1
+func RedirectType(destination string) string {
+	parsed, err := url.Parse(destination)
+	if err != nil || parsed.Hostname() == "" {
+		return "unknown"
+	}
+	return strings.Split(parsed.Hostname(), ".")[0]
+}
@@ -0,0 +1,57 @@
+package metrics
+
+import (
+	"expvar"
+	"net/http"
+	"platform/pkg/logger"
+	"sync"
+)
+
+// Metrics are published through expvar and served as JSON under /debug/vars
+
+var mu sync.Mutex
+
+// Int returns the integer metric with the given name, creating it on first use
+func Int(name string) *expvar.Int {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if v, ok := expvar.Get(name).(*expvar.Int); ok {
+		return v
+	}
+	return expvar.NewInt(name)
+}
+
+// Map returns the keyed metric with the given name, creating it on first use
+func Map(name string) *expvar.Map {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if v, ok := expvar.Get(name).(*expvar.Map); ok {
+		return v
+	}
+	return expvar.NewMap(name)
+}
+
+// Handler serves all published metrics as JSON
+func Handler() http.Handler {
+	return expvar.Handler()
+}
+
+// Serve exposes the metrics on addr under /debug/vars in the background.
+// An empty addr disables the listener.
+func Serve(addr string) {
+	if addr == "" {
+		return
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/debug/vars", Handler())
+
+	go func() {
+		logger.Info("Serving metrics", "addr", addr)
+		if err := http.ListenAndServe(addr, mux); err != nil {
+			logger.Error("Metrics listener stopped", "error", err)
+		}
+	}()
+}
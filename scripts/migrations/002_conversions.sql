@@ -0,0 +1,38 @@
+USE platform_db;
+
+-- Record the click_id and served variant on each click so postbacks can find it
+ALTER TABLE redirect_history
+    ADD COLUMN click_id VARCHAR(255) NULL AFTER request_log_id,
+    ADD COLUMN variant VARCHAR(20) NOT NULL DEFAULT 'primary' AFTER redirect_url,
+    ADD INDEX idx_click_id (click_id);
+
+-- Backfill click_id from the original request URL
+UPDATE redirect_history
+SET click_id = SUBSTRING_INDEX(SUBSTRING_INDEX(original_url, 'click_id=', -1), '&', 1)
+WHERE click_id IS NULL AND original_url LIKE '%click_id=%';
+
+-- Per-client token used to authenticate server-to-server postbacks (SHA-256 hex)
+ALTER TABLE clients
+    ADD COLUMN postback_token_hash CHAR(64) NULL,
+    ADD UNIQUE INDEX idx_postback_token_hash (postback_token_hash);
+
+-- Conversions Table (one row per click_id and event name)
+CREATE TABLE IF NOT EXISTS conversions (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    client_id BIGINT NOT NULL,
+    mapping_id BIGINT NOT NULL,
+    redirect_history_id BIGINT NULL,
+    click_id VARCHAR(255) NOT NULL,
+    event_name VARCHAR(64) NOT NULL,
+    variant VARCHAR(20) NOT NULL,
+    payout DECIMAL(14, 4) NULL,
+    currency CHAR(3) NULL,
+    ip_address VARCHAR(45) NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (client_id) REFERENCES clients(id),
+    FOREIGN KEY (mapping_id) REFERENCES redirect_mappings(id),
+    FOREIGN KEY (redirect_history_id) REFERENCES redirect_history(id) ON DELETE SET NULL,
+    UNIQUE INDEX idx_client_click_event (client_id, click_id, event_name),
+    INDEX idx_mapping_created (mapping_id, created_at),
+    INDEX idx_client_created (client_id, created_at)
+);
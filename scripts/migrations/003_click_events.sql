@@ -0,0 +1,6 @@
+USE platform_db;
+
+-- Correlate stored requests with gateway logs through the X-Request-ID header
+ALTER TABLE request_logs
+    ADD COLUMN request_id VARCHAR(255) NULL AFTER id,
+    ADD INDEX idx_request_id (request_id);
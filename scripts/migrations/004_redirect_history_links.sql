@@ -0,0 +1,16 @@
+USE platform_db;
+
+-- Link each click to the mapping that served it and the owning client
+ALTER TABLE redirect_history
+    ADD COLUMN mapping_id BIGINT NULL AFTER request_log_id,
+    ADD COLUMN client_id BIGINT NULL AFTER mapping_id,
+    ADD CONSTRAINT fk_redirect_history_mapping FOREIGN KEY (mapping_id) REFERENCES redirect_mappings(id),
+    ADD CONSTRAINT fk_redirect_history_client FOREIGN KEY (client_id) REFERENCES clients(id),
+    ADD INDEX idx_mapping_timestamp (mapping_id, redirect_timestamp),
+    ADD INDEX idx_client_timestamp (client_id, redirect_timestamp);
+
+-- Backfill existing rows by matching the hash in the original request path ("/abc123?click_id=...")
+UPDATE redirect_history h
+JOIN redirect_mappings m ON m.hash = SUBSTRING_INDEX(SUBSTRING_INDEX(h.original_url, '?', 1), '/', -1)
+SET h.mapping_id = m.id, h.client_id = m.client_id
+WHERE h.mapping_id IS NULL;
@@ -0,0 +1,29 @@
+USE platform_db;
+
+-- Hourly click counters maintained by the database worker (UTC hours)
+CREATE TABLE IF NOT EXISTS click_rollups_hourly (
+    bucket_start DATETIME NOT NULL,
+    mapping_id BIGINT NOT NULL,
+    client_id BIGINT NOT NULL,
+    variant VARCHAR(20) NOT NULL,
+    country CHAR(2) NOT NULL DEFAULT '',
+    device_class VARCHAR(16) NOT NULL,
+    clicks BIGINT UNSIGNED NOT NULL DEFAULT 0,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    PRIMARY KEY (mapping_id, bucket_start, variant, country, device_class),
+    INDEX idx_client_bucket (client_id, bucket_start)
+);
+
+-- Daily click counters maintained by the database worker (UTC days)
+CREATE TABLE IF NOT EXISTS click_rollups_daily (
+    bucket_date DATE NOT NULL,
+    mapping_id BIGINT NOT NULL,
+    client_id BIGINT NOT NULL,
+    variant VARCHAR(20) NOT NULL,
+    country CHAR(2) NOT NULL DEFAULT '',
+    device_class VARCHAR(16) NOT NULL,
+    clicks BIGINT UNSIGNED NOT NULL DEFAULT 0,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    PRIMARY KEY (mapping_id, bucket_date, variant, country, device_class),
+    INDEX idx_client_bucket (client_id, bucket_date)
+);
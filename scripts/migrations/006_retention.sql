@@ -0,0 +1,23 @@
+USE platform_db;
+
+-- Attribute request logs to the client whose mapping served them, so retention
+-- overrides apply after the redirect history is gone
+ALTER TABLE request_logs
+    ADD COLUMN client_id BIGINT NULL AFTER request_id,
+    ADD INDEX idx_client_timestamp (client_id, timestamp);
+
+UPDATE request_logs l
+JOIN redirect_history h ON h.request_log_id = l.id
+SET l.client_id = h.client_id
+WHERE l.client_id IS NULL;
+
+-- Per-client retention overrides (days) for the raw click tables
+CREATE TABLE IF NOT EXISTS client_retention_policies (
+    client_id BIGINT NOT NULL,
+    table_name VARCHAR(64) NOT NULL,
+    retention_days INT NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    PRIMARY KEY (client_id, table_name),
+    FOREIGN KEY (client_id) REFERENCES clients(id) ON DELETE CASCADE
+);
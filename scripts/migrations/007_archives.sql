@@ -0,0 +1,12 @@
+USE platform_db;
+
+-- One row per archived UTC day of a raw click table
+CREATE TABLE IF NOT EXISTS archive_runs (
+    table_name VARCHAR(64) NOT NULL,
+    range_start DATETIME NOT NULL,
+    range_end DATETIME NOT NULL,
+    manifest_key VARCHAR(512) NOT NULL,
+    row_count BIGINT NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (table_name, range_start)
+);
@@ -0,0 +1,13 @@
+USE platform_db;
+
+-- Per-client tightening of the platform privacy policy applied to request logs.
+-- NULL columns inherit the platform policy.
+CREATE TABLE IF NOT EXISTS client_privacy_settings (
+    client_id BIGINT PRIMARY KEY,
+    header_mode VARCHAR(8) NULL,
+    headers JSON NULL,
+    ip_mode VARCHAR(16) NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    FOREIGN KEY (client_id) REFERENCES clients(id) ON DELETE CASCADE
+);
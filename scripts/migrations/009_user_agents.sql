@@ -0,0 +1,15 @@
+USE platform_db;
+
+-- Fields parsed from the User-Agent by the database worker. NULL until a row is
+-- parsed; older rows are filled by `database-worker backfill-user-agents`.
+ALTER TABLE request_logs
+    ADD COLUMN browser_family VARCHAR(50) NULL AFTER request_headers,
+    ADD COLUMN browser_version VARCHAR(32) NULL AFTER browser_family,
+    ADD COLUMN os_family VARCHAR(50) NULL AFTER browser_version,
+    ADD COLUMN os_version VARCHAR(32) NULL AFTER os_family,
+    ADD COLUMN device_class VARCHAR(16) NULL AFTER os_version,
+    ADD COLUMN is_bot BOOLEAN NULL AFTER device_class,
+    ADD INDEX idx_browser_family (browser_family),
+    ADD INDEX idx_os_family (os_family),
+    ADD INDEX idx_device_class (device_class),
+    ADD INDEX idx_is_bot (is_bot);
@@ -0,0 +1,12 @@
+USE platform_db;
+
+-- Location and network of the client IP from the offline GeoIP databases.
+-- NULL when no database knew the IP or none was configured.
+ALTER TABLE request_logs
+    ADD COLUMN country_code CHAR(2) NULL AFTER is_bot,
+    ADD COLUMN region VARCHAR(100) NULL AFTER country_code,
+    ADD COLUMN city VARCHAR(100) NULL AFTER region,
+    ADD COLUMN asn INT UNSIGNED NULL AFTER city,
+    ADD COLUMN as_org VARCHAR(255) NULL AFTER asn,
+    ADD INDEX idx_country_code (country_code),
+    ADD INDEX idx_asn (asn);
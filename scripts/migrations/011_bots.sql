@@ -0,0 +1,28 @@
+USE platform_db;
+
+-- Clicks the database worker classified as automated, and the first signal that
+-- matched (user_agent, missing_user_agent, crawler_ip or burst)
+ALTER TABLE redirect_history
+    ADD COLUMN is_bot BOOLEAN NOT NULL DEFAULT FALSE AFTER redirect_timestamp,
+    ADD COLUMN bot_reason VARCHAR(32) NULL AFTER is_bot,
+    ADD INDEX idx_client_bot_timestamp (client_id, is_bot, redirect_timestamp);
+
+-- Earlier clicks were only classified by their User-Agent
+UPDATE redirect_history h
+JOIN request_logs l ON l.id = h.request_log_id
+SET h.is_bot = TRUE, h.bot_reason = 'user_agent'
+WHERE l.is_bot = TRUE;
+
+-- Bot clicks are counted apart so stats can leave them out
+ALTER TABLE click_rollups_hourly
+    ADD COLUMN is_bot BOOLEAN NOT NULL DEFAULT FALSE AFTER device_class,
+    DROP PRIMARY KEY,
+    ADD PRIMARY KEY (mapping_id, bucket_start, variant, country, device_class, is_bot);
+
+ALTER TABLE click_rollups_daily
+    ADD COLUMN is_bot BOOLEAN NOT NULL DEFAULT FALSE AFTER device_class,
+    DROP PRIMARY KEY,
+    ADD PRIMARY KEY (mapping_id, bucket_date, variant, country, device_class, is_bot);
+
+UPDATE click_rollups_hourly SET is_bot = TRUE WHERE device_class = 'bot';
+UPDATE click_rollups_daily SET is_bot = TRUE WHERE device_class = 'bot';
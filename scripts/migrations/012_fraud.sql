@@ -0,0 +1,12 @@
+USE platform_db;
+
+-- Fraud score computed by the database worker (0-100) and the rules that fired
+ALTER TABLE redirect_history
+    ADD COLUMN fraud_score TINYINT UNSIGNED NOT NULL DEFAULT 0 AFTER bot_reason,
+    ADD COLUMN fraud_rules JSON NULL AFTER fraud_score,
+    ADD INDEX idx_client_fraud_timestamp (client_id, fraud_score, redirect_timestamp);
+
+-- Webhook endpoints may only want clicks within a range of scores
+ALTER TABLE webhook_endpoints
+    ADD COLUMN min_fraud_score TINYINT UNSIGNED NULL AFTER mapping_ids,
+    ADD COLUMN max_fraud_score TINYINT UNSIGNED NULL AFTER min_fraud_score;
@@ -0,0 +1,16 @@
+USE platform_db;
+
+-- Referrer and UTM parameters parsed by the database worker. source_category is
+-- search, social, email, direct or other, and NULL until the row is parsed.
+ALTER TABLE request_logs
+    ADD COLUMN referrer_url VARCHAR(2048) NULL AFTER as_org,
+    ADD COLUMN referrer_host VARCHAR(255) NULL AFTER referrer_url,
+    ADD COLUMN source_category VARCHAR(16) NULL AFTER referrer_host,
+    ADD COLUMN utm_source VARCHAR(255) NULL AFTER source_category,
+    ADD COLUMN utm_medium VARCHAR(255) NULL AFTER utm_source,
+    ADD COLUMN utm_campaign VARCHAR(255) NULL AFTER utm_medium,
+    ADD COLUMN utm_term VARCHAR(255) NULL AFTER utm_campaign,
+    ADD COLUMN utm_content VARCHAR(255) NULL AFTER utm_term,
+    ADD INDEX idx_referrer_host (referrer_host),
+    ADD INDEX idx_source_category (source_category),
+    ADD INDEX idx_utm_campaign (utm_campaign);
@@ -0,0 +1,26 @@
+USE platform_db;
+
+-- UTM parameters the gateway merges into a mapping's destination. NULL values
+-- inherit the client's defaults below.
+ALTER TABLE redirect_mappings
+    ADD COLUMN utm_source VARCHAR(255) NULL AFTER redirect_url_black,
+    ADD COLUMN utm_medium VARCHAR(255) NULL AFTER utm_source,
+    ADD COLUMN utm_campaign VARCHAR(255) NULL AFTER utm_medium,
+    ADD COLUMN utm_term VARCHAR(255) NULL AFTER utm_campaign,
+    ADD COLUMN utm_content VARCHAR(255) NULL AFTER utm_term,
+    ADD COLUMN utm_policy VARCHAR(16) NULL AFTER utm_content;
+
+-- Per-client default UTM parameters and policy (keep or override parameters
+-- already on the destination). NULL columns set nothing.
+CREATE TABLE IF NOT EXISTS client_utm_settings (
+    client_id BIGINT PRIMARY KEY,
+    utm_source VARCHAR(255) NULL,
+    utm_medium VARCHAR(255) NULL,
+    utm_campaign VARCHAR(255) NULL,
+    utm_term VARCHAR(255) NULL,
+    utm_content VARCHAR(255) NULL,
+    policy VARCHAR(16) NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    FOREIGN KEY (client_id) REFERENCES clients(id) ON DELETE CASCADE
+);
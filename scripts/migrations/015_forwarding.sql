@@ -0,0 +1,13 @@
+USE platform_db;
+
+-- Which query parameters of a click on the short link reach the destination:
+-- none, all, or an allowlist (forward_params). forward_renames maps incoming
+-- names to the names they are forwarded as.
+ALTER TABLE redirect_mappings
+    ADD COLUMN forward_policy VARCHAR(16) NOT NULL DEFAULT 'none' AFTER utm_policy,
+    ADD COLUMN forward_params JSON NULL AFTER forward_policy,
+    ADD COLUMN forward_renames JSON NULL AFTER forward_params;
+
+-- The parameters forwarded with each click, under their forwarded names
+ALTER TABLE redirect_history
+    ADD COLUMN forwarded_params JSON NULL AFTER fraud_rules;
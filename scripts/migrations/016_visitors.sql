@@ -0,0 +1,48 @@
+USE platform_db;
+
+-- The visitor behind each click (gateway cookie, or daily-salted hash of IP and
+-- User-Agent) and whether it was the visitor's first click on the mapping
+-- within the unique window. Earlier clicks have no visitor and are not unique.
+ALTER TABLE redirect_history
+    ADD COLUMN visitor_id CHAR(32) NULL AFTER forwarded_params,
+    ADD COLUMN is_unique BOOLEAN NOT NULL DEFAULT FALSE AFTER visitor_id,
+    ADD INDEX idx_mapping_visitor_timestamp (mapping_id, visitor_id, redirect_timestamp);
+
+ALTER TABLE click_rollups_hourly
+    ADD COLUMN unique_clicks BIGINT UNSIGNED NOT NULL DEFAULT 0 AFTER clicks;
+
+ALTER TABLE click_rollups_daily
+    ADD COLUMN unique_clicks BIGINT UNSIGNED NOT NULL DEFAULT 0 AFTER clicks;
+
+-- HyperLogLog sketches of the distinct visitors of each mapping per UTC hour
+-- and day, merged by the stats API to count visitors over any range
+CREATE TABLE IF NOT EXISTS visitor_rollups_hourly (
+    bucket_start DATETIME NOT NULL,
+    mapping_id BIGINT NOT NULL,
+    client_id BIGINT NOT NULL,
+    is_bot BOOLEAN NOT NULL DEFAULT FALSE,
+    sketch BLOB NOT NULL,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    PRIMARY KEY (mapping_id, bucket_start, is_bot),
+    INDEX idx_client_bucket (client_id, bucket_start)
+);
+
+CREATE TABLE IF NOT EXISTS visitor_rollups_daily (
+    bucket_date DATE NOT NULL,
+    mapping_id BIGINT NOT NULL,
+    client_id BIGINT NOT NULL,
+    is_bot BOOLEAN NOT NULL DEFAULT FALSE,
+    sketch BLOB NOT NULL,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    PRIMARY KEY (mapping_id, bucket_date, is_bot),
+    INDEX idx_client_bucket (client_id, bucket_date)
+);
+
+-- Random salts of the visitor hash, one per UTC day. The gateway deletes
+-- past days' salts, after which their hashes can no longer be linked to an
+-- IP and User-Agent.
+CREATE TABLE IF NOT EXISTS visitor_salts (
+    salt_date DATE PRIMARY KEY,
+    salt BINARY(32) NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,28 @@
+USE platform_db;
+
+-- Lifecycle status of a mapping (draft, active, paused or archived) and what
+-- it serves while not active (fallback, gone or holding); NULL uses the
+-- platform default for the status
+ALTER TABLE redirect_mappings
+    ADD COLUMN status VARCHAR(16) NOT NULL DEFAULT 'active' AFTER forward_renames,
+    ADD COLUMN inactive_response VARCHAR(16) NULL AFTER status,
+    ADD INDEX idx_client_status (client_id, status);
+
+-- Audit trail of mapping status changes. from_status is NULL for the status a
+-- mapping was created with.
+CREATE TABLE IF NOT EXISTS mapping_status_changes (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    mapping_id BIGINT NOT NULL,
+    client_id BIGINT NOT NULL,
+    from_status VARCHAR(16) NULL,
+    to_status VARCHAR(16) NOT NULL,
+    reason VARCHAR(255) NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_mapping_created (mapping_id, created_at),
+    FOREIGN KEY (mapping_id) REFERENCES redirect_mappings(id) ON DELETE CASCADE
+);
+
+-- Existing mappings were active from the start
+INSERT INTO mapping_status_changes (mapping_id, client_id, from_status, to_status, reason, created_at)
+SELECT id, client_id, NULL, 'active', NULL, created_at
+FROM redirect_mappings;
@@ -0,0 +1,31 @@
+USE platform_db;
+
+-- Refresh tokens, stored as SHA-256 hashes. A family is one login session: each
+-- refresh marks the presented token used and adds its replacement to the family.
+-- access_jti is the access token issued with the refresh token.
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    client_id BIGINT NOT NULL,
+    family_id CHAR(36) NOT NULL,
+    token_hash CHAR(64) NOT NULL,
+    access_jti CHAR(36) NOT NULL,
+    access_expires_at DATETIME NOT NULL,
+    expires_at DATETIME NOT NULL,
+    used_at DATETIME NULL,
+    revoked_at DATETIME NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE KEY uniq_token_hash (token_hash),
+    INDEX idx_family (family_id),
+    INDEX idx_expires (expires_at),
+    FOREIGN KEY (client_id) REFERENCES clients(id) ON DELETE CASCADE
+);
+
+-- Access tokens revoked before their expiry, by JWT ID. Rows can be deleted
+-- once the token has expired.
+CREATE TABLE IF NOT EXISTS revoked_tokens (
+    jti CHAR(36) PRIMARY KEY,
+    client_id BIGINT NOT NULL,
+    expires_at DATETIME NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_expires (expires_at)
+);
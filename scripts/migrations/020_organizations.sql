@@ -0,0 +1,43 @@
+USE platform_db;
+
+CREATE TABLE IF NOT EXISTS organizations (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    created_by BIGINT NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Members of an organization and their role (owner, admin, editor or viewer)
+CREATE TABLE IF NOT EXISTS organization_members (
+    organization_id BIGINT NOT NULL,
+    client_id BIGINT NOT NULL,
+    role VARCHAR(16) NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (organization_id, client_id),
+    INDEX idx_client (client_id),
+    FOREIGN KEY (organization_id) REFERENCES organizations(id) ON DELETE CASCADE,
+    FOREIGN KEY (client_id) REFERENCES clients(id) ON DELETE CASCADE
+);
+
+-- Pending and accepted invitations, with the SHA-256 hash of their token
+CREATE TABLE IF NOT EXISTS organization_invitations (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    organization_id BIGINT NOT NULL,
+    email VARCHAR(255) NOT NULL,
+    role VARCHAR(16) NOT NULL,
+    token_hash CHAR(64) NOT NULL,
+    invited_by BIGINT NOT NULL,
+    expires_at DATETIME NOT NULL,
+    accepted_at DATETIME NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE KEY uniq_token_hash (token_hash),
+    INDEX idx_organization (organization_id),
+    FOREIGN KEY (organization_id) REFERENCES organizations(id) ON DELETE CASCADE
+);
+
+-- Mappings owned by an organization. NULL keeps a mapping personal to the
+-- client that created it; client_id is the creator either way.
+ALTER TABLE redirect_mappings
+    ADD COLUMN organization_id BIGINT NULL AFTER client_id,
+    ADD INDEX idx_organization_status (organization_id, status),
+    ADD FOREIGN KEY (organization_id) REFERENCES organizations(id);
@@ -0,0 +1,25 @@
+USE platform_db;
+
+-- Platform admins, suspended accounts and accounts that must change their
+-- password before using the API again. Admins are granted with SQL:
+--   UPDATE clients SET is_admin = TRUE WHERE username = '...';
+ALTER TABLE clients
+    ADD COLUMN is_admin BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN suspended_at DATETIME NULL,
+    ADD COLUMN suspended_reason VARCHAR(255) NULL,
+    ADD COLUMN password_reset_required BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Audit log of the changes admins make
+CREATE TABLE IF NOT EXISTS admin_actions (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    admin_id BIGINT NOT NULL,
+    action VARCHAR(32) NOT NULL,
+    target_type VARCHAR(16) NOT NULL,
+    target_id BIGINT NOT NULL,
+    reason VARCHAR(255) NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_created (created_at),
+    INDEX idx_target (target_type, target_id, created_at),
+    INDEX idx_admin (admin_id, created_at),
+    FOREIGN KEY (admin_id) REFERENCES clients(id)
+);
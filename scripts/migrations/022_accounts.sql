@@ -0,0 +1,25 @@
+USE platform_db;
+
+-- When a client confirmed its email address. Accounts created before email
+-- verification existed count as verified.
+ALTER TABLE clients
+    ADD COLUMN email_verified_at DATETIME NULL;
+
+UPDATE clients SET email_verified_at = created_at;
+
+-- Single-use email verification and password reset tokens, stored as SHA-256
+-- hashes. email is the address the token was sent to.
+CREATE TABLE IF NOT EXISTS account_tokens (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    client_id BIGINT NOT NULL,
+    purpose VARCHAR(16) NOT NULL,
+    email VARCHAR(255) NOT NULL,
+    token_hash CHAR(64) NOT NULL,
+    expires_at DATETIME NOT NULL,
+    used_at DATETIME NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE KEY uniq_token_hash (token_hash),
+    INDEX idx_client_purpose (client_id, purpose, created_at),
+    INDEX idx_expires (expires_at),
+    FOREIGN KEY (client_id) REFERENCES clients(id) ON DELETE CASCADE
+);
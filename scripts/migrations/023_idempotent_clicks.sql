@@ -0,0 +1,33 @@
+USE platform_db;
+
+-- RabbitMQ redelivers a click event when the worker fails part way through it,
+-- so every row the worker writes for an event is keyed by the event. The request
+-- ID comes from the X-Request-ID header and is not unique; the event ID is
+-- generated by the gateway. The key includes timestamp so it still holds when
+-- request_logs is partitioned.
+ALTER TABLE request_logs
+    ADD COLUMN event_id VARCHAR(36) NULL AFTER request_id,
+    ADD UNIQUE KEY uniq_event (event_id, timestamp);
+
+ALTER TABLE redirect_history
+    ADD UNIQUE KEY uniq_request_log (request_log_id);
+
+-- An event is queued once per endpoint; replays are extra deliveries of the
+-- same event and point at the delivery they replay
+ALTER TABLE webhook_deliveries
+    ADD COLUMN replay_of BIGINT NULL AFTER event_type;
+
+-- Mark all but the first delivery of an event already logged twice as replays
+UPDATE webhook_deliveries d
+JOIN (
+    SELECT endpoint_id, event_id, MIN(id) AS first_id
+    FROM webhook_deliveries
+    GROUP BY endpoint_id, event_id
+    HAVING COUNT(*) > 1
+) f ON f.endpoint_id = d.endpoint_id AND f.event_id = d.event_id
+SET d.replay_of = f.first_id
+WHERE d.id > f.first_id;
+
+ALTER TABLE webhook_deliveries
+    ADD COLUMN queued_event_id VARCHAR(36) AS (IF(replay_of IS NULL, event_id, NULL)) STORED,
+    ADD UNIQUE KEY uniq_endpoint_event (endpoint_id, queued_event_id);
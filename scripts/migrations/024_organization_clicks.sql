@@ -0,0 +1,68 @@
+USE platform_db;
+
+-- Record the organization owning the mapping of each click, so clicks on an
+-- organization's mappings are reported, streamed and sent to webhooks for the
+-- organization rather than for the member who created the mapping. NULL means
+-- the mapping is personal to client_id.
+ALTER TABLE redirect_history
+    ADD COLUMN organization_id BIGINT NULL AFTER client_id,
+    ADD INDEX idx_organization_timestamp (organization_id, redirect_timestamp);
+
+UPDATE redirect_history h
+JOIN redirect_mappings m ON m.id = h.mapping_id
+SET h.organization_id = m.organization_id
+WHERE m.organization_id IS NOT NULL;
+
+ALTER TABLE click_rollups_hourly
+    ADD COLUMN organization_id BIGINT NULL AFTER client_id,
+    ADD INDEX idx_organization_bucket (organization_id, bucket_start);
+
+ALTER TABLE click_rollups_daily
+    ADD COLUMN organization_id BIGINT NULL AFTER client_id,
+    ADD INDEX idx_organization_bucket (organization_id, bucket_date);
+
+ALTER TABLE visitor_rollups_hourly
+    ADD COLUMN organization_id BIGINT NULL AFTER client_id,
+    ADD INDEX idx_organization_bucket (organization_id, bucket_start);
+
+ALTER TABLE visitor_rollups_daily
+    ADD COLUMN organization_id BIGINT NULL AFTER client_id,
+    ADD INDEX idx_organization_bucket (organization_id, bucket_date);
+
+UPDATE click_rollups_hourly r
+JOIN redirect_mappings m ON m.id = r.mapping_id
+SET r.organization_id = m.organization_id
+WHERE m.organization_id IS NOT NULL;
+
+UPDATE click_rollups_daily r
+JOIN redirect_mappings m ON m.id = r.mapping_id
+SET r.organization_id = m.organization_id
+WHERE m.organization_id IS NOT NULL;
+
+UPDATE visitor_rollups_hourly r
+JOIN redirect_mappings m ON m.id = r.mapping_id
+SET r.organization_id = m.organization_id
+WHERE m.organization_id IS NOT NULL;
+
+UPDATE visitor_rollups_daily r
+JOIN redirect_mappings m ON m.id = r.mapping_id
+SET r.organization_id = m.organization_id
+WHERE m.organization_id IS NOT NULL;
+
+-- Conversions of clicks on an organization's mappings; client_id is the
+-- account whose postback token reported them
+ALTER TABLE conversions
+    ADD COLUMN organization_id BIGINT NULL AFTER client_id,
+    ADD INDEX idx_organization_created (organization_id, created_at);
+
+UPDATE conversions c
+JOIN redirect_mappings m ON m.id = c.mapping_id
+SET c.organization_id = m.organization_id
+WHERE m.organization_id IS NOT NULL;
+
+-- Webhook endpoints registered for an organization receive the events of its
+-- mappings; client_id is the member who registered them
+ALTER TABLE webhook_endpoints
+    ADD COLUMN organization_id BIGINT NULL AFTER client_id,
+    ADD INDEX idx_organization_active (organization_id, is_active),
+    ADD FOREIGN KEY (organization_id) REFERENCES organizations(id) ON DELETE CASCADE;
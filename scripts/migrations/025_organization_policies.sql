@@ -0,0 +1,12 @@
+USE platform_db;
+
+-- Clicks on an organization's mappings follow the platform privacy and
+-- retention defaults rather than the settings of the member who created the
+-- mapping, so request logs record the organization too
+ALTER TABLE request_logs
+    ADD COLUMN organization_id BIGINT NULL AFTER client_id;
+
+UPDATE request_logs l
+JOIN redirect_history h ON h.request_log_id = l.id
+SET l.organization_id = h.organization_id
+WHERE h.organization_id IS NOT NULL;
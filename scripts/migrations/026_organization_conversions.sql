@@ -0,0 +1,21 @@
+USE platform_db;
+
+-- A click on an organization's mapping converts once per event name for the
+-- organization, whichever member's postback token reports it. Conversions were
+-- keyed by the posting member, so the same conversion could be counted once per
+-- member; keep the first of each and key them by the owner of the mapping.
+DELETE c
+FROM conversions c
+JOIN conversions f
+    ON f.organization_id = c.organization_id
+    AND f.click_id = c.click_id
+    AND f.event_name = c.event_name
+    AND f.id < c.id
+WHERE c.organization_id IS NOT NULL;
+
+ALTER TABLE conversions
+    ADD COLUMN owner_key VARCHAR(32) AS (
+        IF(organization_id IS NULL, CONCAT('client:', client_id), CONCAT('organization:', organization_id))
+    ) STORED AFTER organization_id,
+    DROP INDEX idx_client_click_event,
+    ADD UNIQUE INDEX idx_owner_click_event (owner_key, click_id, event_name);
@@ -0,0 +1,22 @@
+-- Optional: partition request_logs by month so the retention job can drop whole
+-- partitions instead of deleting rows. Not applied automatically; run it during a
+-- maintenance window, then start the worker with RETENTION_PARTITIONED=true.
+--
+-- MySQL does not support foreign keys on partitioned tables, so the reference
+-- from redirect_history is dropped. The retention job never drops a partition
+-- that may still hold a request log referenced by retained redirect history.
+USE platform_db;
+
+ALTER TABLE redirect_history DROP FOREIGN KEY redirect_history_ibfk_1;
+
+-- Every unique key of a partitioned table must include the partitioning column
+ALTER TABLE request_logs
+    DROP PRIMARY KEY,
+    ADD PRIMARY KEY (id, timestamp);
+
+-- Start with a single catch-all partition; the worker splits it into monthly
+-- partitions on its first run and keeps creating them ahead of time
+ALTER TABLE request_logs
+    PARTITION BY RANGE COLUMNS (timestamp) (
+        PARTITION pmax VALUES LESS THAN (MAXVALUE)
+    );